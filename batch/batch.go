@@ -0,0 +1,381 @@
+// Package batch runs Tree-sitter queries over every file in a directory tree.
+//
+// A [Runner] walks a directory, detects the language of each file with a
+// [tree_sitter.LanguageRegistry], parses the files concurrently, and reports
+// the matches of the queries that were added for each language. Errors for
+// individual files don't stop the run; they are collected and returned
+// together at the end.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A node that was captured by a query.
+//
+// The node's data is copied, so that it stays valid after the file's tree has
+// been closed.
+type Capture struct {
+	// The name of the capture, without the leading `@`.
+	Name string
+
+	// The kind of the captured node.
+	Kind string
+
+	// The text of the captured node.
+	Text string
+
+	// The range of the captured node in its file.
+	Range tree_sitter.Range
+}
+
+// A match of a query in a file.
+type Match struct {
+	// The path of the file, starting with the root directory that was
+	// walked.
+	Path string
+
+	// The name of the file's language in the registry.
+	Language string
+
+	// The name that the query was added with.
+	Query string
+
+	// The index of the pattern that matched within the query.
+	PatternIndex uint
+
+	// The captured nodes, in the order that they appear in the file. Nodes
+	// that start at the same position are ordered from the outermost to the
+	// innermost.
+	Captures []Capture
+}
+
+// An error that occurred while processing a single file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Options for a [Runner].
+type Options struct {
+	// The number of files that are parsed at the same time. Defaults to
+	// [runtime.GOMAXPROCS].
+	Workers int
+
+	// The pool that the parsers are taken from. Defaults to a new pool with
+	// one parser per worker.
+	Pool *tree_sitter.ParserPool
+
+	// Files that are larger than this number of bytes are skipped, unless it
+	// is zero.
+	MaxFileSize int64
+
+	// A function that decides whether a file or directory should be skipped.
+	// By default, directories whose names start with a `.`, such as `.git`,
+	// are skipped.
+	Skip func(path string, entry fs.DirEntry) bool
+}
+
+// Runs a set of queries per language over the files in a directory tree.
+//
+// Add the queries with [Runner.AddQuery] before running them. A runner can
+// be used for several runs, including concurrent ones.
+type Runner struct {
+	registry *tree_sitter.LanguageRegistry
+	options  Options
+
+	mu      sync.RWMutex
+	queries map[string][]namedQuery
+}
+
+type namedQuery struct {
+	name  string
+	query *tree_sitter.Query
+}
+
+type fileResult struct {
+	matches []Match
+	err     error
+}
+
+// Create a new runner that detects the languages of files with `registry`.
+//
+// The options can be `nil`.
+func NewRunner(registry *tree_sitter.LanguageRegistry, options *Options) *Runner {
+	r := &Runner{registry: registry, queries: make(map[string][]namedQuery)}
+	if options != nil {
+		r.options = *options
+	}
+	if r.options.Workers <= 0 {
+		r.options.Workers = runtime.GOMAXPROCS(0)
+	}
+	if r.options.Skip == nil {
+		r.options.Skip = skipHiddenDirectories
+	}
+	return r
+}
+
+// Add a query that is run on the files of the given language.
+//
+// The language is looked up by its name in the runner's registry, and the
+// query's matches are reported with the given name.
+func (r *Runner) AddQuery(language string, name string, source string) error {
+	config := r.registry.LanguageForName(language)
+	if config == nil {
+		return fmt.Errorf("unknown language %q", language)
+	}
+	query, queryErr := tree_sitter.NewQuery(config.Language, source)
+	if queryErr != nil {
+		return fmt.Errorf("invalid query %s for %s: %w", name, language, queryErr)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries[language] = append(r.queries[language], namedQuery{name: name, query: query})
+	return nil
+}
+
+// Close the queries of this runner.
+func (r *Runner) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, queries := range r.queries {
+		for _, query := range queries {
+			query.query.Close()
+		}
+	}
+	r.queries = make(map[string][]namedQuery)
+}
+
+// Run the queries over the files in the directory tree at `root`, and call
+// `callback` with each match.
+//
+// The callback is only called by one goroutine at a time, and the matches of
+// each file are reported together, in the order that the queries were added.
+// The files are processed concurrently, so they are reported in no
+// particular order. Files whose language isn't detected or has no queries
+// are skipped.
+//
+// If the callback returns an error, or `ctx` is done, the run stops and that
+// error is returned. Otherwise, the errors of individual files are returned
+// together as [FileError]s joined with [errors.Join], or `nil` if there were
+// none.
+func (r *Runner) Run(ctx context.Context, root string, callback func(Match) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pool := r.options.Pool
+	if pool == nil {
+		pool = tree_sitter.NewParserPool(r.options.Workers)
+		defer pool.Close()
+	}
+
+	paths := make(chan string)
+	results := make(chan fileResult)
+	var walkErrs []error
+	go func() {
+		defer close(paths)
+		walkErrs = r.walk(ctx, root, paths)
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < r.options.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			cursor := tree_sitter.NewQueryCursor()
+			defer cursor.Close()
+			for path := range paths {
+				matches, err := r.processFile(ctx, pool, cursor, path)
+				if err != nil {
+					err = &FileError{Path: path, Err: err}
+				}
+				select {
+				case results <- fileResult{matches: matches, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	var callbackErr error
+	for result := range results {
+		if callbackErr != nil {
+			continue
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+		for _, match := range result.matches {
+			if err := callback(match); err != nil {
+				callbackErr = err
+				cancel()
+				break
+			}
+		}
+	}
+
+	if callbackErr != nil {
+		return callbackErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(append(walkErrs, errs...)...)
+}
+
+// Run the queries like [Runner.Run], but send the matches to a channel.
+//
+// The matches channel is closed when the run is finished, after which the
+// result of the run is sent to the error channel.
+func (r *Runner) Stream(ctx context.Context, root string) (<-chan Match, <-chan error) {
+	matches := make(chan Match)
+	result := make(chan error, 1)
+	go func() {
+		err := r.Run(ctx, root, func(match Match) error {
+			select {
+			case matches <- match:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(matches)
+		result <- err
+	}()
+	return matches, result
+}
+
+// Send the paths of the regular files under `root` to `paths`, and return
+// the errors of the files and directories that couldn't be read.
+func (r *Runner) walk(ctx context.Context, root string, paths chan<- string) []error {
+	var errs []error
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, &FileError{Path: path, Err: err})
+			return nil
+		}
+		if path != root && r.options.Skip(path, entry) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func (r *Runner) processFile(ctx context.Context, pool *tree_sitter.ParserPool, cursor *tree_sitter.QueryCursor, path string) ([]Match, error) {
+	if r.options.MaxFileSize > 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > r.options.MaxFileSize {
+			return nil, nil
+		}
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := r.registry.Detect(path, source)
+	if config == nil {
+		return nil, nil
+	}
+	r.mu.RLock()
+	queries := r.queries[config.Name]
+	r.mu.RUnlock()
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	parser, err := pool.Get(ctx, config.Language, nil)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := parser.ParseWithContext(ctx, func(i int, _ tree_sitter.Point) []byte {
+		return source[min(i, len(source)):]
+	}, nil, nil)
+	pool.Put(parser)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	var matches []Match
+	root := tree.RootNode()
+	for _, query := range queries {
+		captureNames := query.query.CaptureNames()
+		for match := range cursor.MatchesSeq(query.query, root, source) {
+			captures := make([]Capture, len(match.Captures))
+			for i, capture := range match.Captures {
+				captures[i] = Capture{
+					Name:  captureNames[capture.Index],
+					Kind:  capture.Node.Kind(),
+					Text:  capture.Node.Utf8Text(source),
+					Range: capture.Node.Range(),
+				}
+			}
+			// The query returns the captures in the order of its pattern,
+			// which can differ from their order in the file.
+			sort.SliceStable(captures, func(i, j int) bool {
+				a, b := captures[i].Range, captures[j].Range
+				if a.StartByte != b.StartByte {
+					return a.StartByte < b.StartByte
+				}
+				return a.EndByte > b.EndByte
+			})
+			matches = append(matches, Match{
+				Path:         path,
+				Language:     config.Name,
+				Query:        query.name,
+				PatternIndex: match.PatternIndex,
+				Captures:     captures,
+			})
+		}
+	}
+	return matches, nil
+}
+
+func skipHiddenDirectories(_ string, entry fs.DirEntry) bool {
+	return entry.IsDir() && strings.HasPrefix(entry.Name(), ".")
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
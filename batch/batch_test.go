@@ -0,0 +1,212 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/batch"
+	tree_sitter_json "github.com/tree-sitter/tree-sitter-json/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+func newRegistry(t *testing.T) *tree_sitter.LanguageRegistry {
+	registry := tree_sitter.NewLanguageRegistry()
+	assert.Nil(t, registry.Register(tree_sitter.LanguageConfiguration{
+		Name:      "json",
+		Language:  tree_sitter.NewLanguage(tree_sitter_json.Language()),
+		FileTypes: []string{"json"},
+	}))
+	assert.Nil(t, registry.Register(tree_sitter.LanguageConfiguration{
+		Name:         "python",
+		Language:     tree_sitter.NewLanguage(tree_sitter_python.Language()),
+		FileTypes:    []string{"py"},
+		Interpreters: []string{"python"},
+	}))
+	return registry
+}
+
+// Create the given files in a temporary directory, and return its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+func newRunner(t *testing.T, options *Options) *Runner {
+	runner := NewRunner(newRegistry(t), options)
+	assert.Nil(t, runner.AddQuery("python", "functions", `(function_definition name: (identifier) @name)`))
+	assert.Nil(t, runner.AddQuery("python", "calls", `(call function: (identifier) @function)`))
+	assert.Nil(t, runner.AddQuery("json", "keys", `(pair key: (string (string_content) @key))`))
+	return runner
+}
+
+// Format a match as `path query capture=text ...`, with the path relative to
+// the root.
+func formatMatch(root string, match Match) string {
+	path, _ := filepath.Rel(root, match.Path)
+	parts := []string{filepath.ToSlash(path), match.Query}
+	for _, capture := range match.Captures {
+		parts = append(parts, capture.Name+"="+capture.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRunner(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"main.py":            "def main():\n    run()\n",
+		"lib/util.py":        "def helper(x):\n    return len(x)\n",
+		"lib/config.json":    `{"name": "example", "version": 1}`,
+		"scripts/build":      "#!/usr/bin/env python3\nbuild()\n",
+		"README.md":          "# Example\n",
+		".git/hooks/hook.py": "def hook(): pass\n",
+	})
+	runner := newRunner(t, &Options{Workers: 3})
+	defer runner.Close()
+
+	var matches []string
+	err := runner.Run(context.Background(), root, func(match Match) error {
+		matches = append(matches, formatMatch(root, match))
+		return nil
+	})
+	assert.Nil(t, err)
+	sort.Strings(matches)
+	assert.Equal(t, []string{
+		"lib/config.json keys key=name",
+		"lib/config.json keys key=version",
+		"lib/util.py calls function=len",
+		"lib/util.py functions name=helper",
+		"main.py calls function=run",
+		"main.py functions name=main",
+		"scripts/build calls function=build",
+	}, matches)
+}
+
+func TestRunnerCaptures(t *testing.T) {
+	root := writeFiles(t, map[string]string{"a.py": "x = 1\ndef f():\n    pass\n"})
+	runner := newRunner(t, nil)
+	defer runner.Close()
+
+	var matches []Match
+	assert.Nil(t, runner.Run(context.Background(), root, func(match Match) error {
+		matches = append(matches, match)
+		return nil
+	}))
+	assert.Equal(t, []Match{{
+		Path:     filepath.Join(root, "a.py"),
+		Language: "python",
+		Query:    "functions",
+		Captures: []Capture{{
+			Name: "name",
+			Kind: "identifier",
+			Text: "f",
+			Range: tree_sitter.Range{
+				StartByte:  10,
+				EndByte:    11,
+				StartPoint: tree_sitter.Point{Row: 1, Column: 4},
+				EndPoint:   tree_sitter.Point{Row: 1, Column: 5},
+			},
+		}},
+	}}, matches)
+}
+
+func TestRunnerCaptureOrder(t *testing.T) {
+	root := writeFiles(t, map[string]string{"a.py": "def f(a):\n    pass\n"})
+	runner := NewRunner(newRegistry(t), nil)
+	defer runner.Close()
+	assert.Nil(t, runner.AddQuery(
+		"python",
+		"functions",
+		`(function_definition
+		  name: (identifier) @name
+		  parameters: (parameters (identifier) @param)) @function`,
+	))
+
+	var matches []string
+	assert.Nil(t, runner.Run(context.Background(), root, func(match Match) error {
+		matches = append(matches, formatMatch(root, match))
+		return nil
+	}))
+	assert.Equal(t, []string{"a.py functions function=def f(a):\n    pass name=f param=a"}, matches)
+}
+
+func TestRunnerStream(t *testing.T) {
+	files := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		files[name+".py"] = "def " + name + "(): pass\n"
+	}
+	root := writeFiles(t, files)
+	runner := newRunner(t, &Options{Workers: 4})
+	defer runner.Close()
+
+	matches, result := runner.Stream(context.Background(), root)
+	var names []string
+	for match := range matches {
+		names = append(names, match.Captures[0].Text)
+	}
+	assert.Nil(t, <-result)
+	sort.Strings(names)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f", "g", "h"}, names)
+}
+
+func TestRunnerErrors(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"a.py":       "def a(): pass\n",
+		"broken.py":  "",
+		"large.json": `{"key": "` + strings.Repeat("x", 100) + `"}`,
+	})
+	assert.Nil(t, os.Remove(filepath.Join(root, "broken.py")))
+	assert.Nil(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "broken.py")))
+
+	runner := newRunner(t, &Options{
+		MaxFileSize: 50,
+		Skip: func(_ string, entry fs.DirEntry) bool {
+			return entry.Type()&fs.ModeSymlink != 0
+		},
+	})
+	defer runner.Close()
+
+	// The skipped symlink and the large file produce neither matches nor
+	// errors.
+	count := 0
+	assert.Nil(t, runner.Run(context.Background(), root, func(match Match) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 1, count)
+
+	// The errors of unreadable files are collected.
+	err := runner.Run(context.Background(), filepath.Join(root, "missing"), func(Match) error { return nil })
+	var fileErr *FileError
+	assert.True(t, errors.As(err, &fileErr))
+	assert.Equal(t, filepath.Join(root, "missing"), fileErr.Path)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	// An error from the callback stops the run.
+	stop := errors.New("stop")
+	assert.Equal(t, stop, runner.Run(context.Background(), root, func(Match) error { return stop }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, runner.Run(ctx, root, func(Match) error { return nil }))
+}
+
+func TestRunnerAddQueryErrors(t *testing.T) {
+	runner := NewRunner(newRegistry(t), nil)
+	defer runner.Close()
+
+	assert.EqualError(t, runner.AddQuery("ruby", "q", "(identifier) @x"), `unknown language "ruby"`)
+	err := runner.AddQuery("json", "q", "(nonexistent) @x")
+	assert.True(t, strings.HasPrefix(err.Error(), "invalid query q for json: "))
+}
@@ -0,0 +1,69 @@
+package tree_sitter
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// A report about an object that was garbage collected without being closed.
+//
+// See [SetLeakHandler].
+type LeakReport struct {
+	// The name of the object's type, e.g. `Parser`.
+	Type string
+
+	// The stack trace of the goroutine that created the object, or an empty
+	// string if no leak handler was set at the time.
+	Stack string
+}
+
+var leakHandler atomic.Pointer[func(LeakReport)]
+
+// Set a function that is called whenever a [Parser], [Tree], [TreeCursor],
+// [Query], [QueryCursor] or [LookaheadIterator] is garbage collected without
+// having been closed. Pass `nil` to stop reporting leaks.
+//
+// The underlying memory of such objects is released automatically as a
+// safety net, but this only happens once the garbage collector notices them,
+// which may be much later or never. This isn't done for a [Tree] or a
+// [Query], because the nodes and matches that refer to their memory don't
+// keep them reachable, so they must always be closed explicitly; their leaks
+// are only reported, and only for the ones created while a handler is set.
+//
+// While a handler is set, the stack trace of every new object is recorded so
+// that the report can show where the leaked object was created. This is
+// expensive, so it should only be used for debugging.
+//
+// The handler is called from a separate goroutine.
+func SetLeakHandler(handler func(LeakReport)) {
+	if handler == nil {
+		leakHandler.Store(nil)
+	} else {
+		leakHandler.Store(&handler)
+	}
+}
+
+// Get the stack trace to record for a new object, if leaks are being
+// reported.
+func allocationStack() string {
+	if leakHandler.Load() == nil {
+		return ""
+	}
+	return string(debug.Stack())
+}
+
+// Register a cleanup that only reports a leak, for an object whose memory
+// can't be released safely once it is unreachable. This returns `nil` if no
+// leak handler is set.
+func addLeakReport(typeName string) *cleanup {
+	if leakHandler.Load() == nil {
+		return nil
+	}
+	return addCleanup(typeName, func(struct{}) {}, struct{}{})
+}
+
+func reportLeak(typeName string, stack string) {
+	if handler := leakHandler.Load(); handler != nil {
+		(*handler)(LeakReport{Type: typeName, Stack: stack})
+	}
+}
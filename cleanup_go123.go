@@ -0,0 +1,37 @@
+//go:build !go1.24
+
+package tree_sitter
+
+import "runtime"
+
+// A registered cleanup that releases an object's memory if it is garbage
+// collected without being closed.
+//
+// The finalizer is set on this separately allocated value rather than on the
+// object itself, so that a copy of the object keeps it alive too.
+type cleanup struct {
+	// The name of the object's type. The value must not be a tiny allocation
+	// without pointers, whose finalizer may never run.
+	typeName string
+}
+
+// Register a function that releases `arg` once the returned cleanup is
+// unreachable. The function must not refer to the object that stores the
+// cleanup, or it will never be collected.
+func addCleanup[S any](typeName string, release func(S), arg S) *cleanup {
+	stack := allocationStack()
+	c := &cleanup{typeName: typeName}
+	runtime.SetFinalizer(c, func(c *cleanup) {
+		release(arg)
+		reportLeak(c.typeName, stack)
+	})
+	return c
+}
+
+// Cancel the cleanup, because its object was closed explicitly.
+func (c *cleanup) stop() {
+	if c == nil {
+		return
+	}
+	runtime.SetFinalizer(c, nil)
+}
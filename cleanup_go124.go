@@ -0,0 +1,35 @@
+//go:build go1.24
+
+package tree_sitter
+
+import "runtime"
+
+// A registered cleanup that releases an object's memory if it is garbage
+// collected without being closed.
+//
+// The cleanup is attached to this separately allocated value rather than to
+// the object itself, so that a copy of the object keeps it alive too.
+type cleanup struct {
+	handle runtime.Cleanup
+}
+
+// Register a function that releases `arg` once the returned cleanup is
+// unreachable. The function must not refer to the object that stores the
+// cleanup, or it will never be collected.
+func addCleanup[S any](typeName string, release func(S), arg S) *cleanup {
+	stack := allocationStack()
+	c := &cleanup{}
+	c.handle = runtime.AddCleanup(c, func(arg S) {
+		release(arg)
+		reportLeak(typeName, stack)
+	}, arg)
+	return c
+}
+
+// Cancel the cleanup, because its object was closed explicitly.
+func (c *cleanup) stop() {
+	if c == nil {
+		return
+	}
+	c.handle.Stop()
+}
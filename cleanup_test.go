@@ -0,0 +1,106 @@
+package tree_sitter_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestCloseIsIdempotent(t *testing.T) {
+	parser := NewParser()
+	parser.SetLanguage(getLanguage("javascript"))
+	tree := parser.Parse([]byte("a + b"), nil)
+	cursor := tree.Walk()
+	cursorCopy := cursor.Copy()
+
+	query, err := NewQuery(getLanguage("javascript"), "(identifier) @id")
+	assert.Nil(t, err)
+	queryCursor := NewQueryCursor()
+	lookahead := getLanguage("javascript").LookaheadIterator(1)
+
+	for i := 0; i < 2; i++ {
+		lookahead.Close()
+		queryCursor.Close()
+		query.Close()
+		cursorCopy.Close()
+		cursor.Close()
+		tree.Close()
+		parser.Close()
+	}
+
+	var nilTree *Tree
+	nilTree.Close()
+}
+
+func TestCleanupSurvivesCopy(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+	tree := parser.Parse([]byte("a + b"), nil)
+	defer tree.Close()
+
+	cursor := tree.Walk()
+	defer cursor.Close()
+	func() {
+		copied := cursor.Copy()
+		copied.GotoFirstChild()
+		cursor.Close()
+		*cursor = *copied
+	}()
+
+	// The copy's memory must not be released along with the original value.
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "expression_statement", cursor.Node().Kind())
+	assert.True(t, cursor.GotoFirstChild())
+	assert.Equal(t, "binary_expression", cursor.Node().Kind())
+}
+
+func TestLeakHandler(t *testing.T) {
+	leaks := make(chan LeakReport, 16)
+	SetLeakHandler(func(report LeakReport) {
+		select {
+		case leaks <- report:
+		default:
+		}
+	})
+	defer SetLeakHandler(nil)
+
+	// A leaked tree or query is only reported; its memory is never released.
+	func() {
+		parser := NewParser()
+		parser.SetLanguage(getLanguage("javascript"))
+		tree := parser.Parse([]byte("a + b"), nil)
+		_ = tree.RootNode().Kind()
+		query, err := NewQuery(getLanguage("javascript"), "(identifier) @id")
+		assert.Nil(t, err)
+		_ = query.CaptureNames()
+	}()
+
+	reports := make(map[string]LeakReport)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reports) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaks were not reported, got %d of 3", len(reports))
+		}
+		runtime.GC()
+		select {
+		case r := <-leaks:
+			reports[r.Type] = r
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	for _, typeName := range []string{"Parser", "Tree", "Query"} {
+		report, ok := reports[typeName]
+		if assert.True(t, ok, typeName) {
+			assert.True(t, strings.Contains(report.Stack, "TestLeakHandler"), report.Stack)
+		}
+	}
+}
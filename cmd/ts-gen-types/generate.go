@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// An entry of a grammar's `node-types.json` file.
+type nodeType struct {
+	Type     string               `json:"type"`
+	Named    bool                 `json:"named"`
+	Fields   map[string]fieldInfo `json:"fields"`
+	Subtypes []nodeTypeRef        `json:"subtypes"`
+}
+
+type fieldInfo struct {
+	Multiple bool          `json:"multiple"`
+	Required bool          `json:"required"`
+	Types    []nodeTypeRef `json:"types"`
+}
+
+type nodeTypeRef struct {
+	Type  string `json:"type"`
+	Named bool   `json:"named"`
+}
+
+type generatorOptions struct {
+	// The name of the generated package.
+	packageName string
+
+	// The import path of the grammar's Go bindings, and the name of the
+	// function in them that returns the language.
+	languageImport string
+	languageFunc   string
+
+	// The language to read the ids of node kinds and fields from. If it is
+	// `nil`, the ids are looked up when the generated package is
+	// initialized, instead of being generated as constants.
+	language *tree_sitter.Language
+}
+
+// A named node kind that is either a concrete kind or a supertype.
+type kindInfo struct {
+	name   string
+	goName string
+	fields []fieldInfoWithName
+
+	// For supertypes, the concrete kinds that they contain, including those
+	// of nested supertypes.
+	isSupertype bool
+	members     []string
+
+	// For concrete kinds, the supertypes that contain them.
+	supertypes []string
+}
+
+type fieldInfoWithName struct {
+	fieldInfo
+	name       string
+	methodName string
+}
+
+type generator struct {
+	options generatorOptions
+	kinds   map[string]*kindInfo
+	names   []string
+	fields  []string
+	buffer  bytes.Buffer
+}
+
+// The names that the generated code declares itself.
+var reservedNames = map[string]bool{"Language": true, "TypedNode": true}
+
+func generate(nodeTypesJSON []byte, options generatorOptions) ([]byte, error) {
+	var nodeTypes []nodeType
+	if err := json.Unmarshal(nodeTypesJSON, &nodeTypes); err != nil {
+		return nil, fmt.Errorf("invalid node types: %w", err)
+	}
+
+	g := &generator{options: options, kinds: make(map[string]*kindInfo)}
+	if err := g.collectKinds(nodeTypes); err != nil {
+		return nil, err
+	}
+	if err := g.checkIds(); err != nil {
+		return nil, err
+	}
+
+	g.writeHeader()
+	g.writeConstants()
+	g.writeInit()
+	g.writeln("// A syntax node with a typed wrapper in this package.")
+	g.writeln("type TypedNode interface {")
+	g.writeln("AsNode() *tree_sitter.Node")
+	g.writeln("}")
+	for _, name := range g.names {
+		if kind := g.kinds[name]; kind.isSupertype {
+			g.writeSupertype(kind)
+		} else {
+			g.writeKind(kind)
+		}
+	}
+
+	source, err := format.Source(g.buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the generated code: %w", err)
+	}
+	return source, nil
+}
+
+func (g *generator) collectKinds(nodeTypes []nodeType) error {
+	goNames := make(map[string]string)
+	fields := make(map[string]bool)
+	for _, nodeType := range nodeTypes {
+		if !nodeType.Named {
+			continue
+		}
+		goName := goIdentifier(nodeType.Type)
+		if reservedNames[goName] {
+			return fmt.Errorf("node kind %q conflicts with the generated %s", nodeType.Type, goName)
+		}
+		if other, ok := goNames[goName]; ok {
+			return fmt.Errorf("node kinds %q and %q have the same Go name %s", other, nodeType.Type, goName)
+		}
+		goNames[goName] = nodeType.Type
+
+		kind := &kindInfo{
+			name:        nodeType.Type,
+			goName:      goName,
+			isSupertype: len(nodeType.Subtypes) > 0,
+		}
+		for _, subtype := range nodeType.Subtypes {
+			kind.members = append(kind.members, subtype.Type)
+		}
+
+		fieldNames := make([]string, 0, len(nodeType.Fields))
+		for name := range nodeType.Fields {
+			fieldNames = append(fieldNames, name)
+		}
+		sort.Strings(fieldNames)
+		for _, name := range fieldNames {
+			kind.fields = append(kind.fields, fieldInfoWithName{
+				fieldInfo:  nodeType.Fields[name],
+				name:       name,
+				methodName: fieldMethodName(name),
+			})
+			fields[name] = true
+		}
+
+		g.kinds[kind.name] = kind
+		g.names = append(g.names, kind.name)
+	}
+	sort.Strings(g.names)
+	for name := range fields {
+		g.fields = append(g.fields, name)
+	}
+	sort.Strings(g.fields)
+
+	// Flatten nested supertypes, so that each supertype lists all of the
+	// concrete kinds that it contains.
+	for _, name := range g.names {
+		kind := g.kinds[name]
+		if !kind.isSupertype {
+			continue
+		}
+		seen := make(map[string]bool)
+		var members []string
+		var visit func(string)
+		visit = func(name string) {
+			member, ok := g.kinds[name]
+			if !ok || seen[name] {
+				return
+			}
+			seen[name] = true
+			if member.isSupertype {
+				for _, nested := range member.members {
+					visit(nested)
+				}
+			} else {
+				members = append(members, name)
+				member.supertypes = append(member.supertypes, kind.name)
+			}
+		}
+		for _, member := range kind.members {
+			visit(member)
+		}
+		sort.Strings(members)
+		kind.members = members
+	}
+	return nil
+}
+
+// Check that the language knows all of the node kinds and fields, so that
+// their ids can be generated as constants.
+func (g *generator) checkIds() error {
+	language := g.options.language
+	if language == nil {
+		return nil
+	}
+	for _, name := range g.names {
+		if !g.kinds[name].isSupertype && language.IdForNodeKind(name, true) == 0 {
+			return fmt.Errorf("node kind %q is not in the language", name)
+		}
+	}
+	for _, name := range g.fields {
+		if language.FieldIdForName(name) == 0 {
+			return fmt.Errorf("field %q is not in the language", name)
+		}
+	}
+	return nil
+}
+
+func (g *generator) writeln(format string, args ...any) {
+	fmt.Fprintf(&g.buffer, format, args...)
+	g.buffer.WriteByte('\n')
+}
+
+func (g *generator) writeHeader() {
+	g.writeln("// Code generated by ts-gen-types. DO NOT EDIT.")
+	g.writeln("")
+	g.writeln("package %s", g.options.packageName)
+	g.writeln("")
+	g.writeln("import (")
+	g.writeln(`"fmt"`)
+	if len(g.supertypeNames()) > 0 {
+		g.writeln(`"slices"`)
+	}
+	g.writeln("")
+	g.writeln(`tree_sitter "github.com/tree-sitter/go-tree-sitter"`)
+	g.writeln(`grammar %q`, g.options.languageImport)
+	g.writeln(")")
+	g.writeln("")
+	g.writeln("// The language that the node types belong to.")
+	g.writeln("var Language = tree_sitter.NewLanguage(grammar.%s())", g.options.languageFunc)
+	g.writeln("")
+}
+
+func (g *generator) writeConstants() {
+	language := g.options.language
+	if language != nil {
+		g.writeln("// The ids of the named node kinds.")
+		g.writeln("const (")
+	} else {
+		g.writeln("// The ids of the named node kinds, which are looked up when the package is")
+		g.writeln("// initialized.")
+		g.writeln("var (")
+	}
+	for _, name := range g.names {
+		kind := g.kinds[name]
+		if kind.isSupertype {
+			continue
+		}
+		if language != nil {
+			g.writeln("Kind%s uint16 = %d", kind.goName, language.IdForNodeKind(name, true))
+		} else {
+			g.writeln("Kind%s = Language.IdForNodeKind(%q, true)", kind.goName, name)
+		}
+	}
+	g.writeln(")")
+	g.writeln("")
+
+	if len(g.fields) == 0 {
+		return
+	}
+	if language != nil {
+		g.writeln("// The ids of the fields.")
+		g.writeln("const (")
+	} else {
+		g.writeln("// The ids of the fields, which are looked up when the package is")
+		g.writeln("// initialized.")
+		g.writeln("var (")
+	}
+	for _, name := range g.fields {
+		if language != nil {
+			g.writeln("Field%s uint16 = %d", goIdentifier(name), language.FieldIdForName(name))
+		} else {
+			g.writeln("Field%s = Language.FieldIdForName(%q)", goIdentifier(name), name)
+		}
+	}
+	g.writeln(")")
+	g.writeln("")
+}
+
+func (g *generator) writeInit() {
+	g.writeln("// Check that the node types match the language, so that the package fails")
+	g.writeln("// early if it was generated from a different version of the grammar.")
+	g.writeln("func init() {")
+	var entries []string
+	for _, name := range g.names {
+		if kind := g.kinds[name]; !kind.isSupertype {
+			entries = append(entries, fmt.Sprintf("{Kind%s, %q}", kind.goName, name))
+		}
+	}
+	g.writeIdCheck("node kind", "IdForNodeKind(entry.name, true)", entries)
+	if len(g.fields) > 0 {
+		entries = nil
+		for _, name := range g.fields {
+			entries = append(entries, fmt.Sprintf("{Field%s, %q}", goIdentifier(name), name))
+		}
+		g.writeIdCheck("field", "FieldIdForName(entry.name)", entries)
+	}
+
+	if supertypes := g.supertypeNames(); len(supertypes) > 0 {
+		quoted := make([]string, len(supertypes))
+		for i, name := range supertypes {
+			quoted[i] = fmt.Sprintf("%q", name)
+		}
+		g.writeln("")
+		g.writeln("// Languages generated before ABI version 15 don't list their supertypes.")
+		g.writeln("if supertypes := Language.Supertypes(); len(supertypes) > 0 {")
+		g.writeln("var names []string")
+		g.writeln("for _, id := range supertypes {")
+		g.writeln("names = append(names, Language.NodeKindForId(id))")
+		g.writeln("}")
+		g.writeln("for _, name := range []string{%s} {", strings.Join(quoted, ", "))
+		g.writeln("if !slices.Contains(names, name) {")
+		g.writeln(`panic(fmt.Sprintf("%s: %%q is not a supertype in the language", name))`, g.options.packageName)
+		g.writeln("}")
+		g.writeln("}")
+		g.writeln("}")
+	}
+	g.writeln("}")
+	g.writeln("")
+}
+
+// Write a loop that checks the ids of node kinds or fields against the ids
+// that the language returns for their names.
+func (g *generator) writeIdCheck(description, lookup string, entries []string) {
+	g.writeln("for _, entry := range []struct {")
+	g.writeln("id uint16")
+	g.writeln("name string")
+	g.writeln("}{")
+	for _, entry := range entries {
+		g.writeln("%s,", entry)
+	}
+	g.writeln("} {")
+	if g.options.language != nil {
+		g.writeln("if id := Language.%s; id != entry.id {", lookup)
+		g.writeln(
+			`panic(fmt.Sprintf("%s: %s %%q has id %%d in the language, but %%d in the generated code", entry.name, id, entry.id))`,
+			g.options.packageName,
+			description,
+		)
+	} else {
+		// The ids were looked up by name, which returns zero for unknown names.
+		g.writeln("if entry.id == 0 {")
+		g.writeln(`panic(fmt.Sprintf("%s: %s %%q is not in the language", entry.name))`, g.options.packageName, description)
+	}
+	g.writeln("}")
+	g.writeln("}")
+}
+
+func (g *generator) supertypeNames() []string {
+	var names []string
+	for _, name := range g.names {
+		if g.kinds[name].isSupertype {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (g *generator) writeSupertype(kind *kindInfo) {
+	g.writeln("")
+	g.writeln("// A node of one of the `%s` kinds.", kind.name)
+	g.writeln("type %s interface {", kind.goName)
+	g.writeln("TypedNode")
+	g.writeln("is%s()", kind.goName)
+	g.writeln("}")
+	g.writeln("")
+	g.writeln("// Convert a node to [%s] if it is of one of its kinds.", kind.goName)
+	g.writeln("func As%s(node *tree_sitter.Node) (%s, bool) {", kind.goName, kind.goName)
+	g.writeln("if node == nil {")
+	g.writeln("return nil, false")
+	g.writeln("}")
+	g.writeln("switch node.KindId() {")
+	for _, member := range kind.members {
+		goName := g.kinds[member].goName
+		g.writeln("case Kind%s:", goName)
+		g.writeln("return %s{node}, true", goName)
+	}
+	g.writeln("}")
+	g.writeln("return nil, false")
+	g.writeln("}")
+}
+
+func (g *generator) writeKind(kind *kindInfo) {
+	g.writeln("")
+	g.writeln("// A node of kind `%s`.", kind.name)
+	g.writeln("type %s struct {", kind.goName)
+	g.writeln("*tree_sitter.Node")
+	g.writeln("}")
+	g.writeln("")
+	g.writeln("// Convert a node to [%s] if it is of kind `%s`.", kind.goName, kind.name)
+	g.writeln("func As%s(node *tree_sitter.Node) (%s, bool) {", kind.goName, kind.goName)
+	g.writeln("if node == nil || node.KindId() != Kind%s {", kind.goName)
+	g.writeln("return %s{}, false", kind.goName)
+	g.writeln("}")
+	g.writeln("return %s{node}, true", kind.goName)
+	g.writeln("}")
+	g.writeln("")
+	g.writeln("func (n %s) AsNode() *tree_sitter.Node {", kind.goName)
+	g.writeln("return n.Node")
+	g.writeln("}")
+	for _, supertype := range kind.supertypes {
+		g.writeln("")
+		g.writeln("func (%s) is%s() {}", kind.goName, g.kinds[supertype].goName)
+	}
+	for _, field := range kind.fields {
+		g.writeField(kind, field)
+	}
+}
+
+func (g *generator) writeField(kind *kindInfo, field fieldInfoWithName) {
+	// Fields with a single named kind get its wrapper, and other fields get
+	// plain nodes.
+	typeName, convert := "*tree_sitter.Node", ""
+	if len(field.Types) == 1 && field.Types[0].Named {
+		if fieldKind, ok := g.kinds[field.Types[0].Type]; ok {
+			typeName, convert = fieldKind.goName, "As"+fieldKind.goName
+		}
+	}
+
+	g.writeln("")
+	if field.Multiple {
+		elementName := typeName
+		if convert == "" {
+			elementName = "tree_sitter.Node"
+		}
+		g.writeln("// Get the node's `%s` children.", field.name)
+		g.writeln("func (n %s) %s() []%s {", kind.goName, field.methodName, elementName)
+		g.writeln("cursor := n.Walk()")
+		g.writeln("defer cursor.Close()")
+		g.writeln("children := n.ChildrenByFieldName(%q, cursor)", field.name)
+		if convert == "" {
+			g.writeln("return children")
+		} else {
+			g.writeln("result := make([]%s, 0, len(children))", typeName)
+			g.writeln("for i := range children {")
+			g.writeln("if child, ok := %s(&children[i]); ok {", convert)
+			g.writeln("result = append(result, child)")
+			g.writeln("}")
+			g.writeln("}")
+			g.writeln("return result")
+		}
+		g.writeln("}")
+		return
+	}
+
+	g.writeln("// Get the node's `%s` child.", field.name)
+	g.writeln("func (n %s) %s() (%s, bool) {", kind.goName, field.methodName, typeName)
+	if convert == "" {
+		g.writeln("child := n.ChildByFieldId(Field%s)", goIdentifier(field.name))
+		g.writeln("return child, child != nil")
+	} else {
+		g.writeln("return %s(n.ChildByFieldId(Field%s))", convert, goIdentifier(field.name))
+	}
+	g.writeln("}")
+}
+
+// The methods of [tree_sitter.Node], which the generated wrappers embed.
+var nodeMethods = func() map[string]bool {
+	methods := map[string]bool{"AsNode": true}
+	nodeType := reflect.TypeOf(&tree_sitter.Node{})
+	for i := 0; i < nodeType.NumMethod(); i++ {
+		methods[nodeType.Method(i).Name] = true
+	}
+	return methods
+}()
+
+// Get the name of a field's accessor method, which must not hide a method of
+// the embedded node.
+func fieldMethodName(field string) string {
+	name := goIdentifier(field)
+	if nodeMethods[name] {
+		name += "Field"
+	}
+	return name
+}
+
+// Convert a node kind or field name in snake case, such as
+// `_type_identifier`, to an exported Go identifier, such as `TypeIdentifier`.
+func goIdentifier(name string) string {
+	var result strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		result.WriteRune(r)
+	}
+	if result.Len() == 0 || !unicode.IsLetter([]rune(result.String())[0]) {
+		return "N" + result.String()
+	}
+	return result.String()
+}
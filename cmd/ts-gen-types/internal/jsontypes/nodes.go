@@ -0,0 +1,349 @@
+// Code generated by ts-gen-types. DO NOT EDIT.
+
+package jsontypes
+
+import (
+	"fmt"
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	grammar "github.com/tree-sitter/tree-sitter-json/bindings/go"
+)
+
+// The language that the node types belong to.
+var Language = tree_sitter.NewLanguage(grammar.Language())
+
+// The ids of the named node kinds.
+const (
+	KindArray          uint16 = 19
+	KindComment        uint16 = 14
+	KindDocument       uint16 = 15
+	KindEscapeSequence uint16 = 9
+	KindFalse          uint16 = 12
+	KindNull           uint16 = 13
+	KindNumber         uint16 = 10
+	KindObject         uint16 = 17
+	KindPair           uint16 = 18
+	KindString         uint16 = 20
+	KindStringContent  uint16 = 8
+	KindTrue           uint16 = 11
+)
+
+// The ids of the fields.
+const (
+	FieldKey   uint16 = 1
+	FieldValue uint16 = 2
+)
+
+// Check that the node types match the language, so that the package fails
+// early if it was generated from a different version of the grammar.
+func init() {
+	for _, entry := range []struct {
+		id   uint16
+		name string
+	}{
+		{KindArray, "array"},
+		{KindComment, "comment"},
+		{KindDocument, "document"},
+		{KindEscapeSequence, "escape_sequence"},
+		{KindFalse, "false"},
+		{KindNull, "null"},
+		{KindNumber, "number"},
+		{KindObject, "object"},
+		{KindPair, "pair"},
+		{KindString, "string"},
+		{KindStringContent, "string_content"},
+		{KindTrue, "true"},
+	} {
+		if id := Language.IdForNodeKind(entry.name, true); id != entry.id {
+			panic(fmt.Sprintf("jsontypes: node kind %q has id %d in the language, but %d in the generated code", entry.name, id, entry.id))
+		}
+	}
+	for _, entry := range []struct {
+		id   uint16
+		name string
+	}{
+		{FieldKey, "key"},
+		{FieldValue, "value"},
+	} {
+		if id := Language.FieldIdForName(entry.name); id != entry.id {
+			panic(fmt.Sprintf("jsontypes: field %q has id %d in the language, but %d in the generated code", entry.name, id, entry.id))
+		}
+	}
+
+	// Languages generated before ABI version 15 don't list their supertypes.
+	if supertypes := Language.Supertypes(); len(supertypes) > 0 {
+		var names []string
+		for _, id := range supertypes {
+			names = append(names, Language.NodeKindForId(id))
+		}
+		for _, name := range []string{"_value"} {
+			if !slices.Contains(names, name) {
+				panic(fmt.Sprintf("jsontypes: %q is not a supertype in the language", name))
+			}
+		}
+	}
+}
+
+// A syntax node with a typed wrapper in this package.
+type TypedNode interface {
+	AsNode() *tree_sitter.Node
+}
+
+// A node of one of the `_value` kinds.
+type Value interface {
+	TypedNode
+	isValue()
+}
+
+// Convert a node to [Value] if it is of one of its kinds.
+func AsValue(node *tree_sitter.Node) (Value, bool) {
+	if node == nil {
+		return nil, false
+	}
+	switch node.KindId() {
+	case KindArray:
+		return Array{node}, true
+	case KindFalse:
+		return False{node}, true
+	case KindNull:
+		return Null{node}, true
+	case KindNumber:
+		return Number{node}, true
+	case KindObject:
+		return Object{node}, true
+	case KindString:
+		return String{node}, true
+	case KindTrue:
+		return True{node}, true
+	}
+	return nil, false
+}
+
+// A node of kind `array`.
+type Array struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Array] if it is of kind `array`.
+func AsArray(node *tree_sitter.Node) (Array, bool) {
+	if node == nil || node.KindId() != KindArray {
+		return Array{}, false
+	}
+	return Array{node}, true
+}
+
+func (n Array) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (Array) isValue() {}
+
+// A node of kind `comment`.
+type Comment struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Comment] if it is of kind `comment`.
+func AsComment(node *tree_sitter.Node) (Comment, bool) {
+	if node == nil || node.KindId() != KindComment {
+		return Comment{}, false
+	}
+	return Comment{node}, true
+}
+
+func (n Comment) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+// A node of kind `document`.
+type Document struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Document] if it is of kind `document`.
+func AsDocument(node *tree_sitter.Node) (Document, bool) {
+	if node == nil || node.KindId() != KindDocument {
+		return Document{}, false
+	}
+	return Document{node}, true
+}
+
+func (n Document) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+// A node of kind `escape_sequence`.
+type EscapeSequence struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [EscapeSequence] if it is of kind `escape_sequence`.
+func AsEscapeSequence(node *tree_sitter.Node) (EscapeSequence, bool) {
+	if node == nil || node.KindId() != KindEscapeSequence {
+		return EscapeSequence{}, false
+	}
+	return EscapeSequence{node}, true
+}
+
+func (n EscapeSequence) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+// A node of kind `false`.
+type False struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [False] if it is of kind `false`.
+func AsFalse(node *tree_sitter.Node) (False, bool) {
+	if node == nil || node.KindId() != KindFalse {
+		return False{}, false
+	}
+	return False{node}, true
+}
+
+func (n False) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (False) isValue() {}
+
+// A node of kind `null`.
+type Null struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Null] if it is of kind `null`.
+func AsNull(node *tree_sitter.Node) (Null, bool) {
+	if node == nil || node.KindId() != KindNull {
+		return Null{}, false
+	}
+	return Null{node}, true
+}
+
+func (n Null) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (Null) isValue() {}
+
+// A node of kind `number`.
+type Number struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Number] if it is of kind `number`.
+func AsNumber(node *tree_sitter.Node) (Number, bool) {
+	if node == nil || node.KindId() != KindNumber {
+		return Number{}, false
+	}
+	return Number{node}, true
+}
+
+func (n Number) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (Number) isValue() {}
+
+// A node of kind `object`.
+type Object struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Object] if it is of kind `object`.
+func AsObject(node *tree_sitter.Node) (Object, bool) {
+	if node == nil || node.KindId() != KindObject {
+		return Object{}, false
+	}
+	return Object{node}, true
+}
+
+func (n Object) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (Object) isValue() {}
+
+// A node of kind `pair`.
+type Pair struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [Pair] if it is of kind `pair`.
+func AsPair(node *tree_sitter.Node) (Pair, bool) {
+	if node == nil || node.KindId() != KindPair {
+		return Pair{}, false
+	}
+	return Pair{node}, true
+}
+
+func (n Pair) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+// Get the node's `key` child.
+func (n Pair) Key() (String, bool) {
+	return AsString(n.ChildByFieldId(FieldKey))
+}
+
+// Get the node's `value` child.
+func (n Pair) Value() (Value, bool) {
+	return AsValue(n.ChildByFieldId(FieldValue))
+}
+
+// A node of kind `string`.
+type String struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [String] if it is of kind `string`.
+func AsString(node *tree_sitter.Node) (String, bool) {
+	if node == nil || node.KindId() != KindString {
+		return String{}, false
+	}
+	return String{node}, true
+}
+
+func (n String) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (String) isValue() {}
+
+// A node of kind `string_content`.
+type StringContent struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [StringContent] if it is of kind `string_content`.
+func AsStringContent(node *tree_sitter.Node) (StringContent, bool) {
+	if node == nil || node.KindId() != KindStringContent {
+		return StringContent{}, false
+	}
+	return StringContent{node}, true
+}
+
+func (n StringContent) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+// A node of kind `true`.
+type True struct {
+	*tree_sitter.Node
+}
+
+// Convert a node to [True] if it is of kind `true`.
+func AsTrue(node *tree_sitter.Node) (True, bool) {
+	if node == nil || node.KindId() != KindTrue {
+		return True{}, false
+	}
+	return True{node}, true
+}
+
+func (n True) AsNode() *tree_sitter.Node {
+	return n.Node
+}
+
+func (True) isValue() {}
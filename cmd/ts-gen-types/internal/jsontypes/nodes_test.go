@@ -0,0 +1,46 @@
+package jsontypes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/cmd/ts-gen-types/internal/jsontypes"
+)
+
+func TestGeneratedNodeTypes(t *testing.T) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(Language)
+
+	source := []byte(`{"a": [1, null], "b": "c"}`)
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	document, ok := AsDocument(tree.RootNode())
+	assert.True(t, ok)
+	value, ok := AsValue(document.NamedChild(0))
+	assert.True(t, ok)
+	object, ok := value.(Object)
+	assert.True(t, ok)
+
+	pair, ok := AsPair(object.NamedChild(0))
+	assert.True(t, ok)
+	key, ok := pair.Key()
+	assert.True(t, ok)
+	assert.Equal(t, `"a"`, key.Utf8Text(source))
+	pairValue, ok := pair.Value()
+	assert.True(t, ok)
+	array, ok := pairValue.(Array)
+	assert.True(t, ok)
+	assert.Equal(t, "[1, null]", array.AsNode().Utf8Text(source))
+
+	_, ok = AsNull(array.NamedChild(0))
+	assert.False(t, ok)
+	_, ok = AsNull(array.NamedChild(1))
+	assert.True(t, ok)
+	_, ok = AsValue(key.NamedChild(0))
+	assert.False(t, ok)
+	_, ok = AsPair(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,100 @@
+// Command ts-gen-types generates typed Go wrappers for the syntax nodes of a
+// grammar from its `node-types.json` file.
+//
+// For each named node kind, it generates a type that embeds
+// [tree_sitter.Node], a function that converts a node to it, and accessors
+// for its fields. Each supertype becomes an interface that is implemented by
+// the types of its subtypes. The ids of the node kinds and fields are
+// generated as `Kind...` and `Field...` identifiers, and checked against the
+// language when the generated package is initialized.
+//
+// Usage:
+//
+//	ts-gen-types -package gosyntax \
+//		-language-import github.com/tree-sitter/tree-sitter-go/bindings/go \
+//		-o gosyntax/nodes.go node-types.json
+//
+// The ids can only be generated as constants if the generator can load the
+// language, which is done from a shared library with the `-library` flag.
+// Otherwise, they are variables that are looked up by name.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ts-gen-types:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("ts-gen-types", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: ts-gen-types [flags] node-types.json")
+		flags.PrintDefaults()
+	}
+	packageName := flags.String("package", "", "the name of the generated package (default: the output directory's name)")
+	languageImport := flags.String("language-import", "", "the import path of the grammar's Go bindings (required)")
+	languageFunc := flags.String("language-func", "Language", "the function in the bindings that returns the language")
+	library := flags.String("library", "", "a shared library of the grammar, to generate the ids as constants")
+	name := flags.String("name", "", "the name of the language in the shared library")
+	output := flags.String("o", "", "the output file (default: standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *languageImport == "" {
+		flags.Usage()
+		return fmt.Errorf("expected a node types file and a -language-import flag")
+	}
+
+	if *packageName == "" {
+		if *output == "" {
+			return fmt.Errorf("the -package flag is required when writing to standard output")
+		}
+		absolute, err := filepath.Abs(*output)
+		if err != nil {
+			return err
+		}
+		*packageName = filepath.Base(filepath.Dir(absolute))
+	}
+
+	nodeTypes, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	options := generatorOptions{
+		packageName:    *packageName,
+		languageImport: *languageImport,
+		languageFunc:   *languageFunc,
+	}
+	if *library != "" {
+		if *name == "" {
+			return fmt.Errorf("the -name flag is required with -library")
+		}
+		language, err := tree_sitter.LoadLanguageFromLibrary(*library, *name)
+		if err != nil {
+			return err
+		}
+		defer language.Close()
+		options.language = language
+	}
+
+	source, err := generate(nodeTypes, options)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+	return os.WriteFile(*output, source, 0o644)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_json "github.com/tree-sitter/tree-sitter-json/bindings/go"
+)
+
+var update = flag.Bool("update", false, "update the generated jsontypes package")
+
+const jsonTypesPath = "internal/jsontypes/nodes.go"
+
+func readJSONNodeTypes(t *testing.T) []byte {
+	nodeTypes, err := os.ReadFile(filepath.Join("testdata", "json-node-types.json"))
+	assert.Nil(t, err)
+	return nodeTypes
+}
+
+// The jsontypes package is generated with the ids as constants, and its own
+// tests check that the generated code works.
+func TestGenerateWithLanguage(t *testing.T) {
+	source, err := generate(readJSONNodeTypes(t), generatorOptions{
+		packageName:    "jsontypes",
+		languageImport: "github.com/tree-sitter/tree-sitter-json/bindings/go",
+		languageFunc:   "Language",
+		language:       tree_sitter.NewLanguage(tree_sitter_json.Language()),
+	})
+	assert.Nil(t, err)
+
+	if *update {
+		assert.Nil(t, os.WriteFile(jsonTypesPath, source, 0o644))
+	}
+	expected, err := os.ReadFile(jsonTypesPath)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(source), "run the tests with -update to regenerate %s", jsonTypesPath)
+}
+
+func TestGenerateWithoutLanguage(t *testing.T) {
+	source, err := generate(readJSONNodeTypes(t), generatorOptions{
+		packageName:    "jsontypes",
+		languageImport: "github.com/tree-sitter/tree-sitter-json/bindings/go",
+		languageFunc:   "Language",
+	})
+	assert.Nil(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "nodes.go", source, 0)
+	assert.Nil(t, err)
+	assert.Contains(t, string(source), `KindPair           = Language.IdForNodeKind("pair", true)`)
+	assert.Contains(t, string(source), `FieldKey   = Language.FieldIdForName("key")`)
+	assert.NotContains(t, string(source), "const (")
+}
+
+func TestGenerateFields(t *testing.T) {
+	source, err := generate([]byte(`[
+		{"type": "_statement", "named": true, "subtypes": [{"type": "block", "named": true}, {"type": "_simple", "named": true}]},
+		{"type": "_simple", "named": true, "subtypes": [{"type": "call", "named": true}]},
+		{"type": "block", "named": true, "fields": {
+			"statements": {"multiple": true, "required": false, "types": [{"type": "_statement", "named": true}]},
+			"label": {"multiple": false, "required": false, "types": [{"type": "call", "named": true}, {"type": ":", "named": false}]},
+			"range": {"multiple": true, "required": true, "types": [{"type": ";", "named": false}]}
+		}},
+		{"type": "call", "named": true, "fields": {}}
+	]`), generatorOptions{packageName: "p", languageImport: "example.com/grammar", languageFunc: "Language"})
+	assert.Nil(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "nodes.go", source, 0)
+	assert.Nil(t, err)
+
+	code := string(source)
+	assert.Contains(t, code, "func (n Block) Statements() []Statement {")
+	assert.Contains(t, code, "func (n Block) Label() (*tree_sitter.Node, bool) {")
+	assert.Contains(t, code, "func (n Block) RangeField() []tree_sitter.Node {")
+	assert.Contains(t, code, "func (Call) isSimple() {}")
+	assert.Contains(t, code, "func (Call) isStatement() {}")
+	assert.NotContains(t, code, "KindStatement")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	options := generatorOptions{packageName: "p", languageImport: "example.com/grammar", languageFunc: "Language"}
+
+	_, err := generate([]byte(`[{"type": "a_b", "named": true}, {"type": "a__b", "named": true}]`), options)
+	assert.EqualError(t, err, `node kinds "a_b" and "a__b" have the same Go name AB`)
+
+	_, err = generate([]byte(`[{"type": "language", "named": true}]`), options)
+	assert.EqualError(t, err, `node kind "language" conflicts with the generated Language`)
+
+	options.language = tree_sitter.NewLanguage(tree_sitter_json.Language())
+	_, err = generate([]byte(`[{"type": "not_json", "named": true}]`), options)
+	assert.EqualError(t, err, `node kind "not_json" is not in the language`)
+
+	_, err = generate([]byte(`{`), options)
+	assert.True(t, strings.HasPrefix(err.Error(), "invalid node types"))
+}
+
+func TestGoIdentifier(t *testing.T) {
+	assert.Equal(t, "FunctionDeclaration", goIdentifier("function_declaration"))
+	assert.Equal(t, "Expression", goIdentifier("_expression"))
+	assert.Equal(t, "N3dPoint", goIdentifier("3d_point"))
+	assert.Equal(t, "RangeField", fieldMethodName("range"))
+	assert.Equal(t, "Body", fieldMethodName("body"))
+}
@@ -0,0 +1,157 @@
+package tree_sitter
+
+// A symbol that is valid at some position in a document, as returned by
+// [Tree.ExpectedSymbols].
+type ExpectedSymbol struct {
+	Id    uint16
+	Kind  string
+	Named bool
+}
+
+// Options for filtering the symbols returned by [Tree.ExpectedSymbols].
+type ExpectedSymbolsOptions struct {
+	// Leave out symbols that are not visible in the syntax tree, such as
+	// rules whose names start with an underscore.
+	ExcludeHidden bool
+
+	// Leave out extra tokens, such as comments, which are valid almost
+	// anywhere. Extras that are defined as rules rather than as tokens are
+	// non-terminal symbols, and are only left out by `ExcludeNonTerminals`.
+	ExcludeExtras bool
+
+	// Leave out non-terminal symbols, so that only tokens are returned.
+	ExcludeNonTerminals bool
+}
+
+// Get the symbols that are valid at the given byte offset, according to the
+// grammar and the parse state that the parser was in when it reached the
+// offset.
+//
+// Only the text before the offset is taken into account, so for completing
+// a partially typed word, pass the offset at which the word starts.
+//
+// This can be used for grammar-aware keyword completion:
+//
+//	symbols := tree.ExpectedSymbols(offset, &tree_sitter.ExpectedSymbolsOptions{
+//		ExcludeHidden:       true,
+//		ExcludeExtras:       true,
+//		ExcludeNonTerminals: true,
+//	})
+//	for _, symbol := range symbols {
+//		if !symbol.Named {
+//			// symbol.Kind is a keyword or punctuation, e.g. `return`.
+//		}
+//	}
+func (t *Tree) ExpectedSymbols(offset uint, options *ExpectedSymbolsOptions) []ExpectedSymbol {
+	return t.Language().expectedSymbols(t.parseStateAt(offset), options)
+}
+
+// Get the parse state that the parser was in when it reached the given
+// offset.
+func (t *Tree) parseStateAt(offset uint) uint16 {
+	language := t.Language()
+	root := t.RootNode()
+
+	// A token's parse state is the state in which it was lexed, which is
+	// the state right after the preceding token was consumed.
+	if next := firstLeafAfter(root, offset); next != nil {
+		if state := next.ParseState(); language.isValidParseState(state) {
+			return state
+		}
+	}
+
+	prev := lastLeafBefore(root, offset)
+	if prev == nil {
+		// The initial parse state.
+		return 1
+	}
+	for n := prev; n != nil && n.EndByte() == prev.EndByte(); n = n.Parent() {
+		if state := n.ParseState(); language.isValidParseState(state) {
+			if next := language.NextState(state, n.GrammarId()); language.isValidParseState(next) {
+				return next
+			}
+		}
+	}
+
+	// Tokens inside of an `ERROR` node don't have a useful state, so use the
+	// state before the error instead.
+	for n := prev; n != nil; n = n.Parent() {
+		if n.IsError() {
+			return stateBefore(language, n)
+		}
+	}
+	return 1
+}
+
+// Get the first token that starts at or after the given offset.
+func firstLeafAfter(node *Node, offset uint) *Node {
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child.EndByte() < offset || (child.EndByte() == offset && child.StartByte() < offset) {
+			continue
+		}
+		if child.ChildCount() == 0 {
+			if child.StartByte() >= offset {
+				return child
+			}
+			continue
+		}
+		if leaf := firstLeafAfter(child, offset); leaf != nil {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Get the last non-extra token that ends at or before the given offset.
+func lastLeafBefore(node *Node, offset uint) *Node {
+	for i := int(node.ChildCount()) - 1; i >= 0; i-- {
+		child := node.Child(uint(i))
+		if child.IsExtra() || (child.StartByte() >= offset && child.EndByte() > offset) {
+			continue
+		}
+		if child.ChildCount() == 0 {
+			if child.EndByte() <= offset {
+				return child
+			}
+			continue
+		}
+		if leaf := lastLeafBefore(child, offset); leaf != nil {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Get the symbols that are valid in the given parse state.
+func (l *Language) expectedSymbols(state uint16, options *ExpectedSymbolsOptions) []ExpectedSymbol {
+	if options == nil {
+		options = &ExpectedSymbolsOptions{}
+	}
+
+	iter := l.LookaheadIterator(state)
+	if iter == nil {
+		return nil
+	}
+	defer iter.Close()
+
+	tokenCount := l.tokenCount()
+	var symbols []ExpectedSymbol
+	for _, symbol := range iter.Iter() {
+		if options.ExcludeHidden && !l.NodeKindIsVisible(symbol) {
+			continue
+		}
+		if options.ExcludeExtras && l.isExtraToken(state, symbol) {
+			continue
+		}
+		if options.ExcludeNonTerminals && symbol >= tokenCount {
+			continue
+		}
+		symbols = append(symbols, ExpectedSymbol{
+			Id:    symbol,
+			Kind:  l.NodeKindForId(symbol),
+			Named: l.NodeKindIsNamed(symbol),
+		})
+	}
+	return symbols
+}
@@ -0,0 +1,107 @@
+package tree_sitter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func expectedKinds(symbols []ExpectedSymbol) []string {
+	var kinds []string
+	for _, symbol := range symbols {
+		kinds = append(kinds, symbol.Kind)
+	}
+	return kinds
+}
+
+func TestTreeExpectedSymbols(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("rust"))
+
+	source := "fn main() { let x = 1; }"
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	options := &ExpectedSymbolsOptions{
+		ExcludeHidden:       true,
+		ExcludeExtras:       true,
+		ExcludeNonTerminals: true,
+	}
+
+	// After `let`, a pattern or `mut` may follow, but not another statement.
+	kinds := expectedKinds(tree.ExpectedSymbols(16, options))
+	assert.Contains(t, kinds, "mutable_specifier")
+	assert.Contains(t, kinds, "identifier")
+	assert.NotContains(t, kinds, "let")
+	assert.NotContains(t, kinds, "line_comment")
+
+	// At the start of a statement, `let` is valid.
+	kinds = expectedKinds(tree.ExpectedSymbols(12, options))
+	assert.Contains(t, kinds, "let")
+	assert.Contains(t, kinds, "return")
+	assert.Contains(t, kinds, "}")
+
+	// Whitespace doesn't matter.
+	assert.Equal(t, kinds, expectedKinds(tree.ExpectedSymbols(11, options)))
+
+	// At the start of the file, items are valid.
+	kinds = expectedKinds(tree.ExpectedSymbols(0, options))
+	assert.Contains(t, kinds, "fn")
+	assert.Contains(t, kinds, "struct")
+	assert.NotContains(t, kinds, "}")
+
+	// At the end of the file, the state after the last token is used.
+	kinds = expectedKinds(tree.ExpectedSymbols(uint(len(source)), options))
+	assert.Contains(t, kinds, "fn")
+}
+
+func TestTreeExpectedSymbolsOptions(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	language := getLanguage("rust")
+	parser.SetLanguage(language)
+
+	tree := parser.Parse([]byte("fn main() { }"), nil)
+	defer tree.Close()
+
+	all := tree.ExpectedSymbols(12, nil)
+	filtered := tree.ExpectedSymbols(12, &ExpectedSymbolsOptions{
+		ExcludeHidden:       true,
+		ExcludeExtras:       true,
+		ExcludeNonTerminals: true,
+	})
+	assert.Greater(t, len(all), len(filtered))
+
+	allKinds := expectedKinds(all)
+	filteredKinds := expectedKinds(filtered)
+	assert.Contains(t, allKinds, "line_comment")
+	assert.NotContains(t, filteredKinds, "line_comment")
+	assert.Contains(t, allKinds, "let_declaration")
+	assert.NotContains(t, filteredKinds, "let_declaration")
+
+	for _, symbol := range all {
+		assert.Equal(t, language.NodeKindForId(symbol.Id), symbol.Kind)
+		assert.Equal(t, language.NodeKindIsNamed(symbol.Id), symbol.Named)
+	}
+	for _, symbol := range filtered {
+		assert.True(t, language.NodeKindIsVisible(symbol.Id))
+	}
+}
+
+func TestTreeExpectedSymbolsAfterError(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	source := `{"a": 1 2`
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	kinds := expectedKinds(tree.ExpectedSymbols(uint(len(source)), &ExpectedSymbolsOptions{
+		ExcludeHidden:       true,
+		ExcludeNonTerminals: true,
+	}))
+	assert.NotEmpty(t, kinds)
+}
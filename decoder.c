@@ -0,0 +1,73 @@
+#include "decoder.h"
+
+extern uint32_t goDecode(void *decoder, uint8_t *string, uint32_t length, int32_t *code_point);
+
+// The Go decoder that is used by the current thread's parse. Decode functions
+// don't receive the input's payload, and a parse runs entirely on one thread.
+static _Thread_local void *current_go_decoder;
+
+static inline uint32_t decode_with_table(
+  const TSGoDecodeTable *table,
+  const uint8_t *string,
+  uint32_t length,
+  int32_t *code_point
+) {
+  if (length == 0) {
+    *code_point = -1;
+    return 0;
+  }
+  int32_t single = table->single[string[0]];
+  if (single != TS_GO_LEAD_BYTE) {
+    *code_point = single;
+    return 1;
+  }
+  if (length < 2) {
+    *code_point = -1;
+    return 1;
+  }
+  *code_point = ts_go_decode_pair(table, string[0], string[1]);
+  return *code_point == -1 ? 1 : 2;
+}
+
+uint32_t ts_go_decode_latin1(const uint8_t *string, uint32_t length, int32_t *code_point) {
+  // Every byte is the code point with the same value.
+  if (length == 0) {
+    *code_point = -1;
+    return 0;
+  }
+  *code_point = string[0];
+  return 1;
+}
+
+uint32_t ts_go_decode_windows_1252(const uint8_t *string, uint32_t length, int32_t *code_point) {
+  return decode_with_table(&ts_go_windows_1252_table, string, length, code_point);
+}
+
+uint32_t ts_go_decode_shift_jis(const uint8_t *string, uint32_t length, int32_t *code_point) {
+  return decode_with_table(&ts_go_shift_jis_table, string, length, code_point);
+}
+
+uint32_t ts_go_decode_euc_kr(const uint8_t *string, uint32_t length, int32_t *code_point) {
+  return decode_with_table(&ts_go_euc_kr_table, string, length, code_point);
+}
+
+uint32_t ts_go_decode_with_go_decoder(const uint8_t *string, uint32_t length, int32_t *code_point) {
+  return goDecode(current_go_decoder, (uint8_t *)string, length, code_point);
+}
+
+TSTree *ts_go_parse_with_go_decoder(
+  TSParser *parser,
+  const TSTree *old_tree,
+  TSInput input,
+  TSParseOptions options,
+  void *decoder
+) {
+  // Parsers can be nested, for example when a read callback parses another
+  // document, so the previous decoder is restored afterwards.
+  void *previous_decoder = current_go_decoder;
+  current_go_decoder = decoder;
+  input.decode = ts_go_decode_with_go_decoder;
+  TSTree *tree = ts_parser_parse_with_options(parser, old_tree, input, options);
+  current_go_decoder = previous_decoder;
+  return tree;
+}
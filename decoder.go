@@ -0,0 +1,128 @@
+package tree_sitter
+
+//go:generate go run -C internal/gendecodertables . ../../decoder_tables.c
+
+/*
+#cgo CFLAGS: -Iinclude -Isrc -std=c11 -D_POSIX_C_SOURCE=200112L -D_DEFAULT_SOURCE
+#include "decoder.h"
+*/
+import "C"
+
+import (
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/mattn/go-pointer"
+)
+
+var (
+	// Decodes ISO-8859-1 (Latin-1) text.
+	Latin1Decoder Decoder = &tableDecoder{decode: (*[0]byte)(C.ts_go_decode_latin1)}
+
+	// Decodes Windows-1252 text.
+	Windows1252Decoder Decoder = &tableDecoder{table: &C.ts_go_windows_1252_table, decode: (*[0]byte)(C.ts_go_decode_windows_1252)}
+
+	// Decodes Shift-JIS text.
+	ShiftJISDecoder Decoder = &tableDecoder{table: &C.ts_go_shift_jis_table, decode: (*[0]byte)(C.ts_go_decode_shift_jis)}
+
+	// Decodes EUC-KR text, including the extensions of code page 949.
+	EUCKRDecoder Decoder = &tableDecoder{table: &C.ts_go_euc_kr_table, decode: (*[0]byte)(C.ts_go_decode_euc_kr)}
+)
+
+// A [Decoder] for an encoding whose characters are one or two bytes long.
+//
+// Its lookup table is generated C data, which is also read by a C decode
+// function, so that parsing doesn't call back into Go for each character.
+// Latin-1 has no table, since its bytes are their own code points.
+type tableDecoder struct {
+	table  *C.TSGoDecodeTable
+	decode *[0]byte
+}
+
+// Parse text in a non-Unicode encoding provided in chunks by a callback.
+//
+// This is like [Parser.ParseCustomEncoding], but takes a [Decoder]
+// implemented in Go instead of a C function. The built-in decoders, such as
+// [ShiftJISDecoder], are decoded in C; other decoders are called from C for
+// every character, which makes parsing noticeably slower.
+//
+// The byte offsets of the resulting tree refer to the encoded text. Use
+// [Node.DecodedText] to get the text of a node as UTF8.
+//
+// # Arguments:
+//   - `callback` A function that takes a byte offset and position and returns a slice of
+//     encoded text starting at that byte offset and position. The slices can be of any
+//     length. If the given position is at the end of the text, the callback should return an
+//     empty slice.
+//   - `old_tree` A previous syntax tree parsed from the same document. If the text of the
+//     document has changed since `old_tree` was created, then you must edit `old_tree` to match
+//     the new text using [Tree.Edit].
+//   - `options` Options for parsing the text. This can be used to set a progress callback.
+//   - `decoder` The decoder for the text's encoding.
+func (p *Parser) ParseWithDecoder(
+	callback func(int, Point) []byte,
+	oldTree *Tree,
+	options *ParseOptions,
+	decoder Decoder,
+) *Tree {
+	if d, ok := decoder.(*tableDecoder); ok {
+		return p.parseCustomEncoding(callback, oldTree, options, d.decode, nil)
+	}
+	return p.parseCustomEncoding(callback, oldTree, options, nil, decoder)
+}
+
+// Get this node's text, decoded from the given source code to UTF8.
+//
+// This is like [Node.Utf8Text], for trees that were parsed with
+// [Parser.ParseWithDecoder]. Bytes that can't be decoded are replaced with
+// [utf8.RuneError].
+func (n *Node) DecodedText(source []byte, decoder Decoder) string {
+	text := source[n.StartByte():n.EndByte()]
+	var builder strings.Builder
+	for len(text) > 0 {
+		codePoint, bytesRead := decoder.Decode(text)
+		if codePoint < 0 || bytesRead == 0 {
+			codePoint, bytesRead = utf8.RuneError, 1
+		}
+		builder.WriteRune(codePoint)
+		text = text[min(int(bytesRead), len(text)):]
+	}
+	return builder.String()
+}
+
+// This C function is passed to Tree-sitter as the decode function of
+// [Parser.ParseWithDecoder], for decoders that aren't built in.
+//
+//export goDecode
+func goDecode(decoder unsafe.Pointer, data *C.uint8_t, length C.uint32_t, codePoint *C.int32_t) C.uint32_t {
+	d := pointer.Restore(decoder).(Decoder)
+	cp, bytesRead := d.Decode(unsafe.Slice((*byte)(data), int(length)))
+	if bytesRead == 0 && length > 0 {
+		// The lexer would never advance, so skip the byte as invalid.
+		cp, bytesRead = -1, 1
+	}
+	*codePoint = C.int32_t(cp)
+	return min(C.uint32_t(bytesRead), length)
+}
+
+func (d *tableDecoder) Decode(data []byte) (codePoint int32, bytesRead uint32) {
+	if len(data) == 0 {
+		return -1, 0
+	}
+	if d.table == nil {
+		return int32(data[0]), 1
+	}
+	single := int32(d.table.single[data[0]])
+	if single != C.TS_GO_LEAD_BYTE {
+		return single, 1
+	}
+	if len(data) < 2 {
+		return -1, 1
+	}
+	codePoint = int32(C.ts_go_decode_pair(d.table, C.uint8_t(data[0]), C.uint8_t(data[1])))
+	if codePoint < 0 {
+		return -1, 1
+	}
+	return codePoint, 2
+}
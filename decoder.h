@@ -0,0 +1,60 @@
+#ifndef TREE_SITTER_GO_DECODER_H_
+#define TREE_SITTER_GO_DECODER_H_
+
+#include <tree_sitter/api.h>
+
+// A value in a decode table that marks the first byte of a two-byte character.
+#define TS_GO_LEAD_BYTE -2
+
+// A lookup table for an encoding whose characters are one or two bytes long.
+typedef struct {
+  // The code point of each byte, -1 for an invalid byte, or
+  // `TS_GO_LEAD_BYTE`.
+  int32_t single[256];
+
+  // The range of the first and second bytes of two-byte characters.
+  uint8_t min_lead;
+  uint8_t max_lead;
+  uint8_t min_trail;
+  uint8_t max_trail;
+
+  // The code point of each two-byte character, by its first and then its
+  // second byte within the ranges above, or 0 for an invalid pair.
+  const uint16_t *pairs;
+} TSGoDecodeTable;
+
+// The tables in decoder_tables.c, which is generated by
+// internal/gendecodertables.
+extern const TSGoDecodeTable ts_go_windows_1252_table;
+extern const TSGoDecodeTable ts_go_shift_jis_table;
+extern const TSGoDecodeTable ts_go_euc_kr_table;
+
+// Get the code point of a two-byte character, or -1 if it is invalid.
+static inline int32_t ts_go_decode_pair(const TSGoDecodeTable *table, uint8_t lead, uint8_t trail) {
+  if (lead < table->min_lead || lead > table->max_lead || trail < table->min_trail || trail > table->max_trail) {
+    return -1;
+  }
+  uint32_t index = (lead - table->min_lead) * (table->max_trail - table->min_trail + 1) + trail - table->min_trail;
+  uint16_t code_point = table->pairs[index];
+  return code_point == 0 ? -1 : code_point;
+}
+
+uint32_t ts_go_decode_latin1(const uint8_t *string, uint32_t length, int32_t *code_point);
+
+uint32_t ts_go_decode_windows_1252(const uint8_t *string, uint32_t length, int32_t *code_point);
+
+uint32_t ts_go_decode_shift_jis(const uint8_t *string, uint32_t length, int32_t *code_point);
+
+uint32_t ts_go_decode_euc_kr(const uint8_t *string, uint32_t length, int32_t *code_point);
+
+uint32_t ts_go_decode_with_go_decoder(const uint8_t *string, uint32_t length, int32_t *code_point);
+
+TSTree *ts_go_parse_with_go_decoder(
+  TSParser *parser,
+  const TSTree *old_tree,
+  TSInput input,
+  TSParseOptions options,
+  void *decoder
+);
+
+#endif // TREE_SITTER_GO_DECODER_H_
@@ -0,0 +1,161 @@
+package tree_sitter_test
+
+import (
+	"context"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A decoder implemented in Go, which is called from C for each character.
+type utf8Decoder struct{}
+
+func (utf8Decoder) Decode(data []byte) (int32, uint32) {
+	r, size := utf8.DecodeRune(data)
+	if r == utf8.RuneError && size <= 1 {
+		return -1, uint32(size)
+	}
+	return r, uint32(size)
+}
+
+func parseWithDecoder(parser *Parser, source []byte, decoder Decoder) *Tree {
+	return parser.ParseWithDecoder(func(i int, _ Point) []byte {
+		return source[min(i, len(source)):]
+	}, nil, nil, decoder)
+}
+
+func TestParserParseWithDecoder(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("python"))
+
+	for _, test := range []struct {
+		name    string
+		decoder Decoder
+		source  []byte
+		text    string
+	}{
+		{"latin1", Latin1Decoder, []byte("caf\xe9 = 'cr\xe8me'\n"), "café"},
+		// 名前 = 'こんにちは'
+		{"shift_jis", ShiftJISDecoder, []byte("\x96\xbc\x91O = '\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd'\n"), "名前"},
+		// 이름 = '안녕하세요'
+		{"euc_kr", EUCKRDecoder, []byte("\xc0\xcc\xb8\xa7 = '\xbe\xc8\xb3\xe7\xc7\xcf\xbc\xbc\xbf\xe4'\n"), "이름"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			source := test.source
+			tree := parseWithDecoder(parser, source, test.decoder)
+			defer tree.Close()
+			root := tree.RootNode()
+			assert.Equal(t, "(module (expression_statement (assignment left: (identifier) right: (string (string_start) (string_content) (string_end)))))", root.ToSexp())
+			assert.Equal(t, uint(len(source)), root.EndByte())
+
+			identifier := root.NamedDescendantForByteRange(0, 0)
+			assert.Equal(t, "identifier", identifier.Kind())
+			assert.Equal(t, test.text, identifier.DecodedText(source, test.decoder))
+			// The columns are counted in bytes of the encoded text.
+			assert.Equal(t, Point{0, identifier.EndByte()}, identifier.EndPosition())
+		})
+	}
+}
+
+func TestParserParseWithDecoderWindows1252(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	source := []byte("[\"\x93quoted\x94 \x80\"]")
+	tree := parseWithDecoder(parser, source, Windows1252Decoder)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (string (string_content))))", tree.RootNode().ToSexp())
+	content := tree.RootNode().NamedChild(0).NamedChild(0).NamedChild(0)
+	assert.Equal(t, "“quoted” €", content.DecodedText(source, Windows1252Decoder))
+}
+
+func TestParserParseWithGoDecoder(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	source := []byte("const ñ = `→ ${x}`;\n")
+	expected := parser.Parse(source, nil)
+	defer expected.Close()
+
+	tree := parseWithDecoder(parser, source, utf8Decoder{})
+	defer tree.Close()
+	assert.Equal(t, expected.RootNode().ToSexp(), tree.RootNode().ToSexp())
+	assert.Empty(t, expected.ChangedRanges(tree))
+	assert.Equal(t, "ñ", tree.RootNode().NamedDescendantForByteRange(6, 6).DecodedText(source, utf8Decoder{}))
+}
+
+// A decoder that never consumes any bytes.
+type stuckDecoder struct{}
+
+func (stuckDecoder) Decode(data []byte) (int32, uint32) {
+	return 'a', 0
+}
+
+func TestParserParseWithStuckDecoder(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	// Each byte is skipped as invalid instead of hanging the parser.
+	tree := parseWithDecoder(parser, []byte("[1]"), stuckDecoder{})
+	defer tree.Close()
+	assert.Equal(t, uint(3), tree.RootNode().EndByte())
+	assert.True(t, tree.RootNode().HasError())
+}
+
+func TestParserParseWithDecoderContext(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree, err := parser.ParseWithDecoderContext(ctx, func(int, Point) []byte {
+		return []byte("[1]")
+	}, nil, nil, Latin1Decoder)
+	assert.Nil(t, tree)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestDecoders(t *testing.T) {
+	codePoint, bytesRead := ShiftJISDecoder.Decode([]byte{0x82, 0xa0})
+	assert.Equal(t, 'あ', codePoint)
+	assert.Equal(t, uint32(2), bytesRead)
+
+	// An incomplete character can't be decoded.
+	codePoint, bytesRead = ShiftJISDecoder.Decode([]byte{0x82})
+	assert.Equal(t, int32(-1), codePoint)
+	assert.Equal(t, uint32(1), bytesRead)
+
+	codePoint, bytesRead = ShiftJISDecoder.Decode([]byte{0xb1})
+	assert.Equal(t, 'ｱ', codePoint)
+	assert.Equal(t, uint32(1), bytesRead)
+
+	codePoint, bytesRead = EUCKRDecoder.Decode([]byte{0xb0, 0xa1, 'x'})
+	assert.Equal(t, '가', codePoint)
+	assert.Equal(t, uint32(2), bytesRead)
+
+	// A character from the extensions of code page 949.
+	codePoint, bytesRead = EUCKRDecoder.Decode([]byte{0x8c, 0x63})
+	assert.Equal(t, '똠', codePoint)
+	assert.Equal(t, uint32(2), bytesRead)
+
+	// A lead byte followed by a byte that can't end a character.
+	codePoint, bytesRead = ShiftJISDecoder.Decode([]byte{0x82, 0x20})
+	assert.Equal(t, int32(-1), codePoint)
+	assert.Equal(t, uint32(1), bytesRead)
+
+	codePoint, _ = Latin1Decoder.Decode([]byte{0xff})
+	assert.Equal(t, 'ÿ', codePoint)
+	codePoint, _ = Windows1252Decoder.Decode([]byte{0x80})
+	assert.Equal(t, '€', codePoint)
+	codePoint, _ = Windows1252Decoder.Decode([]byte{0x81})
+	assert.Equal(t, int32(-1), codePoint)
+	codePoint, _ = Windows1252Decoder.Decode([]byte{})
+	assert.Equal(t, int32(-1), codePoint)
+}
@@ -0,0 +1,284 @@
+package tree_sitter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The maximum number of expected symbols that are listed in the message of
+// a [Diagnostic].
+const maxExpectedInMessage = 8
+
+// The kind of a syntax problem that is reported by [Tree.Diagnostics].
+type DiagnosticKind int
+
+const (
+	// Text that the parser could not fit into the grammar, represented by an
+	// `ERROR` node.
+	DiagnosticUnexpected DiagnosticKind = iota
+
+	// A token that the parser inserted in order to recover from an error,
+	// represented by a *missing* node.
+	DiagnosticMissing
+)
+
+// A syntax problem in a [Tree].
+type Diagnostic struct {
+	Kind DiagnosticKind
+
+	// The `ERROR` node or the missing node. It is only valid while the tree
+	// is open.
+	Node Node
+
+	// The range of the node. The range of a missing node is empty.
+	Range Range
+
+	// The kinds of the tokens that would have been valid where the problem
+	// occurred, i.e. after the last token that the parser accepted. Anonymous
+	// tokens are quoted, e.g. `';'`. Extra tokens, such as comments, are left
+	// out, since they are valid almost anywhere.
+	//
+	// For a missing node, this is only the kind of the missing token.
+	Expected []string
+
+	// A human-readable description of the problem, e.g. `expected ';'` or
+	// `unexpected number, expected ':'`, which names the token at which the
+	// parser failed.
+	Message string
+}
+
+// Collect the syntax problems in the tree, in document order.
+//
+// The contents of `ERROR` nodes are not searched for further problems.
+func (t *Tree) Diagnostics() []Diagnostic {
+	var diagnostics []Diagnostic
+	language := t.Language()
+	root := t.RootNode()
+
+	cursor := t.Walk()
+	defer cursor.Close()
+	for {
+		node := cursor.Node()
+		descend := node.HasError() && !node.IsError()
+		if node.IsError() {
+			diagnostics = append(diagnostics, newUnexpectedDiagnostic(language, node, root))
+		} else if node.IsMissing() {
+			diagnostics = append(diagnostics, newMissingDiagnostic(node))
+		}
+
+		if descend && cursor.GotoFirstChild() {
+			continue
+		}
+		for !cursor.GotoNextSibling() {
+			if !cursor.GotoParent() {
+				return diagnostics
+			}
+		}
+	}
+}
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case DiagnosticUnexpected:
+		return "unexpected"
+	case DiagnosticMissing:
+		return "missing"
+	default:
+		return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+	}
+}
+
+// Format the diagnostic with its one-based row and column, e.g.
+// `expected ';' at 12:4`.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s at %d:%d", d.Message, d.Range.StartPoint.Row+1, d.Range.StartPoint.Column+1)
+}
+
+func newMissingDiagnostic(node *Node) Diagnostic {
+	expected := formatSymbolKind(node.Kind(), node.IsNamed())
+	return Diagnostic{
+		Kind:     DiagnosticMissing,
+		Node:     *node,
+		Range:    node.Range(),
+		Expected: []string{expected},
+		Message:  "expected " + expected,
+	}
+}
+
+func newUnexpectedDiagnostic(language *Language, node *Node, root *Node) Diagnostic {
+	failed, state := findFailure(language, node, root)
+
+	var message strings.Builder
+	message.WriteString("unexpected ")
+	var unexpected string
+	switch {
+	case failed == nil:
+		message.WriteString("end of input")
+	case failed.Id() == node.Id():
+		message.WriteString("input")
+	default:
+		unexpected = formatSymbolKind(failed.Kind(), failed.IsNamed())
+		message.WriteString(unexpected)
+	}
+
+	var expected []string
+	for _, symbol := range expectedSymbols(language, state) {
+		// A symbol with the same kind as the unexpected token, such as an
+		// alias of it, is not an alternative to it.
+		if symbol != unexpected {
+			expected = append(expected, symbol)
+		}
+	}
+	for i, symbol := range expected {
+		if i == 0 {
+			message.WriteString(", expected ")
+			if len(expected) > 1 {
+				message.WriteString("one of ")
+			}
+		} else {
+			message.WriteString(", ")
+		}
+		if i == maxExpectedInMessage {
+			message.WriteString("...")
+			break
+		}
+		message.WriteString(symbol)
+	}
+
+	return Diagnostic{
+		Kind:     DiagnosticUnexpected,
+		Node:     *node,
+		Range:    node.Range(),
+		Expected: expected,
+		Message:  message.String(),
+	}
+}
+
+// Find the token at which the parser failed within the given `ERROR` node,
+// and the parse state that it was in after the last token that it accepted.
+//
+// An error can start with subtrees that the parser accepted before it got
+// stuck, such as the key in `{"a" 1}`, so these are skipped. If the parser
+// accepted all of the error's children, it failed at the token after the
+// error, which is `nil` at the end of the input. If the error has no
+// children, it consists of characters that could not be lexed, and the error
+// node itself is returned.
+func findFailure(language *Language, node *Node, root *Node) (*Node, uint16) {
+	state := stateBefore(language, node)
+	if node.ChildCount() == 0 {
+		return node, state
+	}
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if !language.isValidParseState(state) {
+			// Continue from the state in which the child's first token was
+			// lexed, which is less precise.
+			state = firstLeaf(child).ParseState()
+		}
+		var failed *Node
+		if state, failed = advanceState(language, state, child); failed != nil {
+			return failed, state
+		}
+	}
+	next := firstLeafAfter(root, node.EndByte())
+	if !language.isValidParseState(state) && next != nil {
+		state = next.ParseState()
+	}
+	return next, state
+}
+
+// Get the parse state after the parser accepted the given node in the given
+// state, or the token within the node that it failed at.
+//
+// The state after a token that can only be accepted after a reduction isn't
+// known, since the reduction depends on the parse stack, so 0 is returned
+// instead.
+func advanceState(language *Language, state uint16, node *Node) (uint16, *Node) {
+	if node.IsExtra() {
+		return state, nil
+	}
+	if node.IsError() {
+		return state, firstLeaf(node)
+	}
+
+	symbol := node.GrammarId()
+	if node.ChildCount() == 0 {
+		if !language.isValidToken(state, symbol) {
+			return state, node
+		}
+		return language.NextState(state, symbol), nil
+	}
+
+	// A node without errors that is valid in this state was accepted as a
+	// whole.
+	if next := language.NextState(state, symbol); language.isValidParseState(next) && !node.HasError() {
+		return next, nil
+	}
+	for i := uint(0); i < node.ChildCount(); i++ {
+		var failed *Node
+		if state, failed = advanceState(language, state, node.Child(i)); failed != nil {
+			return state, failed
+		}
+		if !language.isValidParseState(state) {
+			return 0, nil
+		}
+	}
+	return state, nil
+}
+
+// Get the parse state that the parser was in right before the given `ERROR`
+// node.
+func stateBefore(language *Language, node *Node) uint16 {
+	// The first token of the error was lexed in the state in which the
+	// parser failed to continue.
+	if leaf := firstLeaf(node); leaf.Id() != node.Id() {
+		if state := leaf.ParseState(); language.isValidParseState(state) {
+			return state
+		}
+	}
+	for n := node; n != nil; n = n.Parent() {
+		if prev := n.PrevSibling(); prev != nil {
+			if state := prev.NextParseState(); language.isValidParseState(state) {
+				return state
+			}
+			break
+		}
+	}
+	// The initial parse state.
+	return 1
+}
+
+// Get the kinds of the visible tokens that are valid in the given parse
+// state, leaving out extras.
+func expectedSymbols(language *Language, state uint16) []string {
+	symbols := language.expectedSymbols(state, &ExpectedSymbolsOptions{
+		ExcludeHidden:       true,
+		ExcludeExtras:       true,
+		ExcludeNonTerminals: true,
+	})
+
+	seen := make(map[string]bool)
+	var expected []string
+	for _, symbol := range symbols {
+		kind := formatSymbolKind(symbol.Kind, symbol.Named)
+		if !seen[kind] {
+			seen[kind] = true
+			expected = append(expected, kind)
+		}
+	}
+	return expected
+}
+
+func firstLeaf(node *Node) *Node {
+	for node != nil && node.ChildCount() > 0 {
+		node = node.Child(0)
+	}
+	return node
+}
+
+func formatSymbolKind(kind string, named bool) string {
+	if named {
+		return kind
+	}
+	return "'" + kind + "'"
+}
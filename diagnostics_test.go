@@ -0,0 +1,75 @@
+package tree_sitter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestTreeDiagnosticsMissing(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("rust"))
+
+	tree := parser.Parse([]byte("fn main() {\n    let x = 1\n}"), nil)
+	defer tree.Close()
+
+	diagnostics := tree.Diagnostics()
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, DiagnosticMissing, diagnostics[0].Kind)
+	assert.Equal(t, []string{"';'"}, diagnostics[0].Expected)
+	assert.Equal(t, "expected ';'", diagnostics[0].Message)
+	assert.Equal(t, "expected ';' at 2:14", diagnostics[0].String())
+	assert.Equal(t, diagnostics[0].Range.StartByte, diagnostics[0].Range.EndByte)
+	assert.True(t, diagnostics[0].Node.IsMissing())
+}
+
+func TestTreeDiagnosticsUnexpected(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	tree := parser.Parse([]byte(`{"a" 1}`), nil)
+	defer tree.Close()
+
+	diagnostics := tree.Diagnostics()
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, DiagnosticUnexpected, diagnostics[0].Kind)
+	assert.True(t, diagnostics[0].Node.IsError())
+	assert.Equal(t, uint(1), diagnostics[0].Range.StartByte)
+	// The key was accepted; the parser failed at the value.
+	assert.Equal(t, []string{"':'"}, diagnostics[0].Expected)
+	assert.Equal(t, "unexpected number, expected ':'", diagnostics[0].Message)
+
+	tree = parser.Parse([]byte(`{"a": 1 "b": 2}`), nil)
+	defer tree.Close()
+
+	diagnostics = tree.Diagnostics()
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, "(ERROR (pair key: (string (string_content)) value: (number)))", diagnostics[0].Node.ToSexp())
+	assert.Equal(t, "unexpected '\"', expected one of ',', '}' at 1:2", diagnostics[0].String())
+}
+
+func TestTreeDiagnosticsOrder(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	tree := parser.Parse([]byte("foo(a b);\nif (x { y }\n"), nil)
+	defer tree.Close()
+
+	diagnostics := tree.Diagnostics()
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, DiagnosticUnexpected, diagnostics[0].Kind)
+	assert.Equal(t, "(ERROR (identifier))", diagnostics[0].Node.ToSexp())
+	assert.Contains(t, diagnostics[0].Expected, "','")
+	assert.Contains(t, diagnostics[0].Expected, "')'")
+	assert.NotContains(t, diagnostics[0].Expected, "expression")
+	assert.Equal(t, DiagnosticMissing, diagnostics[1].Kind)
+	assert.Equal(t, "expected ')' at 2:6", diagnostics[1].String())
+
+	tree = parser.Parse([]byte("foo(a, b);"), nil)
+	defer tree.Close()
+	assert.Empty(t, tree.Diagnostics())
+}
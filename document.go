@@ -0,0 +1,165 @@
+package tree_sitter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// An error that is returned when the parser did not produce a syntax tree,
+// because it has no language assigned, or because parsing was cancelled or
+// timed out.
+var ErrParseFailed = errors.New("Failed to parse the text")
+
+// A source code document that owns its text and its syntax tree, and keeps
+// the two in sync as the text is edited.
+//
+// Each edit is applied to both the text and the tree, and the document is
+// then reparsed incrementally, so that callers don't have to compute
+// [InputEdit] values themselves.
+type Document struct {
+	parser *Parser
+	text   []byte
+	tree   *Tree
+}
+
+// Create a new document by parsing the given text with the given parser.
+//
+// The parser is not owned by the document: it is used for every reparse,
+// so it must not be closed while the document is in use. The document takes
+// ownership of `text`, which must not be modified afterwards.
+func NewDocument(parser *Parser, text []byte) (*Document, error) {
+	tree := parser.Parse(text, nil)
+	if tree == nil {
+		return nil, ErrParseFailed
+	}
+	return &Document{parser: parser, text: text, tree: tree}, nil
+}
+
+// Close the document's syntax tree.
+func (d *Document) Close() {
+	d.tree.Close()
+}
+
+// Get the current text of the document.
+//
+// The returned slice must not be modified, and is no longer valid after the
+// next edit.
+func (d *Document) Text() []byte {
+	return d.text
+}
+
+// Get the current syntax tree of the document.
+//
+// The tree is owned by the document, and is closed by the next edit. Use
+// [Tree.Clone] to keep it for longer.
+func (d *Document) Tree() *Tree {
+	return d.tree
+}
+
+// Get the root node of the document's current syntax tree.
+func (d *Document) RootNode() *Node {
+	return d.tree.RootNode()
+}
+
+// Replace the text between the byte offsets `startByte` and `oldEndByte`
+// with `newText`, reparse the document, and return the ranges whose
+// syntactic structure has changed, as described in [Tree.ChangedRanges].
+//
+// If reparsing fails, the text is still edited, and the returned error is
+// [ErrParseFailed]. The document remains usable, and the next edit will
+// reparse it again.
+func (d *Document) Edit(startByte, oldEndByte uint, newText []byte) ([]Range, error) {
+	if startByte > oldEndByte || oldEndByte > uint(len(d.text)) {
+		return nil, fmt.Errorf("Invalid edit range %d..%d for a text of length %d", startByte, oldEndByte, len(d.text))
+	}
+
+	startPosition, _ := d.PointForByte(startByte)
+	oldEndPosition, _ := d.PointForByte(oldEndByte)
+	edit := InputEdit{
+		StartByte:      startByte,
+		OldEndByte:     oldEndByte,
+		NewEndByte:     startByte + uint(len(newText)),
+		StartPosition:  startPosition,
+		OldEndPosition: oldEndPosition,
+		NewEndPosition: startPosition.Advance(newText),
+	}
+
+	text := make([]byte, 0, len(d.text)-int(oldEndByte-startByte)+len(newText))
+	text = append(text, d.text[:startByte]...)
+	text = append(text, newText...)
+	text = append(text, d.text[oldEndByte:]...)
+	d.text = text
+
+	d.tree.Edit(&edit)
+	return d.reparse()
+}
+
+// Replace the text between the points `start` and `oldEnd` with `newText`,
+// reparse the document, and return the ranges whose syntactic structure has
+// changed.
+//
+// This behaves like [Document.Edit], with positions given as rows and byte
+// columns.
+func (d *Document) EditPoints(start, oldEnd Point, newText []byte) ([]Range, error) {
+	startByte, err := d.ByteForPoint(start)
+	if err != nil {
+		return nil, err
+	}
+	oldEndByte, err := d.ByteForPoint(oldEnd)
+	if err != nil {
+		return nil, err
+	}
+	return d.Edit(startByte, oldEndByte, newText)
+}
+
+// Replace the whole text of the document, reparse it, and return the ranges
+// whose syntactic structure has changed.
+func (d *Document) SetText(text []byte) ([]Range, error) {
+	return d.Edit(0, uint(len(d.text)), text)
+}
+
+func (d *Document) reparse() ([]Range, error) {
+	newTree := d.parser.Parse(d.text, d.tree)
+	if newTree == nil {
+		// Parsing may have been cancelled halfway, and the next parse is for
+		// different text, so it must not be resumed.
+		d.parser.Reset()
+		return nil, ErrParseFailed
+	}
+	ranges := d.tree.ChangedRanges(newTree)
+	d.tree.Close()
+	d.tree = newTree
+	return ranges, nil
+}
+
+// Get the row and column of the given byte offset in the document's text.
+func (d *Document) PointForByte(offset uint) (Point, error) {
+	if offset > uint(len(d.text)) {
+		return Point{}, fmt.Errorf("Byte offset %d is out of range for a text of length %d", offset, len(d.text))
+	}
+	return Point{}.Advance(d.text[:offset]), nil
+}
+
+// Get the byte offset of the given row and column in the document's text.
+//
+// The column may point at the end of the row, but not past it.
+func (d *Document) ByteForPoint(point Point) (uint, error) {
+	var offset uint
+	for row := uint(0); row < point.Row; row++ {
+		newline := bytes.IndexByte(d.text[offset:], '\n')
+		if newline < 0 {
+			return 0, fmt.Errorf("Row %d is out of range for a text with %d rows", point.Row, row+1)
+		}
+		offset += uint(newline) + 1
+	}
+
+	lineLength := uint(len(d.text)) - offset
+	if newline := bytes.IndexByte(d.text[offset:], '\n'); newline >= 0 {
+		lineLength = uint(newline)
+	}
+	if point.Column > lineLength {
+		return 0, fmt.Errorf("Column %d is out of range for row %d of length %d", point.Column, point.Row, lineLength)
+	}
+	return offset + point.Column, nil
+}
@@ -0,0 +1,110 @@
+package tree_sitter_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestDocumentEdit(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	doc, err := NewDocument(parser, []byte("let a = 1;\nlet b = 2;\n"))
+	assert.Nil(t, err)
+	defer doc.Close()
+
+	ranges, err := doc.Edit(19, 20, []byte("foo(bar)"))
+	assert.Nil(t, err)
+	assert.Equal(t, "let a = 1;\nlet b = foo(bar);\n", string(doc.Text()))
+	assert.Equal(
+		t,
+		"(program (lexical_declaration (variable_declarator name: (identifier) value: (number))) "+
+			"(lexical_declaration (variable_declarator name: (identifier) value: (call_expression function: (identifier) arguments: (arguments (identifier))))))",
+		doc.RootNode().ToSexp(),
+	)
+	assert.Equal(t, []Range{{
+		StartByte:  19,
+		EndByte:    27,
+		StartPoint: NewPoint(1, 8),
+		EndPoint:   NewPoint(1, 16),
+	}}, ranges)
+
+	ranges, err = doc.EditPoints(NewPoint(0, 10), NewPoint(1, 0), []byte("\n\n// comment\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "let a = 1;\n\n// comment\nlet b = foo(bar);\n", string(doc.Text()))
+	assert.Equal(t, "comment", doc.RootNode().NamedChild(1).Kind())
+	assert.Equal(t, NewPoint(2, 0), doc.RootNode().NamedChild(1).StartPosition())
+	assert.Equal(t, NewPoint(3, 0), doc.RootNode().NamedChild(2).StartPosition())
+	assert.NotEmpty(t, ranges)
+
+	_, err = doc.Edit(5, 100, nil)
+	assert.NotNil(t, err)
+	_, err = doc.EditPoints(NewPoint(0, 11), NewPoint(0, 11), nil)
+	assert.NotNil(t, err)
+	_, err = doc.EditPoints(NewPoint(5, 0), NewPoint(5, 0), nil)
+	assert.NotNil(t, err)
+}
+
+func TestDocumentPositions(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	doc, err := NewDocument(parser, []byte("[\n  1,\n  2\n]"))
+	assert.Nil(t, err)
+	defer doc.Close()
+
+	for offset := uint(0); offset <= uint(len(doc.Text())); offset++ {
+		point, err := doc.PointForByte(offset)
+		assert.Nil(t, err)
+		byteOffset, err := doc.ByteForPoint(point)
+		assert.Nil(t, err)
+		assert.Equal(t, offset, byteOffset)
+	}
+
+	point, err := doc.PointForByte(8)
+	assert.Nil(t, err)
+	assert.Equal(t, NewPoint(2, 1), point)
+	point, err = doc.PointForByte(uint(len(doc.Text())))
+	assert.Nil(t, err)
+	assert.Equal(t, NewPoint(3, 1), point)
+	_, err = doc.PointForByte(uint(len(doc.Text())) + 1)
+	assert.NotNil(t, err)
+}
+
+func TestDocumentMatchesFreshParse(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	doc, err := NewDocument(parser, []byte("function f(a, b) {\n  return a + b;\n}\n"))
+	assert.Nil(t, err)
+	defer doc.Close()
+
+	random := rand.New(rand.NewSource(0))
+	for i := 0; i < 20; i++ {
+		text := doc.Text()
+		position := uint(random.Intn(len(text) + 1))
+		deletedLength := uint(random.Intn(len(text) - int(position) + 1))
+		_, err := doc.Edit(position, position+deletedLength, randWords(random, 3))
+		assert.Nil(t, err)
+
+		freshTree := parser.Parse(doc.Text(), nil)
+		assert.Equal(t, freshTree.RootNode().ToSexp(), doc.RootNode().ToSexp())
+		assert.Equal(t, freshTree.RootNode().EndPosition(), doc.RootNode().EndPosition())
+		freshTree.Close()
+	}
+}
+
+func TestDocumentWithoutLanguage(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+
+	doc, err := NewDocument(parser, []byte("a"))
+	assert.Nil(t, doc)
+	assert.ErrorIs(t, err, ErrParseFailed)
+}
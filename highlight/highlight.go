@@ -0,0 +1,1155 @@
+// Package highlight performs syntax highlighting with Tree-sitter queries.
+//
+// It is a port of the Rust `tree-sitter-highlight` crate, and understands the
+// same `highlights.scm`, `injections.scm` and `locals.scm` query files.
+package highlight
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const cancellationCheckInterval = 100
+
+var (
+	// Returned when highlighting was cancelled with the cancellation flag.
+	ErrCancelled = errors.New("highlighting was cancelled")
+
+	// Returned when a language could not be assigned to the parser.
+	ErrInvalidLanguage = errors.New("invalid language")
+)
+
+// The capture names that are used by the standard highlight queries that
+// ship with Tree-sitter grammars.
+var StandardCaptureNames = []string{
+	"attribute",
+	"boolean",
+	"carriage-return",
+	"comment",
+	"comment.documentation",
+	"constant",
+	"constant.builtin",
+	"constructor",
+	"constructor.builtin",
+	"embedded",
+	"error",
+	"escape",
+	"function",
+	"function.builtin",
+	"keyword",
+	"markup",
+	"markup.bold",
+	"markup.heading",
+	"markup.italic",
+	"markup.link",
+	"markup.link.url",
+	"markup.list",
+	"markup.list.checked",
+	"markup.list.numbered",
+	"markup.list.unchecked",
+	"markup.list.unnumbered",
+	"markup.quote",
+	"markup.raw",
+	"markup.raw.block",
+	"markup.raw.inline",
+	"markup.strikethrough",
+	"module",
+	"number",
+	"operator",
+	"property",
+	"property.builtin",
+	"punctuation",
+	"punctuation.bracket",
+	"punctuation.delimiter",
+	"punctuation.special",
+	"string",
+	"string.escape",
+	"string.regexp",
+	"string.special",
+	"string.special.symbol",
+	"tag",
+	"type",
+	"type.builtin",
+	"variable",
+	"variable.builtin",
+	"variable.member",
+	"variable.parameter",
+}
+
+// Indicates which highlight should be applied to a region of source code.
+//
+// The value is an index into the list of recognized names that was passed to
+// [HighlightConfiguration.Configure].
+type Highlight uint
+
+// Represents a single step in rendering a syntax-highlighted document.
+//
+// This is one of [Source], [HighlightStart] or [HighlightEnd].
+type HighlightEvent interface {
+	isHighlightEvent()
+}
+
+// A region of source code that should be rendered with the highlights that
+// are currently active.
+type Source struct {
+	StartByte uint
+	EndByte   uint
+}
+
+// The start of a highlighted region.
+type HighlightStart struct {
+	Highlight Highlight
+}
+
+// The end of the most recently started highlighted region.
+type HighlightEnd struct{}
+
+func (Source) isHighlightEvent()         {}
+func (HighlightStart) isHighlightEvent() {}
+func (HighlightEnd) isHighlightEvent()   {}
+
+// Contains the data needed to highlight code written in a particular language.
+//
+// This struct is immutable and can be shared between goroutines.
+type HighlightConfiguration struct {
+	Language     *tree_sitter.Language
+	LanguageName string
+	Query        *tree_sitter.Query
+
+	combinedInjectionsQuery       *tree_sitter.Query
+	localsPatternIndex            uint
+	highlightsPatternIndex        uint
+	highlightIndices              []*Highlight
+	nonLocalVariablePatterns      []bool
+	injectionContentCaptureIndex  *uint
+	injectionLanguageCaptureIndex *uint
+	localScopeCaptureIndex        *uint
+	localDefCaptureIndex          *uint
+	localDefValueCaptureIndex     *uint
+	localRefCaptureIndex          *uint
+}
+
+// Performs syntax highlighting, recognizing a given list of highlight names.
+//
+// For the best performance [Highlighter] values should be reused between
+// syntax highlighting calls. A separate highlighter is needed for each
+// goroutine that is performing highlighting.
+type Highlighter struct {
+	Parser  *tree_sitter.Parser
+	cursors []*tree_sitter.QueryCursor
+}
+
+// An iterator over the [HighlightEvent]s of a document.
+//
+// See [Highlighter.Highlight].
+type HighlightIter struct {
+	source             []byte
+	languageName       string
+	byteOffset         uint
+	highlighter        *Highlighter
+	injectionCallback  func(string) *HighlightConfiguration
+	cancellationFlag   *uintptr
+	layers             []*highlightIterLayer
+	iterCount          int
+	nextEvent          HighlightEvent
+	lastHighlightRange *highlightRange
+}
+
+type highlightRange struct {
+	start uint
+	end   uint
+	depth uint
+}
+
+type localDef struct {
+	name       string
+	valueRange [2]uint
+	highlight  *Highlight
+}
+
+type localScope struct {
+	inherits  bool
+	start     uint
+	end       uint
+	localDefs []*localDef
+}
+
+type peekedCapture struct {
+	match        tree_sitter.QueryMatch
+	captureIndex uint
+}
+
+type highlightIterLayer struct {
+	tree              *tree_sitter.Tree
+	cursor            *tree_sitter.QueryCursor
+	captures          tree_sitter.QueryCaptures
+	peeked            *peekedCapture
+	done              bool
+	config            *HighlightConfiguration
+	highlightEndStack []uint
+	scopeStack        []*localScope
+	ranges            []tree_sitter.Range
+	depth             uint
+}
+
+type sortKey struct {
+	offset  uint
+	isStart bool
+	depth   int
+}
+
+// Creates a [HighlightConfiguration] for a given [tree_sitter.Language] and
+// set of highlighting queries.
+//
+// # Arguments:
+//   - `language` The Tree-sitter [tree_sitter.Language] that should be used
+//     for parsing.
+//   - `name` The name of the language, which is used for injections that set
+//     `injection.self` or `injection.parent`.
+//   - `highlightsQuery` A string containing tree patterns for syntax
+//     highlighting. This should be non-empty, otherwise no syntax highlights
+//     will be added.
+//   - `injectionsQuery` A string containing tree patterns for injecting other
+//     languages into the document. This can be empty if no injections are
+//     desired.
+//   - `localsQuery` A string containing tree patterns for tracking local
+//     variable definitions and references. This can be empty if local
+//     variable tracking is not needed.
+//
+// Returns a [HighlightConfiguration] that can then be used with the
+// [Highlighter.Highlight] method.
+func NewHighlightConfiguration(
+	language *tree_sitter.Language,
+	name string,
+	highlightsQuery string,
+	injectionsQuery string,
+	localsQuery string,
+) (*HighlightConfiguration, error) {
+	// Concatenate the query strings, keeping track of the start offset of each section.
+	querySource := injectionsQuery + localsQuery + highlightsQuery
+	localsQueryOffset := uint(len(injectionsQuery))
+	highlightsQueryOffset := localsQueryOffset + uint(len(localsQuery))
+
+	// Construct a single query by concatenating the three query strings, but record the
+	// range of pattern indices that belong to each individual string.
+	query, err := tree_sitter.NewQuery(language, querySource)
+	if err != nil {
+		return nil, err
+	}
+	var localsPatternIndex, highlightsPatternIndex uint
+	for i := uint(0); i < query.PatternCount(); i++ {
+		patternOffset := query.StartByteForPattern(i)
+		if patternOffset < highlightsQueryOffset {
+			highlightsPatternIndex++
+			if patternOffset < localsQueryOffset {
+				localsPatternIndex++
+			}
+		}
+	}
+
+	// Construct a separate query just for dealing with the 'combined injections'.
+	// Disable the combined injection patterns in the main query.
+	combinedInjectionsQuery, err := tree_sitter.NewQuery(language, injectionsQuery)
+	if err != nil {
+		query.Close()
+		return nil, err
+	}
+	hasCombinedQueries := false
+	for i := uint(0); i < localsPatternIndex; i++ {
+		combined := false
+		for _, setting := range query.PropertySettings(i) {
+			if setting.Key == "injection.combined" {
+				combined = true
+				break
+			}
+		}
+		if combined {
+			hasCombinedQueries = true
+			query.DisablePattern(i)
+		} else {
+			combinedInjectionsQuery.DisablePattern(i)
+		}
+	}
+	if !hasCombinedQueries {
+		combinedInjectionsQuery.Close()
+		combinedInjectionsQuery = nil
+	}
+
+	// Find all of the highlighting patterns that are disabled for nodes that
+	// have been identified as local variables.
+	nonLocalVariablePatterns := make([]bool, query.PatternCount())
+	for i := range nonLocalVariablePatterns {
+		for _, predicate := range query.PropertyPredicates(uint(i)) {
+			if !predicate.Positive && predicate.Property.Key == "local" {
+				nonLocalVariablePatterns[i] = true
+				break
+			}
+		}
+	}
+
+	config := &HighlightConfiguration{
+		Language:                 language,
+		LanguageName:             name,
+		Query:                    query,
+		combinedInjectionsQuery:  combinedInjectionsQuery,
+		localsPatternIndex:       localsPatternIndex,
+		highlightsPatternIndex:   highlightsPatternIndex,
+		highlightIndices:         make([]*Highlight, len(query.CaptureNames())),
+		nonLocalVariablePatterns: nonLocalVariablePatterns,
+	}
+
+	// Store the numeric ids for all of the special captures.
+	for i, name := range query.CaptureNames() {
+		index := uint(i)
+		switch name {
+		case "injection.content":
+			config.injectionContentCaptureIndex = &index
+		case "injection.language":
+			config.injectionLanguageCaptureIndex = &index
+		case "local.definition":
+			config.localDefCaptureIndex = &index
+		case "local.definition-value":
+			config.localDefValueCaptureIndex = &index
+		case "local.reference":
+			config.localRefCaptureIndex = &index
+		case "local.scope":
+			config.localScopeCaptureIndex = &index
+		}
+	}
+
+	return config, nil
+}
+
+// Delete the underlying memory for the configuration's queries.
+func (c *HighlightConfiguration) Close() {
+	c.Query.Close()
+	if c.combinedInjectionsQuery != nil {
+		c.combinedInjectionsQuery.Close()
+	}
+}
+
+// Get a slice containing all of the highlight names used in the configuration.
+func (c *HighlightConfiguration) Names() []string {
+	return c.Query.CaptureNames()
+}
+
+// Set the list of recognized highlight names.
+//
+// Tree-sitter syntax-highlighting queries specify highlights in the form of
+// dot-separated highlight names like `punctuation.bracket` and
+// `function.method.builtin`. Consumers of these queries can choose to
+// recognize highlights with different levels of specificity. A recognized
+// name matches a capture name if each of its parts appears in the capture
+// name, so `function.builtin` matches `function.builtin.constructor` but not
+// `function.method`. The recognized name with the most parts wins.
+//
+// When highlighting, results are returned as [Highlight] values, which contain
+// the index of the matched highlight in this list of recognized names.
+func (c *HighlightConfiguration) Configure(recognizedNames []string) {
+	highlightIndices := make([]*Highlight, len(c.Query.CaptureNames()))
+	for i, captureName := range c.Query.CaptureNames() {
+		captureParts := strings.Split(captureName, ".")
+
+		var bestIndex *Highlight
+		bestMatchLen := 0
+		for j, recognizedName := range recognizedNames {
+			length := 0
+			matches := true
+			for _, part := range strings.Split(recognizedName, ".") {
+				length++
+				if !containsString(captureParts, part) {
+					matches = false
+					break
+				}
+			}
+			if matches && length > bestMatchLen {
+				highlight := Highlight(j)
+				bestIndex = &highlight
+				bestMatchLen = length
+			}
+		}
+		highlightIndices[i] = bestIndex
+	}
+	c.highlightIndices = highlightIndices
+}
+
+// Return the list of this configuration's capture names that are neither
+// present in the list of predefined 'canonical' names nor start with an
+// underscore (denoting 'private' captures used as part of capture internals).
+//
+// If `captureNames` is empty, [StandardCaptureNames] is used.
+func (c *HighlightConfiguration) NonconformantCaptureNames(captureNames []string) []string {
+	if len(captureNames) == 0 {
+		captureNames = StandardCaptureNames
+	}
+	var result []string
+	for _, name := range c.Names() {
+		if !strings.HasPrefix(name, "_") && !containsString(captureNames, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Create a new highlighter.
+func NewHighlighter() *Highlighter {
+	return &Highlighter{Parser: tree_sitter.NewParser()}
+}
+
+// Delete the underlying memory for the highlighter's parser and query
+// cursors.
+func (h *Highlighter) Close() {
+	h.Parser.Close()
+	for _, cursor := range h.cursors {
+		cursor.Close()
+	}
+	h.cursors = nil
+}
+
+// Iterate over the highlighted regions for a given slice of source code.
+//
+// # Arguments:
+//   - `config` The configuration of the language to highlight.
+//   - `source` The source code to highlight.
+//   - `cancellationFlag` An optional flag that is read atomically during
+//     highlighting. If it is set to a non-zero value, highlighting stops with
+//     [ErrCancelled].
+//   - `injectionCallback` A function that is called with the name of each
+//     injected language, and returns the configuration to use for it, or `nil`
+//     if the injection should be skipped.
+//
+// The returned [HighlightIter] must be closed if it is not iterated to the
+// end.
+func (h *Highlighter) Highlight(
+	config *HighlightConfiguration,
+	source []byte,
+	cancellationFlag *uintptr,
+	injectionCallback func(string) *HighlightConfiguration,
+) (*HighlightIter, error) {
+	layers, err := newHighlightIterLayers(
+		source,
+		"",
+		h,
+		cancellationFlag,
+		injectionCallback,
+		config,
+		0,
+		[]tree_sitter.Range{{
+			StartByte:  0,
+			EndByte:    math.MaxUint32,
+			StartPoint: tree_sitter.NewPoint(0, 0),
+			EndPoint:   tree_sitter.NewPoint(math.MaxUint32, math.MaxUint32),
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HighlightIter{
+		source:            source,
+		languageName:      config.LanguageName,
+		highlighter:       h,
+		injectionCallback: injectionCallback,
+		cancellationFlag:  cancellationFlag,
+		layers:            layers,
+	}
+	result.sortLayers()
+	return result, nil
+}
+
+// Return the next event in the sequence of highlight events.
+//
+// If there are no more events, it will return `nil`. Once an error is
+// returned, the iterator should be closed.
+func (it *HighlightIter) Next() (HighlightEvent, error) {
+main:
+	for {
+		// If we've already determined the next highlight boundary, just return it.
+		if it.nextEvent != nil {
+			event := it.nextEvent
+			it.nextEvent = nil
+			return event, nil
+		}
+
+		// Periodically check for cancellation, returning `ErrCancelled` if the
+		// cancellation flag was flipped.
+		if it.cancellationFlag != nil {
+			it.iterCount++
+			if it.iterCount >= cancellationCheckInterval {
+				it.iterCount = 0
+				if atomic.LoadUintptr(it.cancellationFlag) != 0 {
+					return nil, ErrCancelled
+				}
+			}
+		}
+
+		// If none of the layers have any more highlight boundaries, terminate.
+		if len(it.layers) == 0 {
+			sourceLen := uint(len(it.source))
+			if it.byteOffset < sourceLen {
+				result := Source{StartByte: it.byteOffset, EndByte: sourceLen}
+				it.byteOffset = sourceLen
+				return result, nil
+			}
+			return nil, nil
+		}
+
+		// Get the next capture from whichever layer has the earliest highlight boundary.
+		layer := it.layers[0]
+		next := layer.peek()
+		if next == nil {
+			// If there are no more captures, then emit any remaining highlight end events.
+			// And if there are none of those, then just advance to the end of the document.
+			if len(layer.highlightEndStack) > 0 {
+				endByte := layer.popHighlightEnd()
+				return it.emitEvent(endByte, HighlightEnd{}), nil
+			}
+			if event := it.emitEvent(uint(len(it.source)), nil); event != nil {
+				return event, nil
+			}
+			return nil, nil
+		}
+
+		nextCapture := next.match.Captures[next.captureIndex]
+		rangeStart, rangeEnd := nextCapture.Node.ByteRange()
+
+		// If any previous highlight ends before this node starts, then before
+		// processing this capture, emit the source code up until the end of the
+		// previous highlight, and an end event for that highlight.
+		if len(layer.highlightEndStack) > 0 {
+			endByte := layer.highlightEndStack[len(layer.highlightEndStack)-1]
+			if endByte <= rangeStart {
+				layer.popHighlightEnd()
+				return it.emitEvent(endByte, HighlightEnd{}), nil
+			}
+		}
+
+		current := layer.next()
+		match := current.match
+		capture := match.Captures[current.captureIndex]
+
+		// If this capture represents an injection, then process the injection.
+		if match.PatternIndex < layer.config.localsPatternIndex {
+			languageName, contentNode, includeChildren := injectionForMatch(
+				layer.config,
+				it.languageName,
+				layer.config.Query,
+				&match,
+				it.source,
+			)
+
+			// Explicitly remove this match so that none of its other captures will remain
+			// in the stream of captures.
+			match.Remove()
+
+			// If a language is found with the given name, then add a new language layer
+			// to the highlighted document.
+			if languageName != "" && contentNode != nil && it.injectionCallback != nil {
+				if config := it.injectionCallback(languageName); config != nil {
+					ranges := intersectRanges(layer.ranges, []tree_sitter.Node{*contentNode}, includeChildren)
+					if len(ranges) > 0 {
+						layers, err := newHighlightIterLayers(
+							it.source,
+							it.languageName,
+							it.highlighter,
+							it.cancellationFlag,
+							it.injectionCallback,
+							config,
+							layer.depth+1,
+							ranges,
+						)
+						if err != nil {
+							return nil, err
+						}
+						for _, layer := range layers {
+							it.insertLayer(layer)
+						}
+					}
+				}
+			}
+
+			it.sortLayers()
+			continue main
+		}
+
+		// Remove from the local scope stack any local scopes that have already ended.
+		for rangeStart > layer.scopeStack[len(layer.scopeStack)-1].end {
+			layer.scopeStack = layer.scopeStack[:len(layer.scopeStack)-1]
+		}
+
+		// If this capture is for tracking local variables, then process the
+		// local variable info.
+		var referenceHighlight *Highlight
+		var definition *localDef
+		for match.PatternIndex < layer.config.highlightsPatternIndex {
+			config := layer.config
+			switch {
+			// If the node represents a local scope, push a new local scope onto
+			// the scope stack.
+			case isCaptureIndex(config.localScopeCaptureIndex, capture.Index):
+				definition = nil
+				scope := &localScope{inherits: true, start: rangeStart, end: rangeEnd}
+				for _, prop := range config.Query.PropertySettings(match.PatternIndex) {
+					if prop.Key == "local.scope-inherits" {
+						scope.inherits = prop.Value == nil || *prop.Value == "true"
+					}
+				}
+				layer.scopeStack = append(layer.scopeStack, scope)
+
+			// If the node represents a definition, add a new definition to the
+			// local scope at the top of the scope stack.
+			case isCaptureIndex(config.localDefCaptureIndex, capture.Index):
+				referenceHighlight = nil
+				scope := layer.scopeStack[len(layer.scopeStack)-1]
+
+				var valueRange [2]uint
+				for _, c := range match.Captures {
+					if isCaptureIndex(config.localDefValueCaptureIndex, c.Index) {
+						valueRange[0], valueRange[1] = c.Node.ByteRange()
+					}
+				}
+
+				definition = &localDef{
+					name:       string(it.source[rangeStart:rangeEnd]),
+					valueRange: valueRange,
+				}
+				scope.localDefs = append(scope.localDefs, definition)
+
+			// If the node represents a reference, then try to find the corresponding
+			// definition in the scope stack.
+			case isCaptureIndex(config.localRefCaptureIndex, capture.Index) && definition == nil:
+				name := string(it.source[rangeStart:rangeEnd])
+			scopes:
+				for i := len(layer.scopeStack) - 1; i >= 0; i-- {
+					scope := layer.scopeStack[i]
+					for j := len(scope.localDefs) - 1; j >= 0; j-- {
+						def := scope.localDefs[j]
+						if def.name == name && rangeStart >= def.valueRange[1] {
+							referenceHighlight = def.highlight
+							break scopes
+						}
+					}
+					if !scope.inherits {
+						break
+					}
+				}
+			}
+
+			// Continue processing any additional matches for the same node.
+			if next := layer.peek(); next != nil {
+				nextCapture := next.match.Captures[next.captureIndex]
+				if nextCapture.Node.Id() == capture.Node.Id() {
+					capture = nextCapture
+					match = layer.next().match
+					continue
+				}
+			}
+
+			it.sortLayers()
+			continue main
+		}
+
+		// Otherwise, this capture must represent a highlight.
+		// If this exact range has already been highlighted by an earlier pattern, or by
+		// a different layer, then skip over this one.
+		if last := it.lastHighlightRange; last != nil {
+			if rangeStart == last.start && rangeEnd == last.end && layer.depth < last.depth {
+				it.sortLayers()
+				continue main
+			}
+		}
+
+		// Once a highlighting pattern is found for the current node, keep iterating over
+		// any later highlighting patterns that also match this node and set the match to it.
+		// Captures for a given node are ordered by pattern index, so these subsequent
+		// captures are guaranteed to be for highlighting, not injections or
+		// local variables.
+		for {
+			next := layer.peek()
+			if next == nil {
+				break
+			}
+			nextCapture := next.match.Captures[next.captureIndex]
+			if nextCapture.Node.Id() != capture.Node.Id() {
+				break
+			}
+			followingMatch := layer.next().match
+
+			// If the current node was found to be a local variable, then ignore
+			// the following match if it's a highlighting pattern that is disabled
+			// for local variables.
+			if (definition != nil || referenceHighlight != nil) &&
+				layer.config.nonLocalVariablePatterns[followingMatch.PatternIndex] {
+				continue
+			}
+
+			match.Remove()
+			capture = nextCapture
+			match = followingMatch
+		}
+
+		currentHighlight := layer.config.highlightIndices[capture.Index]
+
+		// If this node represents a local definition, then store the current
+		// highlight value on the local scope entry representing this node.
+		if definition != nil {
+			definition.highlight = currentHighlight
+		}
+
+		// Emit a scope start event and push the node's end position to the stack.
+		highlight := referenceHighlight
+		if highlight == nil {
+			highlight = currentHighlight
+		}
+		if highlight != nil {
+			it.lastHighlightRange = &highlightRange{start: rangeStart, end: rangeEnd, depth: layer.depth}
+			layer.highlightEndStack = append(layer.highlightEndStack, rangeEnd)
+			return it.emitEvent(rangeStart, HighlightStart{Highlight: *highlight}), nil
+		}
+
+		it.sortLayers()
+	}
+}
+
+// Delete the underlying memory for the syntax trees of any layers that have
+// not been fully iterated, and return their query cursors to the
+// [Highlighter].
+func (it *HighlightIter) Close() {
+	for _, layer := range it.layers {
+		it.releaseLayer(layer)
+	}
+	it.layers = nil
+}
+
+func (it *HighlightIter) emitEvent(offset uint, event HighlightEvent) HighlightEvent {
+	var result HighlightEvent
+	if it.byteOffset < offset {
+		result = Source{StartByte: it.byteOffset, EndByte: offset}
+		it.byteOffset = offset
+		it.nextEvent = event
+	} else {
+		result = event
+	}
+	it.sortLayers()
+	return result
+}
+
+func (it *HighlightIter) sortLayers() {
+	for len(it.layers) > 0 {
+		if key, ok := it.layers[0].sortKey(); ok {
+			i := 0
+			for i+1 < len(it.layers) {
+				if nextKey, ok := it.layers[i+1].sortKey(); ok && nextKey.less(key) {
+					i++
+					continue
+				}
+				break
+			}
+			if i > 0 {
+				first := it.layers[0]
+				copy(it.layers[0:i], it.layers[1:i+1])
+				it.layers[i] = first
+			}
+			break
+		}
+		layer := it.layers[0]
+		it.layers = it.layers[1:]
+		it.releaseLayer(layer)
+	}
+}
+
+func (it *HighlightIter) insertLayer(layer *highlightIterLayer) {
+	key, ok := layer.sortKey()
+	if !ok {
+		it.releaseLayer(layer)
+		return
+	}
+	i := 1
+	for i < len(it.layers) {
+		if keyI, ok := it.layers[i].sortKey(); ok {
+			if key.less(keyI) {
+				it.layers = append(it.layers, nil)
+				copy(it.layers[i+1:], it.layers[i:])
+				it.layers[i] = layer
+				return
+			}
+			i++
+		} else {
+			removed := it.layers[i]
+			it.layers = append(it.layers[:i], it.layers[i+1:]...)
+			it.releaseLayer(removed)
+		}
+	}
+	it.layers = append(it.layers, layer)
+}
+
+func (it *HighlightIter) releaseLayer(layer *highlightIterLayer) {
+	it.highlighter.cursors = append(it.highlighter.cursors, layer.cursor)
+	layer.tree.Close()
+}
+
+// Create a new 'layer' of highlighting for this document.
+//
+// In the event that the new layer contains "combined injections" (injections where multiple
+// disjoint ranges are parsed as one syntax tree), these will be eagerly processed and
+// added to the returned slice.
+func newHighlightIterLayers(
+	source []byte,
+	parentName string,
+	highlighter *Highlighter,
+	cancellationFlag *uintptr,
+	injectionCallback func(string) *HighlightConfiguration,
+	config *HighlightConfiguration,
+	depth uint,
+	ranges []tree_sitter.Range,
+) ([]*highlightIterLayer, error) {
+	type queuedLayer struct {
+		config *HighlightConfiguration
+		depth  uint
+		ranges []tree_sitter.Range
+	}
+
+	result := make([]*highlightIterLayer, 0, 1)
+	// Give back the trees and cursors of the layers created so far when
+	// a later layer fails.
+	release := func() {
+		for _, layer := range result {
+			highlighter.cursors = append(highlighter.cursors, layer.cursor)
+			layer.tree.Close()
+		}
+	}
+	var queue []queuedLayer
+	for {
+		if highlighter.Parser.SetIncludedRanges(ranges) == nil {
+			if err := highlighter.Parser.SetLanguage(config.Language); err != nil {
+				release()
+				return nil, ErrInvalidLanguage
+			}
+
+			var options *tree_sitter.ParseOptions
+			if cancellationFlag != nil {
+				options = &tree_sitter.ParseOptions{
+					ProgressCallback: func(tree_sitter.ParseState) bool {
+						return atomic.LoadUintptr(cancellationFlag) != 0
+					},
+				}
+			}
+			tree := highlighter.Parser.ParseWithOptions(func(i int, _ tree_sitter.Point) []byte {
+				if i < len(source) {
+					return source[i:]
+				}
+				return []byte{}
+			}, nil, options)
+			if tree == nil {
+				highlighter.Parser.Reset()
+				release()
+				return nil, ErrCancelled
+			}
+
+			var cursor *tree_sitter.QueryCursor
+			if n := len(highlighter.cursors); n > 0 {
+				cursor = highlighter.cursors[n-1]
+				highlighter.cursors = highlighter.cursors[:n-1]
+			} else {
+				cursor = tree_sitter.NewQueryCursor()
+			}
+
+			// Process combined injections.
+			if query := config.combinedInjectionsQuery; query != nil && injectionCallback != nil {
+				type combinedInjection struct {
+					languageName    string
+					contentNodes    []tree_sitter.Node
+					includeChildren bool
+				}
+				injectionsByPatternIndex := make([]combinedInjection, query.PatternCount())
+				matches := cursor.Matches(query, tree.RootNode(), source)
+				for match := matches.Next(); match != nil; match = matches.Next() {
+					entry := &injectionsByPatternIndex[match.PatternIndex]
+					languageName, contentNode, includeChildren := injectionForMatch(
+						config,
+						parentName,
+						query,
+						match,
+						source,
+					)
+					if languageName != "" {
+						entry.languageName = languageName
+					}
+					if contentNode != nil {
+						entry.contentNodes = append(entry.contentNodes, *contentNode)
+					}
+					entry.includeChildren = includeChildren
+				}
+				for _, injection := range injectionsByPatternIndex {
+					if injection.languageName == "" || len(injection.contentNodes) == 0 {
+						continue
+					}
+					if nextConfig := injectionCallback(injection.languageName); nextConfig != nil {
+						ranges := intersectRanges(ranges, injection.contentNodes, injection.includeChildren)
+						if len(ranges) > 0 {
+							queue = append(queue, queuedLayer{nextConfig, depth + 1, ranges})
+						}
+					}
+				}
+			}
+
+			result = append(result, &highlightIterLayer{
+				tree:     tree,
+				cursor:   cursor,
+				captures: cursor.Captures(config.Query, tree.RootNode(), source),
+				config:   config,
+				scopeStack: []*localScope{{
+					inherits: false,
+					start:    0,
+					end:      math.MaxUint,
+				}},
+				ranges: ranges,
+				depth:  depth,
+			})
+		}
+
+		if len(queue) == 0 {
+			break
+		}
+
+		next := queue[0]
+		queue = queue[1:]
+		config = next.config
+		depth = next.depth
+		ranges = next.ranges
+	}
+
+	return result, nil
+}
+
+// Compute the ranges that should be included when parsing an injection.
+// This takes into account three things:
+//   - `parentRanges` - The ranges must all fall within the *current* layer's ranges.
+//   - `nodes` - Every injection takes place within a set of nodes. The injection ranges are the
+//     ranges of those nodes.
+//   - `includesChildren` - For some injections, the content nodes' children should be excluded
+//     from the nested document, so that only the content nodes' *own* content is reparsed. For
+//     other injections, the content nodes' entire ranges should be reparsed, including the ranges
+//     of their children.
+func intersectRanges(parentRanges []tree_sitter.Range, nodes []tree_sitter.Node, includesChildren bool) []tree_sitter.Range {
+	cursor := nodes[0].Walk()
+	defer cursor.Close()
+
+	var result []tree_sitter.Range
+	parentIndex := 0
+	parentRange := parentRanges[parentIndex]
+	for _, node := range nodes {
+		precedingRange := tree_sitter.Range{
+			StartByte:  0,
+			StartPoint: tree_sitter.NewPoint(0, 0),
+			EndByte:    node.StartByte(),
+			EndPoint:   node.StartPosition(),
+		}
+		followingRange := tree_sitter.Range{
+			StartByte:  node.EndByte(),
+			StartPoint: node.EndPosition(),
+			EndByte:    math.MaxUint32,
+			EndPoint:   tree_sitter.NewPoint(math.MaxUint32, math.MaxUint32),
+		}
+
+		var excludedRanges []tree_sitter.Range
+		if !includesChildren {
+			for _, child := range node.Children(cursor) {
+				excludedRanges = append(excludedRanges, child.Range())
+			}
+		}
+		excludedRanges = append(excludedRanges, followingRange)
+
+		for _, excludedRange := range excludedRanges {
+			r := tree_sitter.Range{
+				StartByte:  precedingRange.EndByte,
+				StartPoint: precedingRange.EndPoint,
+				EndByte:    excludedRange.StartByte,
+				EndPoint:   excludedRange.StartPoint,
+			}
+			precedingRange = excludedRange
+
+			if r.EndByte < parentRange.StartByte {
+				continue
+			}
+
+			for parentRange.StartByte <= r.EndByte {
+				if parentRange.EndByte > r.StartByte {
+					if r.StartByte < parentRange.StartByte {
+						r.StartByte = parentRange.StartByte
+						r.StartPoint = parentRange.StartPoint
+					}
+
+					if parentRange.EndByte < r.EndByte {
+						if r.StartByte < parentRange.EndByte {
+							result = append(result, tree_sitter.Range{
+								StartByte:  r.StartByte,
+								StartPoint: r.StartPoint,
+								EndByte:    parentRange.EndByte,
+								EndPoint:   parentRange.EndPoint,
+							})
+						}
+						r.StartByte = parentRange.EndByte
+						r.StartPoint = parentRange.EndPoint
+					} else {
+						if r.StartByte < r.EndByte {
+							result = append(result, r)
+						}
+						break
+					}
+				}
+
+				parentIndex++
+				if parentIndex >= len(parentRanges) {
+					return result
+				}
+				parentRange = parentRanges[parentIndex]
+			}
+		}
+	}
+	return result
+}
+
+func injectionForMatch(
+	config *HighlightConfiguration,
+	parentName string,
+	query *tree_sitter.Query,
+	match *tree_sitter.QueryMatch,
+	source []byte,
+) (string, *tree_sitter.Node, bool) {
+	var languageName string
+	var contentNode *tree_sitter.Node
+
+	for _, capture := range match.Captures {
+		if isCaptureIndex(config.injectionLanguageCaptureIndex, capture.Index) {
+			languageName = capture.Node.Utf8Text(source)
+		} else if isCaptureIndex(config.injectionContentCaptureIndex, capture.Index) {
+			node := capture.Node
+			contentNode = &node
+		}
+	}
+
+	includeChildren := false
+	for _, prop := range query.PropertySettings(match.PatternIndex) {
+		switch prop.Key {
+		// In addition to specifying the language name via the text of a
+		// captured node, it can also be hard-coded via a `#set!` predicate
+		// that sets the injection.language key.
+		case "injection.language":
+			if languageName == "" && prop.Value != nil {
+				languageName = *prop.Value
+			}
+
+		// Setting the `injection.self` key can be used to specify that the
+		// language name should be the same as the language of the current
+		// layer.
+		case "injection.self":
+			if languageName == "" {
+				languageName = config.LanguageName
+			}
+
+		// Setting the `injection.parent` key can be used to specify that
+		// the language name should be the same as the language of the
+		// parent layer
+		case "injection.parent":
+			if languageName == "" {
+				languageName = parentName
+			}
+
+		// By default, injections do not include the *children* of an
+		// `injection.content` node - only the ranges that belong to the
+		// node itself. This can be changed using a `#set!` predicate that
+		// sets the `injection.include-children` key.
+		case "injection.include-children":
+			includeChildren = true
+		}
+	}
+
+	return languageName, contentNode, includeChildren
+}
+
+// Get the next capture without consuming it.
+//
+// The match is copied out of the query cursor's memory, since it would
+// otherwise be overwritten by the next call to [tree_sitter.QueryCaptures.Next].
+func (l *highlightIterLayer) peek() *peekedCapture {
+	if l.peeked == nil && !l.done {
+		match, captureIndex := l.captures.Next()
+		if match == nil {
+			l.done = true
+			return nil
+		}
+		copied := *match
+		copied.Captures = append([]tree_sitter.QueryCapture(nil), match.Captures...)
+		l.peeked = &peekedCapture{match: copied, captureIndex: captureIndex}
+	}
+	return l.peeked
+}
+
+func (l *highlightIterLayer) next() *peekedCapture {
+	result := l.peek()
+	l.peeked = nil
+	return result
+}
+
+func (l *highlightIterLayer) popHighlightEnd() uint {
+	endByte := l.highlightEndStack[len(l.highlightEndStack)-1]
+	l.highlightEndStack = l.highlightEndStack[:len(l.highlightEndStack)-1]
+	return endByte
+}
+
+// First, sort scope boundaries by their byte offset in the document. At a
+// given position, emit scope endings before scope beginnings. Finally, emit
+// scope boundaries from deeper layers first.
+func (l *highlightIterLayer) sortKey() (sortKey, bool) {
+	depth := -int(l.depth)
+	var nextStart, nextEnd *uint
+	if next := l.peek(); next != nil {
+		start := next.match.Captures[next.captureIndex].Node.StartByte()
+		nextStart = &start
+	}
+	if len(l.highlightEndStack) > 0 {
+		end := l.highlightEndStack[len(l.highlightEndStack)-1]
+		nextEnd = &end
+	}
+	switch {
+	case nextStart != nil && nextEnd != nil:
+		if *nextStart < *nextEnd {
+			return sortKey{*nextStart, true, depth}, true
+		}
+		return sortKey{*nextEnd, false, depth}, true
+	case nextStart != nil:
+		return sortKey{*nextStart, true, depth}, true
+	case nextEnd != nil:
+		return sortKey{*nextEnd, false, depth}, true
+	default:
+		return sortKey{}, false
+	}
+}
+
+func (k sortKey) less(other sortKey) bool {
+	if k.offset != other.offset {
+		return k.offset < other.offset
+	}
+	if k.isStart != other.isStart {
+		return !k.isStart
+	}
+	return k.depth < other.depth
+}
+
+func isCaptureIndex(index *uint, captureIndex uint32) bool {
+	return index != nil && *index == uint(captureIndex)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
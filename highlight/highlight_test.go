@@ -0,0 +1,280 @@
+package highlight_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/highlight"
+	tree_sitter_html "github.com/tree-sitter/tree-sitter-html/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+)
+
+var highlightNames = []string{
+	"comment",
+	"function",
+	"keyword",
+	"string",
+	"tag",
+	"variable",
+	"variable.parameter",
+}
+
+const javascriptHighlightsQuery = `
+((identifier) @variable (#is-not? local))
+(function_declaration name: (identifier) @function)
+(call_expression function: (identifier) @function)
+(comment) @comment
+(string) @string
+["const" "function" "return"] @keyword
+`
+
+const javascriptLocalsQuery = `
+(statement_block) @local.scope
+(function_declaration) @local.scope
+(formal_parameters (identifier) @local.definition)
+(variable_declarator name: (identifier) @local.definition value: (_) @local.definition-value)
+(identifier) @local.reference
+`
+
+const javascriptParamHighlightsQuery = `
+(formal_parameters (identifier) @variable.parameter)
+`
+
+const htmlHighlightsQuery = `
+(tag_name) @tag
+(comment) @comment
+`
+
+const htmlInjectionsQuery = `
+((script_element (raw_text) @injection.content)
+ (#set! injection.language "javascript"))
+`
+
+func newJavascriptConfig(t *testing.T) *HighlightConfiguration {
+	config, err := NewHighlightConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		"javascript",
+		javascriptHighlightsQuery+javascriptParamHighlightsQuery,
+		"",
+		javascriptLocalsQuery,
+	)
+	assert.Nil(t, err)
+	config.Configure(highlightNames)
+	return config
+}
+
+func newHTMLConfig(t *testing.T) *HighlightConfiguration {
+	config, err := NewHighlightConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_html.Language()),
+		"html",
+		htmlHighlightsQuery,
+		htmlInjectionsQuery,
+		"",
+	)
+	assert.Nil(t, err)
+	config.Configure(highlightNames)
+	return config
+}
+
+type token struct {
+	text  string
+	names []string
+}
+
+func toTokens(t *testing.T, source string, config *HighlightConfiguration, names []string, injectionCallback func(string) *HighlightConfiguration) []token {
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	events, err := highlighter.Highlight(config, []byte(source), nil, injectionCallback)
+	assert.Nil(t, err)
+	defer events.Close()
+
+	var tokens []token
+	var stack []string
+	for {
+		event, err := events.Next()
+		assert.Nil(t, err)
+		if event == nil {
+			break
+		}
+		switch event := event.(type) {
+		case HighlightStart:
+			stack = append(stack, names[event.Highlight])
+		case HighlightEnd:
+			stack = stack[:len(stack)-1]
+		case Source:
+			text := source[event.StartByte:event.EndByte]
+			if strings.TrimSpace(text) != "" && len(stack) > 0 {
+				tokens = append(tokens, token{text, append([]string(nil), stack...)})
+			}
+		}
+	}
+	assert.Empty(t, stack)
+	return tokens
+}
+
+func TestHighlightingJavascript(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+
+	source := "// hi\nconst x = f('a');\n"
+	assert.Equal(
+		t,
+		[]token{
+			{"// hi", []string{"comment"}},
+			{"const", []string{"keyword"}},
+			{"x", []string{"variable"}},
+			{"f", []string{"function"}},
+			{"'a'", []string{"string"}},
+		},
+		toTokens(t, source, config, highlightNames, nil),
+	)
+}
+
+func TestHighlightingLocalVariables(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+
+	source := "function f(a) { return a + b; }"
+	assert.Equal(
+		t,
+		[]token{
+			{"function", []string{"keyword"}},
+			{"f", []string{"function"}},
+			{"a", []string{"variable.parameter"}},
+			{"return", []string{"keyword"}},
+			// References to a local take the highlight of its definition.
+			{"a", []string{"variable.parameter"}},
+			{"b", []string{"variable"}},
+		},
+		toTokens(t, source, config, highlightNames, nil),
+	)
+}
+
+func TestHighlightingInjections(t *testing.T) {
+	jsConfig := newJavascriptConfig(t)
+	defer jsConfig.Close()
+	htmlConfig := newHTMLConfig(t)
+	defer htmlConfig.Close()
+
+	source := "<div><script>const x = 'a';</script></div>"
+	injectionCallback := func(name string) *HighlightConfiguration {
+		if name == "javascript" {
+			return jsConfig
+		}
+		return nil
+	}
+
+	assert.Equal(
+		t,
+		[]token{
+			{"div", []string{"tag"}},
+			{"script", []string{"tag"}},
+			{"const", []string{"keyword"}},
+			{"x", []string{"variable"}},
+			{"'a'", []string{"string"}},
+			{"script", []string{"tag"}},
+			{"div", []string{"tag"}},
+		},
+		toTokens(t, source, htmlConfig, highlightNames, injectionCallback),
+	)
+
+	// Without a configuration for the injected language, the script is plain text.
+	assert.Equal(
+		t,
+		[]token{
+			{"div", []string{"tag"}},
+			{"script", []string{"tag"}},
+			{"script", []string{"tag"}},
+			{"div", []string{"tag"}},
+		},
+		toTokens(t, source, htmlConfig, highlightNames, nil),
+	)
+}
+
+func TestHighlightingCoversSource(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := []byte("const x = 1;\n\nfunction g() { return x; }\n")
+	events, err := highlighter.Highlight(config, source, nil, nil)
+	assert.Nil(t, err)
+	defer events.Close()
+
+	var offset uint
+	for {
+		event, err := events.Next()
+		assert.Nil(t, err)
+		if event == nil {
+			break
+		}
+		if event, ok := event.(Source); ok {
+			assert.Equal(t, offset, event.StartByte)
+			offset = event.EndByte
+		}
+	}
+	assert.Equal(t, uint(len(source)), offset)
+}
+
+func TestHighlightingCancellation(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := []byte(strings.Repeat("const x = f('a');\n", 500))
+	cancellationFlag := uintptr(1)
+	events, err := highlighter.Highlight(config, source, &cancellationFlag, nil)
+	if err == nil {
+		defer events.Close()
+		for {
+			var event HighlightEvent
+			event, err = events.Next()
+			if err != nil || event == nil {
+				break
+			}
+		}
+	}
+	assert.ErrorIs(t, err, ErrCancelled)
+}
+
+func TestHighlightConfigurationConfigure(t *testing.T) {
+	config, err := NewHighlightConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		"javascript",
+		`(identifier) @function.method.builtin (string) @punctuation.bracket (comment) @_private`,
+		"",
+		"",
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	assert.Equal(t, []string{"function.method.builtin", "punctuation.bracket", "_private"}, config.Names())
+	assert.Equal(t, []string{"function.method.builtin"}, config.NonconformantCaptureNames(nil))
+
+	// The most specific recognized name wins, and ties go to the earlier name.
+	names := []string{"function", "function.builtin", "function.method", "punctuation"}
+	config.Configure(names)
+	assert.Equal(
+		t,
+		[]token{{"f", []string{"function.builtin"}}, {"'a'", []string{"punctuation"}}},
+		toTokens(t, "f('a')", config, names, nil),
+	)
+}
+
+func TestHighlightConfigurationInvalidQuery(t *testing.T) {
+	_, err := NewHighlightConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		"javascript",
+		`(not_a_node) @x`,
+		"",
+		"",
+	)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,271 @@
+package highlight
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Renders a stream of [HighlightEvent]s as HTML, wrapping each highlighted
+// region in a `<span>` element.
+//
+// Tags are closed and reopened at the end of each line, so that every line
+// of the output is well-formed on its own.
+type HTMLRenderer struct {
+	// The rendered HTML.
+	HTML []byte
+
+	// The offset in [HTMLRenderer.HTML] at which each line starts.
+	LineOffsets []uint
+
+	carriageReturnHighlight *Highlight
+	lastCarriageReturn      *int
+}
+
+// Renders a stream of [HighlightEvent]s as text with ANSI escape sequences
+// for a terminal.
+type ANSIRenderer struct {
+	// The theme that gives the style for each highlight.
+	Theme *Theme
+
+	// Whether the terminal supports 24-bit color. If not, RGB colors are
+	// approximated with the 256-color palette.
+	TrueColor bool
+}
+
+const ansiReset = "\x1b[0m"
+
+// Create a new HTML renderer.
+func NewHTMLRenderer() *HTMLRenderer {
+	return &HTMLRenderer{
+		HTML:        make([]byte, 0, 8192),
+		LineOffsets: []uint{0},
+	}
+}
+
+// Set the highlight used for lone carriage returns, i.e. ones that are not
+// followed by a line feed.
+//
+// Carriage returns are never rendered, but if this is set, a lone carriage
+// return is replaced by an empty `<span>` with the highlight's attributes, so
+// that it can be made visible with CSS.
+func (r *HTMLRenderer) SetCarriageReturnHighlight(highlight *Highlight) {
+	r.carriageReturnHighlight = highlight
+}
+
+// Clear the rendered HTML, so that the renderer can be reused.
+func (r *HTMLRenderer) Reset() {
+	r.HTML = r.HTML[:0]
+	r.LineOffsets = append(r.LineOffsets[:0], 0)
+	r.lastCarriageReturn = nil
+}
+
+// Render all of the events of a [HighlightIter] into [HTMLRenderer.HTML].
+//
+// # Arguments:
+//   - `events` The highlight events to render.
+//   - `source` The source code that was highlighted.
+//   - `attributeCallback` A function that returns the HTML attributes of the
+//     `<span>` for a given highlight, such as the ones returned by
+//     [ClassAttributes] and [Theme.StyleAttributes].
+//
+// Invalid UTF-8 in the source is replaced with the Unicode replacement
+// character, and the characters `<`, `>`, `&`, `'` and `"` are escaped.
+func (r *HTMLRenderer) Render(events *HighlightIter, source []byte, attributeCallback func(Highlight) string) error {
+	var highlights []Highlight
+	for {
+		event, err := events.Next()
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			break
+		}
+		switch event := event.(type) {
+		case HighlightStart:
+			highlights = append(highlights, event.Highlight)
+			r.startHighlight(event.Highlight, attributeCallback)
+		case HighlightEnd:
+			highlights = highlights[:len(highlights)-1]
+			r.endHighlight()
+		case Source:
+			r.addText(source[event.StartByte:event.EndByte], highlights, attributeCallback)
+		}
+	}
+
+	if r.lastCarriageReturn != nil {
+		offset := *r.lastCarriageReturn
+		r.lastCarriageReturn = nil
+		r.addCarriageReturn(offset, attributeCallback)
+	}
+	if len(r.HTML) == 0 || r.HTML[len(r.HTML)-1] != '\n' {
+		r.HTML = append(r.HTML, '\n')
+	}
+	if r.LineOffsets[len(r.LineOffsets)-1] == uint(len(r.HTML)) {
+		r.LineOffsets = r.LineOffsets[:len(r.LineOffsets)-1]
+	}
+	return nil
+}
+
+// Get the rendered HTML of each line, including its trailing newline.
+func (r *HTMLRenderer) Lines() []string {
+	lines := make([]string, 0, len(r.LineOffsets))
+	for i, start := range r.LineOffsets {
+		end := uint(len(r.HTML))
+		if i+1 < len(r.LineOffsets) {
+			end = r.LineOffsets[i+1]
+		}
+		lines = append(lines, string(r.HTML[start:end]))
+	}
+	return lines
+}
+
+// Write the rendered HTML as a `<table>` with one row per line, where the
+// first cell contains the line number and has the class `line-number`, and
+// the second contains the line and has the class `line`.
+func (r *HTMLRenderer) WriteTable(w io.Writer) error {
+	if _, err := io.WriteString(w, "<table>\n"); err != nil {
+		return err
+	}
+	for i, line := range r.Lines() {
+		if _, err := fmt.Fprintf(w, "  <tr><td class=line-number>%d</td><td class=line>%s</td></tr>\n", i+1, line); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</table>\n")
+	return err
+}
+
+func (r *HTMLRenderer) addCarriageReturn(offset int, attributeCallback func(Highlight) string) {
+	if r.carriageReturnHighlight == nil {
+		return
+	}
+	// If a CR is the last character in a `Source` region, then we don't know
+	// until the next `Source` event or EOF whether it is part of CRLF or on
+	// its own. To avoid unbounded lookahead, save the offset of the CR and
+	// insert there now that we know.
+	rest := append([]byte(nil), r.HTML[offset:]...)
+	r.HTML = r.HTML[:offset]
+	r.startHighlight(*r.carriageReturnHighlight, attributeCallback)
+	r.endHighlight()
+	r.HTML = append(r.HTML, rest...)
+}
+
+func (r *HTMLRenderer) startHighlight(highlight Highlight, attributeCallback func(Highlight) string) {
+	r.HTML = append(r.HTML, "<span"...)
+	if attributes := attributeCallback(highlight); attributes != "" {
+		r.HTML = append(r.HTML, ' ')
+		r.HTML = append(r.HTML, attributes...)
+	}
+	r.HTML = append(r.HTML, '>')
+}
+
+func (r *HTMLRenderer) endHighlight() {
+	r.HTML = append(r.HTML, "</span>"...)
+}
+
+func (r *HTMLRenderer) addText(src []byte, highlights []Highlight, attributeCallback func(Highlight) string) {
+	if !utf8.Valid(src) {
+		src = bytes.ToValidUTF8(src, []byte(string(utf8.RuneError)))
+	}
+	for _, c := range src {
+		// Don't render carriage return characters, but allow lone carriage returns (not
+		// followed by line feeds) to be styled via the attribute callback.
+		if c == '\r' {
+			offset := len(r.HTML)
+			r.lastCarriageReturn = &offset
+			continue
+		}
+		if r.lastCarriageReturn != nil {
+			offset := *r.lastCarriageReturn
+			r.lastCarriageReturn = nil
+			if c != '\n' {
+				r.addCarriageReturn(offset, attributeCallback)
+			}
+		}
+
+		// At line boundaries, close and re-open all of the open tags.
+		if c == '\n' {
+			for range highlights {
+				r.endHighlight()
+			}
+			r.HTML = append(r.HTML, c)
+			r.LineOffsets = append(r.LineOffsets, uint(len(r.HTML)))
+			for _, highlight := range highlights {
+				r.startHighlight(highlight, attributeCallback)
+			}
+		} else if escape := htmlEscape(c); escape != "" {
+			r.HTML = append(r.HTML, escape...)
+		} else {
+			r.HTML = append(r.HTML, c)
+		}
+	}
+}
+
+func htmlEscape(c byte) string {
+	switch c {
+	case '>':
+		return "&gt;"
+	case '<':
+		return "&lt;"
+	case '&':
+		return "&amp;"
+	case '\'':
+		return "&#39;"
+	case '"':
+		return "&quot;"
+	default:
+		return ""
+	}
+}
+
+// Create a new ANSI renderer that uses the given theme.
+func NewANSIRenderer(theme *Theme, trueColor bool) *ANSIRenderer {
+	return &ANSIRenderer{Theme: theme, TrueColor: trueColor}
+}
+
+// Render all of the events of a [HighlightIter] to a writer.
+//
+// Each region of source code is written with the style of the innermost
+// highlight that has a non-empty style, followed by a reset sequence.
+func (r *ANSIRenderer) Render(w io.Writer, events *HighlightIter, source []byte) error {
+	var styles []string
+	for {
+		event, err := events.Next()
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+		switch event := event.(type) {
+		case HighlightStart:
+			style := r.Theme.Style(event.Highlight).ANSI(r.TrueColor)
+			if style == "" && len(styles) > 0 {
+				style = styles[len(styles)-1]
+			}
+			styles = append(styles, style)
+		case HighlightEnd:
+			styles = styles[:len(styles)-1]
+		case Source:
+			text := source[event.StartByte:event.EndByte]
+			if len(styles) == 0 || styles[len(styles)-1] == "" {
+				_, err = w.Write(text)
+			} else {
+				_, err = io.WriteString(w, styles[len(styles)-1]+string(text)+ansiReset)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Render all of the events of a [HighlightIter] to a string.
+func (r *ANSIRenderer) RenderString(events *HighlightIter, source []byte) (string, error) {
+	var result strings.Builder
+	err := r.Render(&result, events, source)
+	return result.String(), err
+}
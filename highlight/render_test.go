@@ -0,0 +1,122 @@
+package highlight_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter/highlight"
+)
+
+func highlightEvents(t *testing.T, highlighter *Highlighter, config *HighlightConfiguration, source string) *HighlightIter {
+	events, err := highlighter.Highlight(config, []byte(source), nil, nil)
+	assert.Nil(t, err)
+	return events
+}
+
+func TestHTMLRendererClasses(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := "const s = '<a>';\n/* one\ntwo */\n"
+	events := highlightEvents(t, highlighter, config, source)
+	defer events.Close()
+
+	renderer := NewHTMLRenderer()
+	assert.Nil(t, renderer.Render(events, []byte(source), ClassAttributes(highlightNames)))
+	assert.Equal(
+		t,
+		[]string{
+			"<span class=\"keyword\">const</span> <span class=\"variable\">s</span> = <span class=\"string\">&#39;&lt;a&gt;&#39;</span>;\n",
+			// Open tags are closed and reopened around line breaks.
+			"<span class=\"comment\">/* one</span>\n",
+			"<span class=\"comment\">two */</span>\n",
+		},
+		renderer.Lines(),
+	)
+
+	var table strings.Builder
+	assert.Nil(t, renderer.WriteTable(&table))
+	assert.True(t, strings.HasPrefix(table.String(), "<table>\n  <tr><td class=line-number>1</td><td class=line><span class=\"keyword\">const</span>"))
+	assert.Equal(t, 3, strings.Count(table.String(), "<tr>"))
+	assert.True(t, strings.HasSuffix(table.String(), "</td></tr>\n</table>\n"))
+
+	renderer.Reset()
+	assert.Empty(t, renderer.HTML)
+	assert.Equal(t, []uint{0}, renderer.LineOffsets)
+}
+
+func TestHTMLRendererCarriageReturns(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := "a;\r\nb;\rc;"
+	events := highlightEvents(t, highlighter, config, source)
+	defer events.Close()
+
+	renderer := NewHTMLRenderer()
+	carriageReturn := Highlight(0)
+	renderer.SetCarriageReturnHighlight(&carriageReturn)
+	assert.Nil(t, renderer.Render(events, []byte(source), ClassAttributes(highlightNames)))
+	assert.Equal(
+		t,
+		"<span class=\"variable\">a</span>;\n"+
+			"<span class=\"variable\">b</span>;<span class=\"comment\"></span><span class=\"variable\">c</span>;\n",
+		string(renderer.HTML),
+	)
+}
+
+func TestHTMLRendererInlineStyles(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+	theme := DefaultTheme()
+	config.Configure(theme.Names)
+
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := "function f() {}"
+	events := highlightEvents(t, highlighter, config, source)
+	defer events.Close()
+
+	renderer := NewHTMLRenderer()
+	assert.Nil(t, renderer.Render(events, []byte(source), theme.StyleAttributes()))
+	assert.Equal(
+		t,
+		"<span style='color: #5f00d7;'>function</span> <span style='color: #005fd7;'>f</span>() {}\n",
+		string(renderer.HTML),
+	)
+}
+
+func TestANSIRenderer(t *testing.T) {
+	config := newJavascriptConfig(t)
+	defer config.Close()
+
+	theme := NewTheme(map[string]Style{
+		"keyword":  {Color: &Color{Index: 56}, Bold: true},
+		"string":   {Color: &Color{R: 0x12, G: 0x34, B: 0x56, IsRGB: true}},
+		"variable": {},
+	})
+	config.Configure(theme.Names)
+
+	highlighter := NewHighlighter()
+	defer highlighter.Close()
+
+	source := "const x = 'a';"
+
+	events := highlightEvents(t, highlighter, config, source)
+	output, err := NewANSIRenderer(theme, true).RenderString(events, []byte(source))
+	events.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[1;38;5;56mconst\x1b[0m x = \x1b[38;2;18;52;86m'a'\x1b[0m;", output)
+
+	events = highlightEvents(t, highlighter, config, source)
+	output, err = NewANSIRenderer(theme, false).RenderString(events, []byte(source))
+	events.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[1;38;5;56mconst\x1b[0m x = \x1b[38;5;23m'a'\x1b[0m;", output)
+}
@@ -0,0 +1,381 @@
+package highlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A color, either from the 256-color ANSI palette or an arbitrary RGB color.
+//
+// In JSON, a palette color is written as its index, e.g. `26`, and an RGB
+// color as a hex string, e.g. `"#005fd7"`. The names `black`, `red`, `green`,
+// `yellow`, `blue`, `purple`, `cyan` and `white` are also accepted.
+type Color struct {
+	R, G, B uint8
+
+	// Whether the color is an RGB color rather than a palette color.
+	IsRGB bool
+
+	// The index of a palette color.
+	Index uint8
+}
+
+// The style that is applied to a highlighted region.
+//
+// In JSON, a style is either a [Color], or an object with the optional keys
+// `color`, `bold`, `italic` and `underline`.
+type Style struct {
+	Color     *Color
+	Bold      bool
+	Italic    bool
+	Underline bool
+}
+
+// A mapping from highlight names to styles.
+//
+// In JSON, a theme is an object whose keys are highlight names and whose
+// values are [Style]s, e.g. `{"keyword": 56, "comment": {"color": 245,
+// "italic": true}}`. A `null` value recognizes a highlight name without
+// styling it.
+type Theme struct {
+	// The highlight names that the theme styles, sorted alphabetically.
+	// These should be passed to [HighlightConfiguration.Configure].
+	Names []string
+
+	// The style for each of the names.
+	Styles []Style
+}
+
+var namedColors = map[string]uint8{
+	"black":  0,
+	"red":    1,
+	"green":  2,
+	"yellow": 3,
+	"blue":   4,
+	"purple": 5,
+	"cyan":   6,
+	"white":  7,
+}
+
+// The RGB values of the 16 system colors, as used by xterm.
+var systemColors = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0x80, 0x00, 0x00}, {0x00, 0x80, 0x00}, {0x80, 0x80, 0x00},
+	{0x00, 0x00, 0x80}, {0x80, 0x00, 0x80}, {0x00, 0x80, 0x80}, {0xc0, 0xc0, 0xc0},
+	{0x80, 0x80, 0x80}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x00, 0x00, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+var colorCubeLevels = [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// Create a new theme from a map of highlight names to styles.
+func NewTheme(styles map[string]Style) *Theme {
+	theme := &Theme{
+		Names:  make([]string, 0, len(styles)),
+		Styles: make([]Style, 0, len(styles)),
+	}
+	for name := range styles {
+		theme.Names = append(theme.Names, name)
+	}
+	sort.Strings(theme.Names)
+	for _, name := range theme.Names {
+		theme.Styles = append(theme.Styles, styles[name])
+	}
+	return theme
+}
+
+// Get the default theme, which is the one used by the `tree-sitter` CLI.
+func DefaultTheme() *Theme {
+	color := func(index uint8) *Color {
+		return &Color{Index: index}
+	}
+	return NewTheme(map[string]Style{
+		"attribute":             {Color: color(124), Italic: true},
+		"comment":               {Color: color(245), Italic: true},
+		"constant":              {Color: color(94)},
+		"constant.builtin":      {Color: color(94), Bold: true},
+		"constructor":           {Color: color(136)},
+		"embedded":              {},
+		"function":              {Color: color(26)},
+		"function.builtin":      {Color: color(26), Bold: true},
+		"keyword":               {Color: color(56)},
+		"module":                {Color: color(136)},
+		"number":                {Color: color(94), Bold: true},
+		"operator":              {Color: color(239), Bold: true},
+		"property":              {Color: color(124)},
+		"property.builtin":      {Color: color(124), Bold: true},
+		"punctuation":           {Color: color(239)},
+		"punctuation.bracket":   {Color: color(239)},
+		"punctuation.delimiter": {Color: color(239)},
+		"punctuation.special":   {Color: color(239)},
+		"string":                {Color: color(28)},
+		"string.special":        {Color: color(30)},
+		"tag":                   {Color: color(18)},
+		"type":                  {Color: color(23)},
+		"type.builtin":          {Color: color(23), Bold: true},
+		"variable":              {Color: color(252)},
+		"variable.builtin":      {Color: color(252), Bold: true},
+		"variable.parameter":    {Color: color(252), Underline: true},
+	})
+}
+
+// Get the style for a highlight that was produced by a
+// [HighlightConfiguration] configured with the theme's names.
+func (t *Theme) Style(highlight Highlight) Style {
+	if int(highlight) < len(t.Styles) {
+		return t.Styles[highlight]
+	}
+	return Style{}
+}
+
+// Get an attribute callback for [HTMLRenderer.Render] that renders each
+// highlight as an inline `style` attribute.
+func (t *Theme) StyleAttributes() func(Highlight) string {
+	attributes := make([]string, len(t.Styles))
+	for i, style := range t.Styles {
+		if css := style.CSS(); css != "" {
+			attributes[i] = fmt.Sprintf("style='%s'", css)
+		}
+	}
+	return func(highlight Highlight) string {
+		if int(highlight) < len(attributes) {
+			return attributes[highlight]
+		}
+		return ""
+	}
+}
+
+// Get an attribute callback for [HTMLRenderer.Render] that renders each
+// highlight as a `class` attribute, with the dots in its name replaced by
+// spaces, e.g. `class="function builtin"`.
+func ClassAttributes(names []string) func(Highlight) string {
+	attributes := make([]string, len(names))
+	for i, name := range names {
+		attributes[i] = fmt.Sprintf(`class="%s"`, strings.ReplaceAll(name, ".", " "))
+	}
+	return func(highlight Highlight) string {
+		if int(highlight) < len(attributes) {
+			return attributes[highlight]
+		}
+		return ""
+	}
+}
+
+// Get the CSS declarations for the style, e.g. `color: #005fd7;font-weight: bold;`.
+func (s Style) CSS() string {
+	var css strings.Builder
+	if s.Color != nil {
+		fmt.Fprintf(&css, "color: %s;", s.Color.Hex())
+	}
+	if s.Bold {
+		css.WriteString("font-weight: bold;")
+	}
+	if s.Italic {
+		css.WriteString("font-style: italic;")
+	}
+	if s.Underline {
+		css.WriteString("text-decoration: underline;")
+	}
+	return css.String()
+}
+
+// Get the escape sequence that starts the style in a terminal.
+//
+// If `trueColor` is false, RGB colors are approximated by the closest
+// palette color. Returns an empty string for an empty style.
+func (s Style) ANSI(trueColor bool) string {
+	var codes []string
+	if s.Bold {
+		codes = append(codes, "1")
+	}
+	if s.Italic {
+		codes = append(codes, "3")
+	}
+	if s.Underline {
+		codes = append(codes, "4")
+	}
+	if s.Color != nil {
+		if s.Color.IsRGB && trueColor {
+			codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", s.Color.R, s.Color.G, s.Color.B))
+		} else {
+			codes = append(codes, fmt.Sprintf("38;5;%d", s.Color.Palette()))
+		}
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// Get the RGB components of the color. Palette colors are converted using
+// the standard xterm palette.
+func (c Color) RGB() (uint8, uint8, uint8) {
+	if c.IsRGB {
+		return c.R, c.G, c.B
+	}
+	switch {
+	case c.Index < 16:
+		rgb := systemColors[c.Index]
+		return rgb[0], rgb[1], rgb[2]
+	case c.Index < 232:
+		i := c.Index - 16
+		return colorCubeLevels[i/36], colorCubeLevels[i/6%6], colorCubeLevels[i%6]
+	default:
+		gray := 8 + 10*(c.Index-232)
+		return gray, gray, gray
+	}
+}
+
+// Get the index of the color in the 256-color palette. For RGB colors, this
+// is the closest color in the color cube or the grayscale ramp.
+func (c Color) Palette() uint8 {
+	if !c.IsRGB {
+		return c.Index
+	}
+
+	nearestLevel := func(value uint8) int {
+		best := 0
+		for i, level := range colorCubeLevels {
+			if absDiff(level, value) < absDiff(colorCubeLevels[best], value) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearestLevel(c.R), nearestLevel(c.G), nearestLevel(c.B)
+	cube := Color{Index: uint8(16 + 36*r + 6*g + b)}
+
+	average := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grayIndex := 23
+	if average < 238 {
+		grayIndex = max(0, (average-3)/10)
+	}
+	gray := Color{Index: uint8(232 + grayIndex)}
+
+	if c.distance(gray) < c.distance(cube) {
+		return gray.Index
+	}
+	return cube.Index
+}
+
+// Get the color as a CSS hex string, e.g. `#005fd7`.
+func (c Color) Hex() string {
+	r, g, b := c.RGB()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func (c Color) distance(other Color) int {
+	r1, g1, b1 := c.RGB()
+	r2, g2, b2 := other.RGB()
+	dr, dg, db := absDiff(r1, r2), absDiff(g1, g2), absDiff(b1, b2)
+	return dr*dr + dg*dg + db*db
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func (t *Theme) UnmarshalJSON(data []byte) error {
+	var styles map[string]*Style
+	if err := json.Unmarshal(data, &styles); err != nil {
+		return err
+	}
+	values := make(map[string]Style, len(styles))
+	for name, style := range styles {
+		if style != nil {
+			values[name] = *style
+		} else {
+			values[name] = Style{}
+		}
+	}
+	*t = *NewTheme(values)
+	return nil
+}
+
+func (t *Theme) MarshalJSON() ([]byte, error) {
+	styles := make(map[string]Style, len(t.Names))
+	for i, name := range t.Names {
+		styles[name] = t.Styles[i]
+	}
+	return json.Marshal(styles)
+}
+
+func (s *Style) UnmarshalJSON(data []byte) error {
+	var object struct {
+		Color     *Color `json:"color"`
+		Bold      bool   `json:"bold"`
+		Italic    bool   `json:"italic"`
+		Underline bool   `json:"underline"`
+	}
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		*s = Style{
+			Color:     object.Color,
+			Bold:      object.Bold,
+			Italic:    object.Italic,
+			Underline: object.Underline,
+		}
+		return nil
+	}
+
+	var color Color
+	if err := json.Unmarshal(data, &color); err != nil {
+		return err
+	}
+	*s = Style{Color: &color}
+	return nil
+}
+
+func (s Style) MarshalJSON() ([]byte, error) {
+	if !s.Bold && !s.Italic && !s.Underline {
+		if s.Color == nil {
+			return []byte("null"), nil
+		}
+		return json.Marshal(s.Color)
+	}
+	return json.Marshal(struct {
+		Color     *Color `json:"color,omitempty"`
+		Bold      bool   `json:"bold,omitempty"`
+		Italic    bool   `json:"italic,omitempty"`
+		Underline bool   `json:"underline,omitempty"`
+	}{s.Color, s.Bold, s.Italic, s.Underline})
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var index uint8
+	if err := json.Unmarshal(data, &index); err == nil {
+		*c = Color{Index: index}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("Invalid color %s: expected a palette index or a string", data)
+	}
+	if index, ok := namedColors[name]; ok {
+		*c = Color{Index: index}
+		return nil
+	}
+	if len(name) == 7 && name[0] == '#' {
+		value, err := strconv.ParseUint(name[1:], 16, 32)
+		if err == nil {
+			*c = Color{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), IsRGB: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid color %q", name)
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	if c.IsRGB {
+		return json.Marshal(c.Hex())
+	}
+	return json.Marshal(c.Index)
+}
@@ -0,0 +1,62 @@
+package highlight_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter/highlight"
+)
+
+func TestThemeFromJSON(t *testing.T) {
+	var theme Theme
+	err := json.Unmarshal([]byte(`{
+		"keyword": 56,
+		"string": "#00ff7f",
+		"comment": {"color": "blue", "italic": true},
+		"operator": {"bold": true},
+		"embedded": null
+	}`), &theme)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"comment", "embedded", "keyword", "operator", "string"}, theme.Names)
+	assert.Equal(t, Style{Color: &Color{Index: 4}, Italic: true}, theme.Styles[0])
+	assert.Equal(t, Style{}, theme.Styles[1])
+	assert.Equal(t, Style{Color: &Color{Index: 56}}, theme.Styles[2])
+	assert.Equal(t, Style{Bold: true}, theme.Styles[3])
+	assert.Equal(t, Style{Color: &Color{R: 0x00, G: 0xff, B: 0x7f, IsRGB: true}}, theme.Style(4))
+	assert.Equal(t, Style{}, theme.Style(5))
+
+	data, err := json.Marshal(&theme)
+	assert.Nil(t, err)
+	assert.JSONEq(
+		t,
+		`{"comment": {"color": 4, "italic": true}, "embedded": null, "keyword": 56, "operator": {"bold": true}, "string": "#00ff7f"}`,
+		string(data),
+	)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"keyword": "#12345"}`), &theme))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"keyword": "magenta"}`), &theme))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"keyword": 256}`), &theme))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"keyword": {"bold": 1}}`), &theme))
+}
+
+func TestStyles(t *testing.T) {
+	style := Style{Color: &Color{Index: 26}, Bold: true, Italic: true, Underline: true}
+	assert.Equal(t, "color: #005fd7;font-weight: bold;font-style: italic;text-decoration: underline;", style.CSS())
+	assert.Equal(t, "\x1b[1;3;4;38;5;26m", style.ANSI(true))
+	assert.Equal(t, "", Style{}.CSS())
+	assert.Equal(t, "", Style{}.ANSI(true))
+}
+
+func TestColorConversions(t *testing.T) {
+	assert.Equal(t, "#800000", Color{Index: 1}.Hex())
+	assert.Equal(t, "#ff0000", Color{Index: 196}.Hex())
+	assert.Equal(t, "#080808", Color{Index: 232}.Hex())
+	assert.Equal(t, "#eeeeee", Color{Index: 255}.Hex())
+
+	assert.Equal(t, uint8(196), Color{R: 0xff, IsRGB: true}.Palette())
+	assert.Equal(t, uint8(16), Color{IsRGB: true}.Palette())
+	assert.Equal(t, uint8(244), Color{R: 0x80, G: 0x80, B: 0x80, IsRGB: true}.Palette())
+	assert.Equal(t, uint8(26), Color{Index: 26}.Palette())
+}
@@ -0,0 +1,147 @@
+// Command gendecodertables generates the lookup tables of the decoders that
+// are built into the tree_sitter package, such as ShiftJISDecoder.
+//
+// The tables are derived from the encodings of golang.org/x/text, which is
+// why this command is a separate module: the tree_sitter package only needs
+// the generated C source, not the dependency. Run it with `go generate` in
+// the root of the repository, which writes `decoder_tables.c`.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/transform"
+)
+
+// The value in a table of single bytes that marks the first byte of a
+// two-byte character. It must match `TS_GO_LEAD_BYTE` in decoder.h.
+const leadByte = -2
+
+type table struct {
+	name     string
+	encoding encoding.Encoding
+}
+
+var tables = []table{
+	{"windows_1252", charmap.Windows1252},
+	{"shift_jis", japanese.ShiftJIS},
+	{"euc_kr", korean.EUCKR},
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gendecodertables output.c")
+		os.Exit(2)
+	}
+
+	var output bytes.Buffer
+	output.WriteString("// Code generated by internal/gendecodertables; DO NOT EDIT.\n\n")
+	output.WriteString("#include \"decoder.h\"\n")
+	for _, t := range tables {
+		if err := writeTable(&output, t); err != nil {
+			fmt.Fprintln(os.Stderr, "gendecodertables:", err)
+			os.Exit(1)
+		}
+	}
+	if err := os.WriteFile(os.Args[1], output.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "gendecodertables:", err)
+		os.Exit(1)
+	}
+}
+
+// Write a `TSGoDecodeTable` with the code points of every character of an
+// encoding: first the single bytes, where -1 marks an invalid byte, then the
+// pairs of bytes between the smallest and largest lead and trail bytes,
+// where 0 marks an invalid pair.
+func writeTable(output *bytes.Buffer, t table) error {
+	decoder := t.encoding.NewDecoder()
+	var buffer [utf8.UTFMax]byte
+	decode := func(src []byte) (int32, int, error) {
+		decoder.Reset()
+		nDst, nSrc, err := decoder.Transform(buffer[:], src, false)
+		if err != nil {
+			return -1, nSrc, err
+		}
+		r, size := utf8.DecodeRune(buffer[:nDst])
+		if r == utf8.RuneError || size != nDst {
+			return -1, nSrc, nil
+		}
+		return r, nSrc, nil
+	}
+
+	var single [256]int32
+	for b := range single {
+		codePoint, n, err := decode([]byte{byte(b)})
+		switch {
+		case err == transform.ErrShortSrc:
+			single[b] = leadByte
+		case n == 1:
+			single[b] = codePoint
+		default:
+			single[b] = -1
+		}
+	}
+
+	pairs := make(map[[2]int]int32)
+	minLead, maxLead := 256, -1
+	minTrail, maxTrail := 256, -1
+	for lead := range single {
+		if single[lead] != leadByte {
+			continue
+		}
+		for trail := 0; trail < 256; trail++ {
+			codePoint, n, _ := decode([]byte{byte(lead), byte(trail)})
+			if n != 2 || codePoint < 0 {
+				continue
+			}
+			if codePoint == 0 || codePoint > 0xffff {
+				return fmt.Errorf("%s: code point U+%04X of %02X %02X doesn't fit the table", t.name, codePoint, lead, trail)
+			}
+			pairs[[2]int{lead, trail}] = codePoint
+			minLead, maxLead = min(minLead, lead), max(maxLead, lead)
+			minTrail, maxTrail = min(minTrail, trail), max(maxTrail, trail)
+		}
+	}
+
+	fmt.Fprintf(output, "\n")
+	if len(pairs) > 0 {
+		fmt.Fprintf(output, "static const uint16_t %s_pairs[] = {", t.name)
+		k := 0
+		for lead := minLead; lead <= maxLead; lead++ {
+			for trail := minTrail; trail <= maxTrail; trail++ {
+				if k%12 == 0 {
+					output.WriteString("\n ")
+				}
+				fmt.Fprintf(output, " 0x%04x,", pairs[[2]int{lead, trail}])
+				k++
+			}
+		}
+		output.WriteString("\n};\n\n")
+	}
+
+	fmt.Fprintf(output, "const TSGoDecodeTable ts_go_%s_table = {\n", t.name)
+	output.WriteString("  .single = {")
+	for b, codePoint := range single {
+		if b%8 == 0 {
+			output.WriteString("\n   ")
+		}
+		fmt.Fprintf(output, " %d,", codePoint)
+	}
+	output.WriteString("\n  },\n")
+	if len(pairs) > 0 {
+		fmt.Fprintf(output, "  .min_lead = 0x%02x,\n", minLead)
+		fmt.Fprintf(output, "  .max_lead = 0x%02x,\n", maxLead)
+		fmt.Fprintf(output, "  .min_trail = 0x%02x,\n", minTrail)
+		fmt.Fprintf(output, "  .max_trail = 0x%02x,\n", maxTrail)
+		fmt.Fprintf(output, "  .pairs = %s_pairs,\n", t.name)
+	}
+	output.WriteString("};\n")
+	return nil
+}
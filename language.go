@@ -3,11 +3,35 @@ package tree_sitter
 /*
 #cgo CFLAGS: -Iinclude -Isrc -std=c11 -D_POSIX_C_SOURCE=200112L -D_DEFAULT_SOURCE
 #include <tree_sitter/api.h>
+
+// Count the terminal symbols of a language, whose ids are smaller than the
+// ids of all non-terminal symbols. The count isn't part of the public API,
+// so it is derived from the parse table: a non-terminal always has a next
+// state where it is valid, so the symbols that are valid without one only
+// cause reductions and are terminals, as are the symbols of the error state.
+static uint32_t language_token_count(const TSLanguage *self) {
+  uint32_t count = 0;
+  uint32_t state_count = ts_language_state_count(self);
+  TSLookaheadIterator *iterator = ts_lookahead_iterator_new(self, 0);
+  if (!iterator) return 0;
+  for (uint32_t state = 0; state < state_count; state++) {
+    ts_lookahead_iterator_reset_state(iterator, state);
+    while (ts_lookahead_iterator_next(iterator)) {
+      TSSymbol symbol = ts_lookahead_iterator_current_symbol(iterator);
+      if (symbol >= count && (state == 0 || ts_language_next_state(self, state, symbol) == 0)) {
+        count = symbol + 1;
+      }
+    }
+  }
+  ts_lookahead_iterator_delete(iterator);
+  return count;
+}
 */
 import "C"
 
 import (
 	"fmt"
+	"sync"
 	"unsafe"
 )
 
@@ -18,7 +42,8 @@ const MIN_COMPATIBLE_LANGUAGE_VERSION = C.TREE_SITTER_MIN_COMPATIBLE_LANGUAGE_VE
 // An opaque object that defines how to parse a particular language. The code
 // for each [Language] is generated by the Tree-sitter CLI.
 type Language struct {
-	Inner *C.TSLanguage
+	Inner   *C.TSLanguage
+	library unsafe.Pointer
 }
 
 // An error that occurred when trying to assign an incompatible [TSLanguage] to
@@ -57,6 +82,16 @@ func (l *Language) AbiVersion() uint32 {
 	return uint32(C.ts_language_abi_version(l.Inner))
 }
 
+// Get the name of this language. This returns an empty string in older
+// parsers.
+func (l *Language) Name() string {
+	ptr := C.ts_language_name(l.Inner)
+	if ptr == nil {
+		return ""
+	}
+	return C.GoString(ptr)
+}
+
 // Get the metadata for this language. This information is generated by the
 // CLI, and relies on the language author providing the correct metadata in
 // the language's `tree-sitter.json` file.
@@ -72,6 +107,26 @@ func (l *Language) Metadata() *LanguageMetadata {
 	}
 }
 
+// Check if the language came from a Wasm module. If so, then in order to use
+// this language with a [Parser], that parser must have a Wasm store assigned.
+func (l *Language) IsWasm() bool {
+	return bool(C.ts_language_is_wasm(l.Inner))
+}
+
+// Release the memory associated with a language that was created at
+// runtime, such as one loaded from a Wasm module, and unload the shared
+// library it was loaded from with [LoadLanguageFromLibrary], if any.
+//
+// Languages obtained from a grammar's Go bindings are statically allocated,
+// so closing them has no effect.
+func (l *Language) Close() {
+	C.ts_language_delete(l.Inner)
+	if l.library != nil {
+		closeLibrary(l.library)
+		l.library = nil
+	}
+}
+
 // Get the number of distinct node types in this language.
 func (l *Language) NodeKindCount() uint32 {
 	return uint32(C.ts_language_symbol_count(l.Inner))
@@ -109,6 +164,25 @@ func (l *Language) NodeKindIsSupertype(id uint16) bool {
 	return C.ts_language_symbol_type(l.Inner, C.TSSymbol(id)) == C.TSSymbolTypeSupertype
 }
 
+// Get a list of all supertype symbols for the language.
+//
+// This returns an empty slice in parsers generated before ABI version 15.
+func (l *Language) Supertypes() []uint16 {
+	var length C.uint32_t
+	ptr := C.ts_language_supertypes(l.Inner, &length)
+	return symbolSlice(ptr, length)
+}
+
+// Get a list of all subtype symbol ids for a given supertype symbol.
+//
+// See [Language.Supertypes] for fetching all supertype symbols. This returns
+// an empty slice in parsers generated before ABI version 15.
+func (l *Language) Subtypes(supertype uint16) []uint16 {
+	var length C.uint32_t
+	ptr := C.ts_language_subtypes(l.Inner, C.TSSymbol(supertype), &length)
+	return symbolSlice(ptr, length)
+}
+
 // Get the number of distinct field names in this language.
 func (l *Language) FieldCount() uint32 {
 	return uint32(C.ts_language_field_count(l.Inner))
@@ -152,6 +226,57 @@ func (l *Language) LookaheadIterator(state uint16) *LookaheadIterator {
 	return newLookaheadIterator(ptr)
 }
 
+// The number of terminal symbols of each language, by its pointer.
+var languageTokenCounts sync.Map
+
+// Get the number of terminal symbols in the language. Their ids are smaller
+// than the ids of all non-terminal symbols.
+func (l *Language) tokenCount() uint16 {
+	if count, ok := languageTokenCounts.Load(l.Inner); ok {
+		return count.(uint16)
+	}
+	count := uint16(C.language_token_count(l.Inner))
+	languageTokenCounts.Store(l.Inner, count)
+	return count
+}
+
+// Check if the given state is a parse state other than the error state.
+func (l *Language) isValidParseState(state uint16) bool {
+	return state != 0 && uint32(state) < l.ParseStateCount()
+}
+
+// Check if the given token is an extra in the given parse state, i.e. a
+// token like a comment that can appear anywhere. Extras are shifted without
+// changing the parse state.
+func (l *Language) isExtraToken(state uint16, symbol uint16) bool {
+	return symbol < l.tokenCount() && l.NextState(state, symbol) == state
+}
+
+// Check if the given token is valid in the given parse state.
+func (l *Language) isValidToken(state uint16, symbol uint16) bool {
+	iter := l.LookaheadIterator(state)
+	if iter == nil {
+		return false
+	}
+	defer iter.Close()
+	for _, valid := range iter.Iter() {
+		if valid == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+func symbolSlice(ptr *C.TSSymbol, length C.uint32_t) []uint16 {
+	symbols := make([]uint16, int(length))
+	if length > 0 {
+		for i, symbol := range unsafe.Slice(ptr, int(length)) {
+			symbols[i] = uint16(symbol)
+		}
+	}
+	return symbols
+}
+
 func (l *LanguageError) Error() string {
 	return fmt.Sprintf("Incompatible language version %d. Expected minimum %d, maximum %d", l.version, C.TREE_SITTER_MIN_COMPATIBLE_LANGUAGE_VERSION, C.TREE_SITTER_LANGUAGE_VERSION)
 }
@@ -0,0 +1,287 @@
+package tree_sitter
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The information that a [LanguageRegistry] uses to decide which
+// [Language] a file is written in.
+//
+// This mirrors the `file-types`, `first-line-regex` and `injection-regex`
+// keys of a grammar's `tree-sitter.json` file.
+type LanguageConfiguration struct {
+	// The name of the language, e.g. `javascript`. Defaults to
+	// [Language.Name] for languages that provide one.
+	Name string
+
+	// The language itself.
+	Language *Language
+
+	// The file extensions (without the leading dot) or whole file names that
+	// are written in this language, e.g. `js`, `mjs` or `Makefile`.
+	FileTypes []string
+
+	// A regular expression that is matched against the first line of a file
+	// to detect this language, e.g. `^#!.*\bnode\b`. This is also used to
+	// choose between languages that claim the same file type.
+	FirstLineRegex string
+
+	// The names of the interpreters that may appear in a shebang line for
+	// this language, e.g. `node` or `python`.
+	Interpreters []string
+
+	// A regular expression that is matched against the language name given
+	// in an injection, e.g. `^(js|javascript)$`.
+	InjectionRegex string
+
+	firstLineRegex *regexp.Regexp
+	injectionRegex *regexp.Regexp
+}
+
+// A thread-safe collection of [LanguageConfiguration]s that can be used to
+// find the right [Language] for a file or for an injected language name.
+type LanguageRegistry struct {
+	mu             sync.RWMutex
+	configurations []*LanguageConfiguration
+}
+
+// Create a new, empty language registry.
+func NewLanguageRegistry() *LanguageRegistry {
+	return &LanguageRegistry{}
+}
+
+// Add a language to the registry.
+//
+// Returns an error if the configuration has no language, if neither it nor
+// its language has a name, or if one of its regular expressions is invalid.
+// Languages registered earlier take precedence over languages registered
+// later when both match equally well.
+func (r *LanguageRegistry) Register(config LanguageConfiguration) error {
+	if config.Language == nil {
+		return fmt.Errorf("Language configuration for %s is missing a language", config.Name)
+	}
+	if config.Name == "" {
+		config.Name = config.Language.Name()
+	}
+	if config.Name == "" {
+		return fmt.Errorf("Language configuration is missing a name")
+	}
+
+	if config.FirstLineRegex != "" {
+		regex, err := regexp.Compile(config.FirstLineRegex)
+		if err != nil {
+			return fmt.Errorf("Invalid first line regex for %s: %w", config.Name, err)
+		}
+		config.firstLineRegex = regex
+	}
+	if config.InjectionRegex != "" {
+		regex, err := regexp.Compile(config.InjectionRegex)
+		if err != nil {
+			return fmt.Errorf("Invalid injection regex for %s: %w", config.Name, err)
+		}
+		config.injectionRegex = regex
+	}
+	config.FileTypes = append([]string(nil), config.FileTypes...)
+	config.Interpreters = append([]string(nil), config.Interpreters...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configurations = append(r.configurations, &config)
+	return nil
+}
+
+// Get all of the languages in the registry, in the order they were
+// registered.
+func (r *LanguageRegistry) Languages() []*LanguageConfiguration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*LanguageConfiguration(nil), r.configurations...)
+}
+
+// Get the language with the given name.
+//
+// Returns `nil` if no language with that name has been registered.
+func (r *LanguageRegistry) LanguageForName(name string) *LanguageConfiguration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, config := range r.configurations {
+		if config.Name == name {
+			return config
+		}
+	}
+	return nil
+}
+
+// Get the language for a given file path, based only on its file name and
+// extension.
+//
+// If several languages claim the same file type, the first one that was
+// registered is returned. Use [LanguageRegistry.Detect] to also take the
+// file's content into account.
+func (r *LanguageRegistry) LanguageForPath(path string) *LanguageConfiguration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	candidates := r.candidatesForPath(path)
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
+
+// Get the language for an injected language name, such as the `js` in a
+// Markdown code fence or the value of an `injection.language` property.
+//
+// The name is matched against each language's injection regex first. If
+// none matches, it is compared to the language names and file types,
+// ignoring case.
+func (r *LanguageRegistry) LanguageForInjection(name string) *LanguageConfiguration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, config := range r.configurations {
+		if config.injectionRegex != nil && config.injectionRegex.MatchString(name) {
+			return config
+		}
+	}
+	for _, config := range r.configurations {
+		if strings.EqualFold(config.Name, name) {
+			return config
+		}
+	}
+	for _, config := range r.configurations {
+		for _, fileType := range config.FileTypes {
+			if strings.EqualFold(fileType, name) {
+				return config
+			}
+		}
+	}
+	return nil
+}
+
+// Detect the language of a file from its path and content.
+//
+// The file name and extension are checked first. If more than one language
+// claims them, the first line regexes are used to pick one. If the path
+// doesn't identify a language, the interpreter named in a shebang line is
+// used, followed by the first line regexes of every registered language.
+//
+// The content may be `nil`, in which case only the path is used. Returns
+// `nil` if the language could not be detected.
+func (r *LanguageRegistry) Detect(path string, content []byte) *LanguageConfiguration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	firstLine := content
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	firstLine = bytes.TrimSuffix(firstLine, []byte{'\r'})
+
+	candidates := r.candidatesForPath(path)
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	if len(candidates) > 1 {
+		for _, config := range candidates {
+			if config.firstLineRegex != nil && config.firstLineRegex.Match(firstLine) {
+				return config
+			}
+		}
+		return candidates[0]
+	}
+
+	if interpreter := shebangInterpreter(firstLine); interpreter != "" {
+		trimmed := strings.TrimRight(interpreter, "0123456789.")
+		for _, config := range r.configurations {
+			for _, name := range config.Interpreters {
+				if name == interpreter || name == trimmed {
+					return config
+				}
+			}
+		}
+	}
+
+	for _, config := range r.configurations {
+		if config.firstLineRegex != nil && config.firstLineRegex.Match(firstLine) {
+			return config
+		}
+	}
+	return nil
+}
+
+// Get the semantic version of the language, as reported by
+// [Language.Metadata], in the form `major.minor.patch`.
+//
+// Returns an empty string if the language doesn't provide any metadata.
+func (c *LanguageConfiguration) Version() string {
+	metadata := c.Language.Metadata()
+	if metadata == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", metadata.MajorVersion, metadata.MinorVersion, metadata.PatchVersion)
+}
+
+func (r *LanguageRegistry) candidatesForPath(path string) []*LanguageConfiguration {
+	fileName := filepath.Base(path)
+
+	var candidates []*LanguageConfiguration
+	for _, config := range r.configurations {
+		for _, fileType := range config.FileTypes {
+			if fileType == fileName {
+				candidates = append(candidates, config)
+				break
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates
+	}
+
+	// Prefer the longest matching extension, so that `d.ts` wins over `ts`.
+	longest := 0
+	for _, config := range r.configurations {
+		length := 0
+		for _, fileType := range config.FileTypes {
+			if len(fileType) > length && strings.HasSuffix(fileName, "."+fileType) {
+				length = len(fileType)
+			}
+		}
+		if length == 0 || length < longest {
+			continue
+		}
+		if length > longest {
+			longest = length
+			candidates = candidates[:0]
+		}
+		candidates = append(candidates, config)
+	}
+	return candidates
+}
+
+// Get the name of the interpreter in a shebang line such as
+// `#!/usr/bin/env -S python3 -u`, without its directory.
+func shebangInterpreter(line []byte) string {
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return ""
+	}
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = filepath.Base(field)
+			break
+		}
+	}
+	return interpreter
+}
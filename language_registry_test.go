@@ -0,0 +1,129 @@
+package tree_sitter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func newTestRegistry(t *testing.T) *LanguageRegistry {
+	registry := NewLanguageRegistry()
+	configs := []LanguageConfiguration{
+		{
+			Name:           "javascript",
+			Language:       getLanguage("javascript"),
+			FileTypes:      []string{"js", "mjs", "cjs", "jsx"},
+			Interpreters:   []string{"node"},
+			InjectionRegex: "^(js|javascript)$",
+		},
+		{
+			Name:           "python",
+			Language:       getLanguage("python"),
+			FileTypes:      []string{"py", "pyi", "SConstruct"},
+			Interpreters:   []string{"python"},
+			InjectionRegex: "^(py|python)$",
+		},
+		{
+			Name:           "ruby",
+			Language:       getLanguage("ruby"),
+			FileTypes:      []string{"rb", "Rakefile", "Gemfile"},
+			Interpreters:   []string{"ruby"},
+			FirstLineRegex: `^#!.*\bruby\b`,
+		},
+		{
+			Name:      "cpp",
+			Language:  getLanguage("cpp"),
+			FileTypes: []string{"cc", "cpp", "h"},
+		},
+		{
+			Name:           "c",
+			Language:       getLanguage("c"),
+			FileTypes:      []string{"c", "h"},
+			FirstLineRegex: `^/\* C \*/`,
+		},
+		{
+			Name:      "html",
+			Language:  getLanguage("html"),
+			FileTypes: []string{"html", "htm"},
+		},
+		{
+			Name:      "embedded-template",
+			Language:  getLanguage("embedded-template"),
+			FileTypes: []string{"html.erb", "erb"},
+		},
+	}
+	for _, config := range configs {
+		assert.Nil(t, registry.Register(config))
+	}
+	return registry
+}
+
+func TestLanguageRegistryDetectByPath(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	assert.Equal(t, "javascript", registry.Detect("src/index.mjs", nil).Name)
+	assert.Equal(t, "python", registry.Detect("/a/b/SConstruct", nil).Name)
+	assert.Equal(t, "ruby", registry.Detect("Rakefile", nil).Name)
+	assert.Equal(t, "embedded-template", registry.Detect("views/index.html.erb", nil).Name)
+	assert.Equal(t, "html", registry.Detect("index.html", nil).Name)
+	assert.Equal(t, "cpp", registry.LanguageForPath("lib.h").Name)
+	assert.Nil(t, registry.Detect("README", nil))
+	assert.Nil(t, registry.LanguageForPath("main.rs"))
+}
+
+func TestLanguageRegistryDetectByContent(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	// Ambiguous file types are resolved with the first line regex.
+	assert.Equal(t, "cpp", registry.Detect("lib.h", []byte("int x;\n")).Name)
+	assert.Equal(t, "c", registry.Detect("lib.h", []byte("/* C */\nint x;\n")).Name)
+
+	// Shebang interpreters are used when the path has no known file type.
+	assert.Equal(t, "javascript", registry.Detect("bin/run", []byte("#!/usr/bin/env node\nconsole.log(1)\n")).Name)
+	assert.Equal(t, "python", registry.Detect("bin/run", []byte("#!/usr/bin/python3.11\nprint(1)\n")).Name)
+	assert.Equal(t, "python", registry.Detect("bin/run", []byte("#!/usr/bin/env -S python3 -u\r\nprint(1)\n")).Name)
+	assert.Equal(t, "ruby", registry.Detect("bin/run", []byte("#! /usr/local/bin/ruby -w\n")).Name)
+	assert.Nil(t, registry.Detect("bin/run", []byte("#!/bin/sh\n")))
+	assert.Nil(t, registry.Detect("bin/run", []byte("echo hi\n")))
+}
+
+func TestLanguageRegistryInjections(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	assert.Equal(t, "javascript", registry.LanguageForInjection("js").Name)
+	assert.Equal(t, "javascript", registry.LanguageForInjection("javascript").Name)
+	assert.Equal(t, "python", registry.LanguageForInjection("py").Name)
+	assert.Equal(t, "ruby", registry.LanguageForInjection("Ruby").Name)
+	assert.Equal(t, "cpp", registry.LanguageForInjection("cc").Name)
+	assert.Nil(t, registry.LanguageForInjection("rust"))
+}
+
+func TestLanguageRegistryMetadata(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	assert.Len(t, registry.Languages(), 7)
+	config := registry.LanguageForName("python")
+	assert.Equal(t, getLanguage("python"), config.Language)
+	assert.Equal(t, "", registry.LanguageForName("javascript").Version())
+	assert.Nil(t, registry.LanguageForName("rust"))
+}
+
+func TestLanguageRegistryInvalidConfiguration(t *testing.T) {
+	registry := NewLanguageRegistry()
+
+	// The JSON grammar is too old to have a name that could be used instead.
+	assert.NotNil(t, registry.Register(LanguageConfiguration{Language: getLanguage("json")}))
+	assert.NotNil(t, registry.Register(LanguageConfiguration{Name: "rust"}))
+	assert.NotNil(t, registry.Register(LanguageConfiguration{
+		Name:           "rust",
+		Language:       getLanguage("rust"),
+		FirstLineRegex: "(",
+	}))
+	assert.NotNil(t, registry.Register(LanguageConfiguration{
+		Name:           "rust",
+		Language:       getLanguage("rust"),
+		InjectionRegex: "[",
+	}))
+	assert.Empty(t, registry.Languages())
+}
@@ -1,9 +1,14 @@
 package tree_sitter_test
 
 import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
 )
 
 func TestSymbolMetadataChecks(t *testing.T) {
@@ -26,3 +31,81 @@ func TestSymbolMetadataChecks(t *testing.T) {
 		}
 	}
 }
+
+func TestLanguageIsNotWasm(t *testing.T) {
+	language := getLanguage("rust")
+	assert.False(t, language.IsWasm())
+	language.Close()
+}
+
+func TestLoadLanguageFromMissingLibrary(t *testing.T) {
+	language, err := LoadLanguageFromLibrary("./does-not-exist/libtree-sitter-foo.so", "foo")
+	assert.Nil(t, language)
+	var libraryErr *LibraryError
+	assert.ErrorAs(t, err, &libraryErr)
+	assert.Equal(t, "./does-not-exist/libtree-sitter-foo.so", libraryErr.Path)
+}
+
+func TestLoadLanguageFromLibrary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("building a shared library is only supported on Unix in this test")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler available")
+	}
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/tree-sitter/tree-sitter-json").Output()
+	if err != nil {
+		t.Skipf("failed to locate the JSON grammar: %v", err)
+	}
+	srcDir := filepath.Join(strings.TrimSpace(string(out)), "src")
+	libPath := filepath.Join(t.TempDir(), "libtree-sitter-json.so")
+	build := exec.Command(cc, "-shared", "-fPIC", "-I", srcDir, filepath.Join(srcDir, "parser.c"), "-o", libPath)
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build the JSON grammar: %v\n%s", err, out)
+	}
+
+	language, err := LoadLanguageFromLibrary(libPath, "json")
+	assert.Nil(t, err)
+	defer language.Close()
+
+	parser := NewParser()
+	defer parser.Close()
+	assert.Nil(t, parser.SetLanguage(language))
+
+	tree := parser.Parse([]byte(`{"a": [1, 2]}`), nil)
+	defer tree.Close()
+	assert.Equal(t, "(document (object (pair key: (string (string_content)) value: (array (number) (number)))))", tree.RootNode().ToSexp())
+
+	_, err = LoadLanguageFromLibrary(libPath, "not-json")
+	var libraryErr *LibraryError
+	assert.ErrorAs(t, err, &libraryErr)
+}
+
+func TestLanguageNameAndSupertypes(t *testing.T) {
+	// Names and supertype maps were added in ABI version 15.
+	json := getLanguage("json")
+	assert.Less(t, json.AbiVersion(), uint32(15))
+	assert.Equal(t, "", json.Name())
+	assert.Empty(t, json.Supertypes())
+
+	language := getLanguage("rust")
+	assert.GreaterOrEqual(t, language.AbiVersion(), uint32(15))
+	assert.Equal(t, "rust", language.Name())
+
+	supertypes := language.Supertypes()
+	var names []string
+	for _, supertype := range supertypes {
+		assert.True(t, language.NodeKindIsSupertype(supertype))
+		names = append(names, language.NodeKindForId(supertype))
+	}
+	assert.Contains(t, names, "_expression")
+
+	expression := language.IdForNodeKind("_expression", true)
+	var subtypes []string
+	for _, subtype := range language.Subtypes(expression) {
+		subtypes = append(subtypes, language.NodeKindForId(subtype))
+	}
+	assert.Contains(t, subtypes, "binary_expression")
+	assert.Contains(t, subtypes, "call_expression")
+}
@@ -0,0 +1,59 @@
+package tree_sitter
+
+/*
+#cgo CFLAGS: -Iinclude -Isrc -std=c11 -D_POSIX_C_SOURCE=200112L -D_DEFAULT_SOURCE
+#include <tree_sitter/api.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An error that occurred when trying to load a [Language] from a shared
+// library.
+type LibraryError struct {
+	Path    string
+	Message string
+}
+
+// Load a language from a compiled grammar shared library, such as
+// `libtree-sitter-javascript.so`, `libtree-sitter-javascript.dylib` or
+// `tree-sitter-javascript.dll`.
+//
+// The `name` is the name of the language as it appears in the grammar, and
+// is used to find the `tree_sitter_<name>` function exported by the library.
+// Dashes in the name are replaced with underscores.
+//
+// The language's ABI version is checked against this library's
+// [LANGUAGE_VERSION] and [MIN_COMPATIBLE_LANGUAGE_VERSION] constants, and a
+// [LanguageError] is returned if it is incompatible.
+//
+// The library stays loaded until [Language.Close] is called. Trees, queries
+// and other objects created with the language must not be used after that.
+func LoadLanguageFromLibrary(path string, name string) (*Language, error) {
+	handle, err := openLibrary(path)
+	if err != nil {
+		return nil, &LibraryError{Path: path, Message: err.Error()}
+	}
+
+	symbol := "tree_sitter_" + strings.ReplaceAll(name, "-", "_")
+	ptr, err := loadLanguageSymbol(handle, symbol)
+	if err != nil {
+		closeLibrary(handle)
+		return nil, &LibraryError{Path: path, Message: err.Error()}
+	}
+
+	language := &Language{Inner: (*C.TSLanguage)(ptr), library: handle}
+	version := language.AbiVersion()
+	if version < MIN_COMPATIBLE_LANGUAGE_VERSION || version > LANGUAGE_VERSION {
+		closeLibrary(handle)
+		return nil, &LanguageError{version}
+	}
+	return language, nil
+}
+
+func (e *LibraryError) Error() string {
+	return fmt.Sprintf("Failed to load language from %s: %s", e.Path, e.Message)
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package tree_sitter
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+
+static const void *_ts_call_language_fn(void *fn) {
+	return ((const void *(*)(void))fn)();
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// Wrapper for Unix systems
+func openLibrary(path string) (unsafe.Pointer, error) {
+	cPath := C.CString(path)
+	defer go_free(unsafe.Pointer(cPath))
+	handle := C.dlopen(cPath, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return nil, errors.New(C.GoString(C.dlerror()))
+	}
+	return handle, nil
+}
+
+func loadLanguageSymbol(handle unsafe.Pointer, symbol string) (unsafe.Pointer, error) {
+	cSymbol := C.CString(symbol)
+	defer go_free(unsafe.Pointer(cSymbol))
+	C.dlerror()
+	fn := C.dlsym(handle, cSymbol)
+	if fn == nil {
+		return nil, errors.New(C.GoString(C.dlerror()))
+	}
+	return unsafe.Pointer(C._ts_call_language_fn(fn)), nil
+}
+
+func closeLibrary(handle unsafe.Pointer) {
+	C.dlclose(handle)
+}
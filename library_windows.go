@@ -0,0 +1,42 @@
+//go:build windows
+
+package tree_sitter
+
+/*
+#include <windows.h>
+
+static const void *_ts_call_language_fn(FARPROC fn) {
+	return ((const void *(*)(void))fn)();
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Wrapper for Windows systems
+func openLibrary(path string) (unsafe.Pointer, error) {
+	cPath := C.CString(path)
+	defer go_free(unsafe.Pointer(cPath))
+	handle := C.LoadLibraryA(cPath)
+	if handle == nil {
+		return nil, fmt.Errorf("LoadLibrary failed with error code %d", uint32(C.GetLastError()))
+	}
+	return unsafe.Pointer(handle), nil
+}
+
+func loadLanguageSymbol(handle unsafe.Pointer, symbol string) (unsafe.Pointer, error) {
+	cSymbol := C.CString(symbol)
+	defer go_free(unsafe.Pointer(cSymbol))
+	fn := C.GetProcAddress(C.HMODULE(handle), cSymbol)
+	if fn == nil {
+		return nil, fmt.Errorf("%s: GetProcAddress failed with error code %d", symbol, uint32(C.GetLastError()))
+	}
+	return unsafe.Pointer(C._ts_call_language_fn(fn)), nil
+}
+
+func closeLibrary(handle unsafe.Pointer) {
+	C.FreeLibrary(C.HMODULE(handle))
+}
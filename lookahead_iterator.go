@@ -11,15 +11,24 @@ import (
 )
 
 type LookaheadIterator struct {
-	_inner *C.TSLookaheadIterator
+	_inner  *C.TSLookaheadIterator
+	cleanup *cleanup
 }
 
 func newLookaheadIterator(ptr *C.TSLookaheadIterator) *LookaheadIterator {
-	return &LookaheadIterator{_inner: ptr}
+	l := &LookaheadIterator{_inner: ptr}
+	l.cleanup = addCleanup("LookaheadIterator", func(ptr *C.TSLookaheadIterator) {
+		C.ts_lookahead_iterator_delete(ptr)
+	}, ptr)
+	return l
 }
 
 func (l *LookaheadIterator) Close() {
-	C.ts_lookahead_iterator_delete(l._inner)
+	if l._inner != nil {
+		l.cleanup.stop()
+		C.ts_lookahead_iterator_delete(l._inner)
+		l._inner = nil
+	}
 }
 
 func (l *LookaheadIterator) Language() *Language {
@@ -0,0 +1,276 @@
+// Package lsp converts between Tree-sitter positions, whose columns are
+// byte offsets, and Language Server Protocol positions, whose characters are
+// counted in the code units of a negotiated encoding.
+//
+// Only `\n` is treated as a line terminator, in agreement with the rows of
+// [tree_sitter.Point]. A `\r` before a `\n` is part of the line's text.
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// The encoding in which the characters of a [Position] are counted. The
+// values match the LSP `PositionEncodingKind` strings.
+type PositionEncoding string
+
+const (
+	// Characters are counted in bytes.
+	UTF8 PositionEncoding = "utf-8"
+
+	// Characters are counted in UTF-16 code units. This is the default
+	// encoding of the protocol.
+	UTF16 PositionEncoding = "utf-16"
+
+	// Characters are counted in Unicode code points.
+	UTF32 PositionEncoding = "utf-32"
+)
+
+// A zero-based position in a text document, as defined by the protocol.
+type Position struct {
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+}
+
+// A half-open range in a text document, as defined by the protocol.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// An index of the line starts of a text, for converting between byte
+// offsets, [tree_sitter.Point] values and [Position] values.
+//
+// The index does not copy the text, which must not be modified while the
+// index is in use.
+type LineIndex struct {
+	text       []byte
+	lineStarts []uint
+}
+
+// Create a new index for the given text.
+func NewLineIndex(text []byte) *LineIndex {
+	lineStarts := []uint{0}
+	for i, c := range text {
+		if c == '\n' {
+			lineStarts = append(lineStarts, uint(i)+1)
+		}
+	}
+	return &LineIndex{text: text, lineStarts: lineStarts}
+}
+
+// Get the indexed text.
+func (li *LineIndex) Text() []byte {
+	return li.text
+}
+
+// Get the number of lines in the text.
+func (li *LineIndex) LineCount() uint {
+	return uint(len(li.lineStarts))
+}
+
+// Get the text of the given line, without its line terminator.
+func (li *LineIndex) line(row uint) []byte {
+	end := uint(len(li.text))
+	if row+1 < uint(len(li.lineStarts)) {
+		end = li.lineStarts[row+1] - 1
+	}
+	return li.text[li.lineStarts[row]:end]
+}
+
+// Convert a byte offset to a position.
+//
+// An offset inside of a multi-byte character is rounded down to the start of
+// that character.
+func (li *LineIndex) Position(offset uint, encoding PositionEncoding) (Position, error) {
+	point, err := li.Point(offset)
+	if err != nil {
+		return Position{}, err
+	}
+	return li.PositionForPoint(point, encoding)
+}
+
+// Convert a Tree-sitter point to a position.
+func (li *LineIndex) PositionForPoint(point tree_sitter.Point, encoding PositionEncoding) (Position, error) {
+	if point.Row >= li.LineCount() {
+		return Position{}, fmt.Errorf("Row %d is out of range for a text with %d lines", point.Row, li.LineCount())
+	}
+	line := li.line(point.Row)
+	if point.Column > uint(len(line)) {
+		return Position{}, fmt.Errorf("Column %d is out of range for row %d of length %d", point.Column, point.Row, len(line))
+	}
+	units, err := unitsBefore(line, point.Column, encoding)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Line: uint32(point.Row), Character: uint32(units)}, nil
+}
+
+// Convert a byte offset to a Tree-sitter point.
+func (li *LineIndex) Point(offset uint) (tree_sitter.Point, error) {
+	if offset > uint(len(li.text)) {
+		return tree_sitter.Point{}, fmt.Errorf("Byte offset %d is out of range for a text of length %d", offset, len(li.text))
+	}
+	row := uint(sort.Search(len(li.lineStarts), func(i int) bool {
+		return li.lineStarts[i] > offset
+	})) - 1
+	return tree_sitter.NewPoint(row, offset-li.lineStarts[row]), nil
+}
+
+// Convert a position to a Tree-sitter point.
+//
+// As required by the protocol, a character past the end of the line refers
+// to the end of the line. A character inside of a multi-unit character is
+// rounded down to the start of that character.
+func (li *LineIndex) PointForPosition(position Position, encoding PositionEncoding) (tree_sitter.Point, error) {
+	row := uint(position.Line)
+	if row >= li.LineCount() {
+		return tree_sitter.Point{}, fmt.Errorf("Line %d is out of range for a text with %d lines", row, li.LineCount())
+	}
+	column, err := bytesBefore(li.line(row), uint(position.Character), encoding)
+	if err != nil {
+		return tree_sitter.Point{}, err
+	}
+	return tree_sitter.NewPoint(row, column), nil
+}
+
+// Convert a position to a byte offset.
+func (li *LineIndex) Offset(position Position, encoding PositionEncoding) (uint, error) {
+	point, err := li.PointForPosition(position, encoding)
+	if err != nil {
+		return 0, err
+	}
+	return li.lineStarts[point.Row] + point.Column, nil
+}
+
+// Convert a Tree-sitter range to a protocol range.
+func (li *LineIndex) Range(r tree_sitter.Range, encoding PositionEncoding) (Range, error) {
+	start, err := li.PositionForPoint(r.StartPoint, encoding)
+	if err != nil {
+		return Range{}, err
+	}
+	end, err := li.PositionForPoint(r.EndPoint, encoding)
+	if err != nil {
+		return Range{}, err
+	}
+	return Range{Start: start, End: end}, nil
+}
+
+// Convert a protocol range to a Tree-sitter range.
+func (li *LineIndex) TreeSitterRange(r Range, encoding PositionEncoding) (tree_sitter.Range, error) {
+	startPoint, err := li.PointForPosition(r.Start, encoding)
+	if err != nil {
+		return tree_sitter.Range{}, err
+	}
+	endPoint, err := li.PointForPosition(r.End, encoding)
+	if err != nil {
+		return tree_sitter.Range{}, err
+	}
+	return tree_sitter.Range{
+		StartByte:  li.lineStarts[startPoint.Row] + startPoint.Column,
+		EndByte:    li.lineStarts[endPoint.Row] + endPoint.Column,
+		StartPoint: startPoint,
+		EndPoint:   endPoint,
+	}, nil
+}
+
+// Compute the [tree_sitter.InputEdit] for replacing the given range with
+// `newText`, as described by a `TextDocumentContentChangeEvent`.
+func (li *LineIndex) InputEdit(r Range, newText []byte, encoding PositionEncoding) (tree_sitter.InputEdit, error) {
+	oldRange, err := li.TreeSitterRange(r, encoding)
+	if err != nil {
+		return tree_sitter.InputEdit{}, err
+	}
+	if oldRange.StartByte > oldRange.EndByte {
+		return tree_sitter.InputEdit{}, fmt.Errorf("Range end %v is before its start %v", r.End, r.Start)
+	}
+
+	return tree_sitter.InputEdit{
+		StartByte:      oldRange.StartByte,
+		OldEndByte:     oldRange.EndByte,
+		NewEndByte:     oldRange.StartByte + uint(len(newText)),
+		StartPosition:  oldRange.StartPoint,
+		OldEndPosition: oldRange.EndPoint,
+		NewEndPosition: oldRange.StartPoint.Advance(newText),
+	}, nil
+}
+
+// Apply a change to the text, and return an index of the new text along
+// with the edit that must be applied to a tree parsed from the old text.
+//
+// The indexed text is not modified.
+func (li *LineIndex) ApplyChange(r Range, newText []byte, encoding PositionEncoding) (*LineIndex, tree_sitter.InputEdit, error) {
+	edit, err := li.InputEdit(r, newText, encoding)
+	if err != nil {
+		return nil, tree_sitter.InputEdit{}, err
+	}
+	text := make([]byte, 0, len(li.text)-int(edit.OldEndByte-edit.StartByte)+len(newText))
+	text = append(text, li.text[:edit.StartByte]...)
+	text = append(text, newText...)
+	text = append(text, li.text[edit.OldEndByte:]...)
+	return NewLineIndex(text), edit, nil
+}
+
+// Count the code units of the complete characters in the first `column`
+// bytes of the line.
+func unitsBefore(line []byte, column uint, encoding PositionEncoding) (uint, error) {
+	if err := checkEncoding(encoding); err != nil {
+		return 0, err
+	}
+	var units, offset uint
+	for offset < column {
+		r, size := utf8.DecodeRune(line[offset:])
+		if offset+uint(size) > column {
+			break
+		}
+		units += unitLen(r, size, encoding)
+		offset += uint(size)
+	}
+	return units, nil
+}
+
+// Count the bytes of the complete characters in the first `units` code
+// units of the line.
+func bytesBefore(line []byte, units uint, encoding PositionEncoding) (uint, error) {
+	if err := checkEncoding(encoding); err != nil {
+		return 0, err
+	}
+	var count, offset uint
+	for offset < uint(len(line)) {
+		r, size := utf8.DecodeRune(line[offset:])
+		n := unitLen(r, size, encoding)
+		if count+n > units {
+			break
+		}
+		count += n
+		offset += uint(size)
+	}
+	return offset, nil
+}
+
+func checkEncoding(encoding PositionEncoding) error {
+	switch encoding {
+	case UTF8, UTF16, UTF32:
+		return nil
+	default:
+		return fmt.Errorf("Unsupported position encoding %q", encoding)
+	}
+}
+
+// Get the number of code units of a character that is encoded with `size`
+// bytes. Invalid bytes count as a single unit.
+func unitLen(r rune, size int, encoding PositionEncoding) uint {
+	switch encoding {
+	case UTF8:
+		return uint(size)
+	case UTF16:
+		if r >= 0x10000 {
+			return 2
+		}
+	}
+	return 1
+}
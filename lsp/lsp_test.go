@@ -0,0 +1,112 @@
+package lsp_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/lsp"
+)
+
+// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 UTF-16 units.
+const source = "let s = 'é😀x';\nfoo();\n"
+
+func TestPositionEncodings(t *testing.T) {
+	index := NewLineIndex([]byte(source))
+	assert.Equal(t, uint(3), index.LineCount())
+
+	xOffset := uint(15)
+	assert.Equal(t, byte('x'), source[xOffset])
+
+	for encoding, character := range map[PositionEncoding]uint32{
+		UTF8:  15,
+		UTF16: 12,
+		UTF32: 11,
+	} {
+		position, err := index.Position(xOffset, encoding)
+		assert.Nil(t, err)
+		assert.Equal(t, Position{Line: 0, Character: character}, position, encoding)
+
+		offset, err := index.Offset(position, encoding)
+		assert.Nil(t, err)
+		assert.Equal(t, xOffset, offset, encoding)
+	}
+
+	position, err := index.Position(20, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, Position{Line: 1, Character: 1}, position)
+
+	point, err := index.PointForPosition(Position{Line: 1, Character: 2}, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, tree_sitter.NewPoint(1, 2), point)
+}
+
+func TestPositionRounding(t *testing.T) {
+	index := NewLineIndex([]byte(source))
+
+	// An offset inside of the emoji is rounded down to its start.
+	position, err := index.Position(13, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, Position{Line: 0, Character: 10}, position)
+
+	// A character between the two UTF-16 units of the emoji is rounded down
+	// as well.
+	point, err := index.PointForPosition(Position{Line: 0, Character: 11}, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, tree_sitter.NewPoint(0, 11), point)
+
+	// A character past the end of the line refers to the end of the line.
+	point, err = index.PointForPosition(Position{Line: 1, Character: 100}, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, tree_sitter.NewPoint(1, 6), point)
+
+	_, err = index.PointForPosition(Position{Line: 3, Character: 0}, UTF16)
+	assert.NotNil(t, err)
+	_, err = index.Position(uint(len(source))+1, UTF16)
+	assert.NotNil(t, err)
+	_, err = index.Position(0, PositionEncoding("utf-7"))
+	assert.NotNil(t, err)
+}
+
+func TestRangeConversions(t *testing.T) {
+	index := NewLineIndex([]byte(source))
+
+	tsRange := tree_sitter.Range{
+		StartByte:  8,
+		EndByte:    17,
+		StartPoint: tree_sitter.NewPoint(0, 8),
+		EndPoint:   tree_sitter.NewPoint(0, 17),
+	}
+	lspRange, err := index.Range(tsRange, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, Range{Start: Position{0, 8}, End: Position{0, 14}}, lspRange)
+
+	roundTrip, err := index.TreeSitterRange(lspRange, UTF16)
+	assert.Nil(t, err)
+	assert.Equal(t, tsRange, roundTrip)
+}
+
+func TestApplyChange(t *testing.T) {
+	index := NewLineIndex([]byte(source))
+
+	// Replace the emoji and the `x` with two lines.
+	newIndex, edit, err := index.ApplyChange(
+		Range{Start: Position{0, 10}, End: Position{0, 13}},
+		[]byte("ü\nab"),
+		UTF16,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "let s = 'éü\nab';\nfoo();\n", string(newIndex.Text()))
+	assert.Equal(t, tree_sitter.InputEdit{
+		StartByte:      11,
+		OldEndByte:     16,
+		NewEndByte:     16,
+		StartPosition:  tree_sitter.NewPoint(0, 11),
+		OldEndPosition: tree_sitter.NewPoint(0, 16),
+		NewEndPosition: tree_sitter.NewPoint(1, 2),
+	}, edit)
+	assert.Equal(t, uint(4), newIndex.LineCount())
+
+	_, _, err = index.ApplyChange(Range{Start: Position{1, 0}, End: Position{0, 0}}, nil, UTF16)
+	assert.NotNil(t, err)
+}
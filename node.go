@@ -5,7 +5,11 @@ package tree_sitter
 #include <tree_sitter/api.h>
 */
 import "C"
-import "unsafe"
+
+import (
+	"iter"
+	"unsafe"
+)
 
 // A single node within a syntax [Tree].
 // Note that this is a C-compatible struct
@@ -259,6 +263,88 @@ func (n *Node) NamedChildren(cursor *TreeCursor) []Node {
 	return result
 }
 
+// Get an iterator over this node's children.
+//
+// Unlike [Node.Children], the iterator creates and closes its own
+// [TreeCursor], which is reused for all of the children. It is safe to
+// break out of the loop early.
+func (n *Node) ChildrenSeq() iter.Seq[Node] {
+	return func(yield func(Node) bool) {
+		cursor := n.Walk()
+		defer cursor.Close()
+		if !cursor.GotoFirstChild() {
+			return
+		}
+		for {
+			if !yield(*cursor.Node()) || !cursor.GotoNextSibling() {
+				return
+			}
+		}
+	}
+}
+
+// Get an iterator over this node's named children.
+//
+// See also [Node.ChildrenSeq].
+func (n *Node) NamedChildrenSeq() iter.Seq[Node] {
+	return func(yield func(Node) bool) {
+		for child := range n.ChildrenSeq() {
+			if child.IsNamed() && !yield(child) {
+				return
+			}
+		}
+	}
+}
+
+// Get an iterator over this node and all of its descendants, in preorder,
+// i.e. each node comes before its children.
+//
+// A single [TreeCursor] is used for the whole traversal. It is safe to break
+// out of the loop early.
+func (n *Node) Descendants() iter.Seq[Node] {
+	return func(yield func(Node) bool) {
+		cursor := n.Walk()
+		defer cursor.Close()
+		for {
+			if !yield(*cursor.Node()) {
+				return
+			}
+			if cursor.GotoFirstChild() {
+				continue
+			}
+			for !cursor.GotoNextSibling() {
+				if !cursor.GotoParent() {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Get an iterator over this node and all of its descendants, in postorder,
+// i.e. each node comes after its children.
+//
+// See also [Node.Descendants].
+func (n *Node) DescendantsPostorder() iter.Seq[Node] {
+	return func(yield func(Node) bool) {
+		cursor := n.Walk()
+		defer cursor.Close()
+		for cursor.GotoFirstChild() {
+		}
+		for {
+			if !yield(*cursor.Node()) {
+				return
+			}
+			if cursor.GotoNextSibling() {
+				for cursor.GotoFirstChild() {
+				}
+			} else if !cursor.GotoParent() {
+				return
+			}
+		}
+	}
+}
+
 // Iterate over this node's children with a given field name.
 //
 // See also [Node.Children].
@@ -210,6 +210,72 @@ func TestNodeChildren(t *testing.T) {
 	assert.Equal(t, []string{"{", "pair", "}"}, objectKinds)
 }
 
+func TestNodeChildrenSeq(t *testing.T) {
+	tree := parseJsonExample()
+	defer tree.Close()
+	arrayNode := tree.RootNode().Child(0)
+
+	var kinds []string
+	for child := range arrayNode.ChildrenSeq() {
+		kinds = append(kinds, child.Kind())
+	}
+	assert.Equal(t, []string{"[", "number", ",", "false", ",", "object", "]"}, kinds)
+
+	var namedKinds []string
+	for child := range arrayNode.NamedChildrenSeq() {
+		namedKinds = append(namedKinds, child.Kind())
+	}
+	assert.Equal(t, []string{"number", "false", "object"}, namedKinds)
+
+	// Breaking out early stops the iteration.
+	kinds = nil
+	for child := range arrayNode.ChildrenSeq() {
+		kinds = append(kinds, child.Kind())
+		if child.Kind() == "number" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"[", "number"}, kinds)
+
+	leaf := arrayNode.Child(0)
+	for range leaf.ChildrenSeq() {
+		t.Fatal("a leaf node has no children")
+	}
+}
+
+func TestNodeDescendants(t *testing.T) {
+	tree := parseJsonExample()
+	defer tree.Close()
+	objectNode := tree.RootNode().Child(0).NamedChild(2)
+
+	var preorder []string
+	for node := range objectNode.Descendants() {
+		preorder = append(preorder, node.Kind())
+	}
+	assert.Equal(t, []string{"object", "{", "pair", "string", "\"", "string_content", "\"", ":", "null", "}"}, preorder)
+	assert.Equal(t, int(objectNode.DescendantCount()), len(preorder))
+
+	var postorder []string
+	for node := range objectNode.DescendantsPostorder() {
+		postorder = append(postorder, node.Kind())
+	}
+	assert.Equal(t, []string{"{", "\"", "string_content", "\"", "string", ":", "null", "pair", "}", "object"}, postorder)
+
+	var visited []string
+	for node := range objectNode.Descendants() {
+		visited = append(visited, node.Kind())
+		if node.Kind() == "string" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"object", "{", "pair", "string"}, visited)
+
+	leaf := objectNode.Child(0)
+	for node := range leaf.DescendantsPostorder() {
+		assert.Equal(t, "{", node.Kind())
+	}
+}
+
 func TestNodeChildrenByFieldName(t *testing.T) {
 	parser := NewParser()
 	defer parser.Close()
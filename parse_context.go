@@ -0,0 +1,160 @@
+package tree_sitter
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unsafe"
+)
+
+var (
+	// Returned by the context-aware parse functions when the parser has no
+	// language.
+	ErrNoLanguage = errors.New("The parser has no language")
+
+	// Returned by the context-aware parse functions when parsing was stopped
+	// by [ParseOptions.ProgressCallback], or by the deprecated timeout or
+	// cancellation flag.
+	ErrParseHalted = errors.New("Parsing was halted before it finished")
+)
+
+// Parse UTF8 text provided in chunks by a callback, stopping when `ctx` is
+// done.
+//
+// This is like [Parser.ParseWithOptions], but reports why parsing stopped
+// early:
+//   - If `ctx` was cancelled or its deadline passed, the error is
+//     [context.Canceled] or [context.DeadlineExceeded], and the parser is
+//     reset, so that the next parse starts from the beginning. The same
+//     applies when the [ParserConfig.Timeout] of a parser from a
+//     [ParserPool] passed.
+//   - If the progress callback in `options` returned `true`, the error is
+//     [ErrParseHalted], and the parser is left as it is, so that calling
+//     this function again with the same arguments resumes where it left
+//     off. Call [Parser.Reset] to start over instead.
+//   - If the parser has no language, the error is [ErrNoLanguage].
+func (p *Parser) ParseWithContext(
+	ctx context.Context,
+	callback func(int, Point) []byte,
+	oldTree *Tree,
+	options *ParseOptions,
+) (*Tree, error) {
+	return p.parseWithContext(ctx, options, func(options *ParseOptions) *Tree {
+		return p.ParseWithOptions(callback, oldTree, options)
+	})
+}
+
+// Parse UTF16 little-endian text provided in chunks by a callback, stopping
+// when `ctx` is done.
+//
+// See [Parser.ParseUTF16LEWithOptions] for the arguments, and
+// [Parser.ParseWithContext] for the errors.
+func (p *Parser) ParseUTF16LEWithContext(
+	ctx context.Context,
+	callback func(int, Point) []uint16,
+	oldTree *Tree,
+	options *ParseOptions,
+) (*Tree, error) {
+	return p.parseWithContext(ctx, options, func(options *ParseOptions) *Tree {
+		return p.ParseUTF16LEWithOptions(callback, oldTree, options)
+	})
+}
+
+// Parse UTF16 big-endian text provided in chunks by a callback, stopping when
+// `ctx` is done.
+//
+// See [Parser.ParseUTF16BEWithOptions] for the arguments, and
+// [Parser.ParseWithContext] for the errors.
+func (p *Parser) ParseUTF16BEWithContext(
+	ctx context.Context,
+	callback func(int, Point) []uint16,
+	oldTree *Tree,
+	options *ParseOptions,
+) (*Tree, error) {
+	return p.parseWithContext(ctx, options, func(options *ParseOptions) *Tree {
+		return p.ParseUTF16BEWithOptions(callback, oldTree, options)
+	})
+}
+
+// Parse text in a custom encoding provided in chunks by a callback, stopping
+// when `ctx` is done.
+//
+// See [Parser.ParseCustomEncoding] for the arguments, and
+// [Parser.ParseWithContext] for the errors.
+func (p *Parser) ParseCustomEncodingWithContext(
+	ctx context.Context,
+	callback func(int, Point) []byte,
+	oldTree *Tree,
+	options *ParseOptions,
+	decode unsafe.Pointer,
+) (*Tree, error) {
+	return p.parseWithContext(ctx, options, func(options *ParseOptions) *Tree {
+		return p.ParseCustomEncoding(callback, oldTree, options, decode)
+	})
+}
+
+// Parse text in a non-Unicode encoding provided in chunks by a callback,
+// stopping when `ctx` is done.
+//
+// See [Parser.ParseWithDecoder] for the arguments, and
+// [Parser.ParseWithContext] for the errors.
+func (p *Parser) ParseWithDecoderContext(
+	ctx context.Context,
+	callback func(int, Point) []byte,
+	oldTree *Tree,
+	options *ParseOptions,
+	decoder Decoder,
+) (*Tree, error) {
+	return p.parseWithContext(ctx, options, func(options *ParseOptions) *Tree {
+		return p.ParseWithDecoder(callback, oldTree, options, decoder)
+	})
+}
+
+// Run a parse function with a progress callback that stops it when `ctx` is
+// done, and turn a `nil` tree into an error.
+func (p *Parser) parseWithContext(ctx context.Context, options *ParseOptions, parse func(*ParseOptions) *Tree) (*Tree, error) {
+	if p.timeout > 0 {
+		// Report the timeout as the context's deadline.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.Language() == nil {
+		return nil, ErrNoLanguage
+	}
+
+	haltedByCallback := false
+	tree := parse(&ParseOptions{
+		ProgressCallback: func(state ParseState) bool {
+			if ctx.Err() != nil {
+				return true
+			}
+			if options != nil && options.ProgressCallback != nil && options.ProgressCallback(state) {
+				haltedByCallback = true
+				return true
+			}
+			return false
+		},
+	})
+	if tree != nil {
+		return tree, nil
+	}
+
+	if haltedByCallback {
+		return nil, ErrParseHalted
+	}
+	err := ctx.Err()
+	if deadline, ok := ctx.Deadline(); ok && err == nil && !time.Now().Before(deadline) {
+		// The parser's timeout halted it before the context noticed that
+		// the same deadline passed.
+		err = context.DeadlineExceeded
+	}
+	if err != nil {
+		p.Reset()
+		return nil, err
+	}
+	return nil, ErrParseHalted
+}
@@ -0,0 +1,128 @@
+package tree_sitter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A JSON array of zeros that ends at byte offset `end`, which must be even.
+func longArray(end int) func(int, Point) []byte {
+	return func(offset int, _ Point) []byte {
+		if offset == 0 {
+			return []byte("[0")
+		} else if offset < end {
+			return []byte(",0")
+		} else if offset == end {
+			return []byte("]")
+		}
+		return []byte{}
+	}
+}
+
+func TestParserParseWithContext(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	tree, err := parser.ParseWithContext(context.Background(), func(i int, _ Point) []byte {
+		return []byte("[1, 2]")[min(i, 6):]
+	}, nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number) (number)))", tree.RootNode().ToSexp())
+}
+
+func TestParserParseWithContextCancelled(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree, err := parser.ParseWithContext(ctx, longArray(1000), nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, context.Canceled, err)
+
+	// Cancel the context while parsing.
+	ctx, cancel = context.WithCancel(context.Background())
+	tree, err = parser.ParseWithContext(ctx, func(offset int, _ Point) []byte {
+		if offset > 1000 {
+			cancel()
+		}
+		return []byte(",0")
+	}, nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, context.Canceled, err)
+
+	// The parser was reset, so the next parse starts from the beginning.
+	tree, err = parser.ParseWithContext(context.Background(), func(i int, _ Point) []byte {
+		return []byte("[3]")[min(i, 3):]
+	}, nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number)))", tree.RootNode().ToSexp())
+}
+
+func TestParserParseWithContextDeadline(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	tree, err := parser.ParseUTF16LEWithContext(ctx, func(int, Point) []uint16 {
+		return []uint16{',', '0'}
+	}, nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestParserParseWithContextHaltedByCallback(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	halt := true
+	options := &ParseOptions{ProgressCallback: func(state ParseState) bool {
+		return halt && state.CurrentByteOffset > 100
+	}}
+	tree, err := parser.ParseWithContext(context.Background(), longArray(1000), nil, options)
+	assert.Nil(t, tree)
+	assert.Equal(t, ErrParseHalted, err)
+
+	// The parser resumes where it stopped.
+	halt = false
+	tree, err = parser.ParseWithContext(context.Background(), longArray(1000), nil, options)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, "array", tree.RootNode().Child(0).Kind())
+	assert.Equal(t, uint(1001), tree.RootNode().EndByte())
+}
+
+func TestParserParseWithContextWithoutLanguage(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+
+	tree, err := parser.ParseWithContext(context.Background(), longArray(10), nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, ErrNoLanguage, err)
+}
+
+func TestParserParseCtxWithoutCancellationFlag(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, parser.ParseCtx(ctx, []byte("[1]"), nil))
+	assert.Nil(t, parser.CancellationFlag())
+
+	tree := parser.ParseCtx(context.Background(), []byte("[1]"), nil)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number)))", tree.RootNode().ToSexp())
+}
@@ -4,6 +4,7 @@ package tree_sitter
 #cgo CFLAGS: -Iinclude -Isrc -std=c11 -D_POSIX_C_SOURCE=200112L -D_DEFAULT_SOURCE
 #include <tree_sitter/api.h>
 #include <stdio.h>
+#include "decoder.h"
 
 extern void logCallback(void *payload, TSLogType log_type, char *message);
 extern char *readUTF8(void *payload, uint32_t byte_index, TSPoint position, uint32_t *bytes_read);
@@ -17,7 +18,8 @@ import "C"
 import (
 	"context"
 	"os"
-	"sync/atomic"
+	"runtime"
+	"time"
 	"unsafe"
 
 	"github.com/mattn/go-pointer"
@@ -26,7 +28,12 @@ import (
 // A stateful object that this is used to produce a [Tree] based on some
 // source code.
 type Parser struct {
-	_inner *C.TSParser
+	_inner  *C.TSParser
+	cleanup *cleanup
+
+	// The maximum duration of each parse, or zero for no limit. This is set
+	// by [ParserPool] from [ParserConfig.Timeout].
+	timeout time.Duration
 }
 
 // A stateful object that is passed into the progress callback [ParseOptions.ProgressCallback]
@@ -57,13 +64,26 @@ type ParseOptions struct {
 
 // Create a new parser.
 func NewParser() *Parser {
-	return &Parser{_inner: C.ts_parser_new()}
+	inner := C.ts_parser_new()
+	p := &Parser{_inner: inner}
+	p.cleanup = addCleanup("Parser", deleteParser, inner)
+	return p
 }
 
 func (p *Parser) Close() {
-	p.StopPrintingDotGraphs()
-	p.SetLogger(nil)
-	C.ts_parser_delete(p._inner)
+	if p._inner != nil {
+		p.cleanup.stop()
+		deleteParser(p._inner)
+		p._inner = nil
+	}
+}
+
+func deleteParser(inner *C.TSParser) {
+	C.ts_parser_print_dot_graphs(inner, C.int(-1))
+	prevLogger := C.ts_parser_logger(inner)
+	C.ts_parser_set_logger(inner, C.TSLogger{})
+	pointer.Unref(prevLogger.payload)
+	C.ts_parser_delete(inner)
 }
 
 // Set the language that the parser should use for parsing.
@@ -112,13 +132,6 @@ func logCallback(payload unsafe.Pointer, cLogType C.TSLogType, cMessage *C.char)
 // Set the logging callback that a parser should use during parsing.
 func (p *Parser) SetLogger(logger Logger) {
 	prevLogger := C.ts_parser_logger(p._inner)
-	if prevLogger.payload != nil {
-		// Clean up the old logger
-		oldLogger := (*Logger)(prevLogger.payload)
-		if oldLogger != nil {
-			oldLogger = nil
-		}
-	}
 
 	// Prepare the new logger
 	var cLogger C.TSLogger
@@ -138,8 +151,10 @@ func (p *Parser) SetLogger(logger Logger) {
 		}
 	}
 
-	// Set the new logger in the parser
+	// Set the new logger in the parser, and then release the old one, which
+	// the parser no longer refers to
 	C.ts_parser_set_logger(p._inner, cLogger)
+	pointer.Unref(prevLogger.payload)
 }
 
 // Get the parser's current logger.
@@ -178,7 +193,7 @@ func (p *Parser) Parse(text []byte, oldTree *Tree) *Tree {
 	}, oldTree, nil)
 }
 
-// Deprecated: Use [Parser.ParseWithOptions] instead, and handle cancellation in the callback, this will be removed in 0.26.
+// Deprecated: Use [Parser.ParseWithContext] instead, which reports why parsing stopped, this will be removed in 0.26.
 //
 // Parse a slice of UTF8 text.
 //
@@ -189,22 +204,13 @@ func (p *Parser) Parse(text []byte, oldTree *Tree) *Tree {
 //     document has changed since `old_tree` was created, then you must edit `old_tree` to match
 //     the new text using [Tree.Edit].
 func (p *Parser) ParseCtx(ctx context.Context, text []byte, oldTree *Tree) *Tree {
-	finish := make(chan struct{})
-
-	if ctx.Done() != nil {
-		go func() {
-			select {
-			case <-ctx.Done():
-				atomic.StoreUintptr(p.CancellationFlag(), 1)
-			case <-finish:
-				return
-			}
-		}()
-	}
-
-	tree := p.Parse(text, oldTree)
-	close(finish)
-
+	length := len(text)
+	tree, _ := p.ParseWithContext(ctx, func(i int, _ Point) []byte {
+		if i < length {
+			return text[i:]
+		}
+		return []byte{}
+	}, oldTree, nil)
 	return tree
 }
 
@@ -264,6 +270,11 @@ type payload[T any] struct {
 	callback func(int, Point) []T
 	text     []T
 	cStrings []*C.char
+
+	// Whether to free each chunk when the next one is read. The lexer only
+	// uses the latest chunk, so this keeps the memory used for the text
+	// bounded by the size of the chunks.
+	freeChunks bool
 }
 
 // This C function is passed to Tree-sitter as the input callback.
@@ -271,6 +282,12 @@ type payload[T any] struct {
 //export readUTF8
 func readUTF8(_payload unsafe.Pointer, byteIndex C.uint32_t, position C.TSPoint, bytesRead *C.uint32_t) *C.char {
 	payload := pointer.Restore(_payload).(*payload[byte])
+	if payload.freeChunks {
+		for _, cString := range payload.cStrings {
+			go_free(unsafe.Pointer(cString))
+		}
+		payload.cStrings = payload.cStrings[:0]
+	}
 	payload.text = payload.callback(int(byteIndex), Point{uint(position.row), uint(position.column)})
 	*bytesRead = C.uint32_t(len(payload.text))
 	strbytes := C.CString(string(payload.text))
@@ -317,10 +334,16 @@ func (p *Parser) ParseWith(callback func(int, Point) []byte, oldTree *Tree) *Tre
 //     the new text using [Tree.Edit].
 //   - `options` Options for parsing the text. This can be used to set a progress callback, or context.
 func (p *Parser) ParseWithOptions(callback func(int, Point) []byte, oldTree *Tree, options *ParseOptions) *Tree {
+	return p.parseUTF8(callback, oldTree, options, false)
+}
+
+func (p *Parser) parseUTF8(callback func(int, Point) []byte, oldTree *Tree, options *ParseOptions, freeChunks bool) *Tree {
+	options = p.withTimeout(options)
 	payload := payload[byte]{
-		callback: callback,
-		text:     nil,
-		cStrings: make([]*C.char, 0),
+		callback:   callback,
+		text:       nil,
+		cStrings:   make([]*C.char, 0),
+		freeChunks: freeChunks,
 	}
 
 	defer func() {
@@ -352,6 +375,9 @@ func (p *Parser) ParseWithOptions(callback func(int, Point) []byte, oldTree *Tre
 	}
 
 	cNewTree := C.ts_parser_parse_with_options(p._inner, cOldTree, cInput, cOptions)
+	// The parser's memory is released once the parser is unreachable, unless
+	// it was closed, so it must not become unreachable while it parses.
+	runtime.KeepAlive(p)
 
 	if cNewTree != nil {
 		return newTree(cNewTree)
@@ -444,6 +470,7 @@ func (p *Parser) ParseUTF16LEWith(callback func(int, Point) []uint16, oldTree *T
 //     the new text using [Tree.Edit].
 //   - `options` Options for parsing the text. This can be used to set a progress callback.
 func (p *Parser) ParseUTF16LEWithOptions(callback func(int, Point) []uint16, oldTree *Tree, options *ParseOptions) *Tree {
+	options = p.withTimeout(options)
 	payload := payload[uint16]{
 		callback: callback,
 		text:     nil,
@@ -479,6 +506,7 @@ func (p *Parser) ParseUTF16LEWithOptions(callback func(int, Point) []uint16, old
 	}
 
 	cNewTree := C.ts_parser_parse_with_options(p._inner, cOldTree, cInput, cOptions)
+	runtime.KeepAlive(p)
 
 	if cNewTree != nil {
 		return newTree(cNewTree)
@@ -515,6 +543,7 @@ func (p *Parser) ParseUTF16BEWith(callback func(int, Point) []uint16, oldTree *T
 //     the new text using [Tree.Edit].
 //   - `options` Options for parsing the text. This can be used to set a progress callback.
 func (p *Parser) ParseUTF16BEWithOptions(callback func(int, Point) []uint16, oldTree *Tree, options *ParseOptions) *Tree {
+	options = p.withTimeout(options)
 	payload := payload[uint16]{
 		callback: callback,
 		text:     nil,
@@ -550,6 +579,7 @@ func (p *Parser) ParseUTF16BEWithOptions(callback func(int, Point) []uint16, old
 	}
 
 	cNewTree := C.ts_parser_parse_with_options(p._inner, cOldTree, cInput, cOptions)
+	runtime.KeepAlive(p)
 
 	if cNewTree != nil {
 		return newTree(cNewTree)
@@ -559,9 +589,15 @@ func (p *Parser) ParseUTF16BEWithOptions(callback func(int, Point) []uint16, old
 }
 
 // Decoder interface defines the required method for custom decoding
+//
+// See [Parser.ParseWithDecoder].
 type Decoder interface {
 	// Decode takes a byte slice and returns the decoded code point and number of bytes consumed
 	// Returns -1 as codePoint if decoding fails
+	//
+	// Unless data is empty, bytesRead must be at least 1 and at most len(data), even if decoding
+	// fails, so that the parser can move past the invalid bytes. A bytesRead of 0 is treated as
+	// an invalid byte, and a larger bytesRead is limited to len(data).
 	Decode(data []byte) (codePoint int32, bytesRead uint32)
 }
 
@@ -603,12 +639,31 @@ func (p *Parser) ParseCustomEncoding(
 	options *ParseOptions,
 	decode unsafe.Pointer,
 ) *Tree {
+	return p.parseCustomEncoding(callback, oldTree, options, (*[0]byte)(decode), nil)
+}
+
+// Parse text with either a C decode function, or a Go decoder that is called
+// by a C decode function.
+func (p *Parser) parseCustomEncoding(
+	callback func(int, Point) []byte,
+	oldTree *Tree,
+	options *ParseOptions,
+	decode *[0]byte,
+	goDecoder Decoder,
+) *Tree {
+	options = p.withTimeout(options)
 	payload := &payload[byte]{
 		callback: callback,
 		text:     nil,
 		cStrings: make([]*C.char, 0),
 	}
 
+	defer func() {
+		for _, cString := range payload.cStrings {
+			go_free(unsafe.Pointer(cString))
+		}
+	}()
+
 	cptr := pointer.Save(payload)
 	defer pointer.Unref(cptr)
 
@@ -616,7 +671,7 @@ func (p *Parser) ParseCustomEncoding(
 		payload:  unsafe.Pointer(cptr),
 		read:     (*[0]byte)(C.readCustomEncoding),
 		encoding: C.TSInputEncodingCustom,
-		decode:   (*[0]byte)(decode),
+		decode:   decode,
 	}
 
 	var cOldTree *C.TSTree
@@ -632,7 +687,15 @@ func (p *Parser) ParseCustomEncoding(
 		}
 	}
 
-	cNewTree := C.ts_parser_parse_with_options(p._inner, cOldTree, cInput, cOptions)
+	var cNewTree *C.TSTree
+	if goDecoder != nil {
+		decoderPtr := pointer.Save(goDecoder)
+		defer pointer.Unref(decoderPtr)
+		cNewTree = C.ts_go_parse_with_go_decoder(p._inner, cOldTree, cInput, cOptions, decoderPtr)
+	} else {
+		cNewTree = C.ts_parser_parse_with_options(p._inner, cOldTree, cInput, cOptions)
+	}
+	runtime.KeepAlive(p)
 
 	if cNewTree != nil {
 		return newTree(cNewTree)
@@ -641,6 +704,27 @@ func (p *Parser) ParseCustomEncoding(
 	return nil
 }
 
+// Add a progress callback to `options` that halts parsing once the parser's
+// timeout has passed, if it has one.
+func (p *Parser) withTimeout(options *ParseOptions) *ParseOptions {
+	if p.timeout <= 0 {
+		return options
+	}
+	deadline := time.Now().Add(p.timeout)
+	var progressCallback func(ParseState) bool
+	if options != nil {
+		progressCallback = options.ProgressCallback
+	}
+	return &ParseOptions{
+		ProgressCallback: func(state ParseState) bool {
+			if time.Now().After(deadline) {
+				return true
+			}
+			return progressCallback != nil && progressCallback(state)
+		},
+	}
+}
+
 // Instruct the parser to start the next parse from the beginning.
 //
 // If the parser previously failed because of a timeout or a cancellation,
@@ -0,0 +1,249 @@
+package tree_sitter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Returned by [ParserPool.Get] when the pool has been closed.
+var ErrParserPoolClosed = errors.New("The parser pool is closed")
+
+// Settings that are applied to a parser that is taken from a [ParserPool].
+type ParserConfig struct {
+	// The maximum duration that each parse is allowed to take, or zero for no
+	// limit. A parse that takes longer is halted like one that is stopped by
+	// [ParseOptions.ProgressCallback], except that the context-aware parse
+	// functions, such as [Parser.ParseWithContext], return
+	// [context.DeadlineExceeded].
+	Timeout time.Duration
+
+	// The logging callback that the parser should use. See [Parser.SetLogger].
+	Logger Logger
+
+	// The ranges of text that the parser should include when parsing. See
+	// [Parser.SetIncludedRanges].
+	IncludedRanges []Range
+}
+
+// Statistics about the parsers of a [ParserPool].
+type ParserPoolStats struct {
+	// The number of parsers that are in use.
+	InUse int
+
+	// The number of parsers that are waiting to be reused.
+	Idle int
+
+	// The total number of parsers that were created.
+	Created uint64
+
+	// The total number of times that an idle parser was reused.
+	Reused uint64
+
+	// The total number of idle parsers that were closed to make room for a
+	// parser with a different language.
+	Evicted uint64
+
+	// The total number of times that [ParserPool.Get] had to wait for a parser
+	// to be returned.
+	Waits uint64
+}
+
+// A pool of parsers that can be shared between goroutines.
+//
+// Each parser is only used by one goroutine at a time: [ParserPool.Get]
+// takes a parser for a given language out of the pool, and
+// [ParserPool.Put] resets it and returns it to the pool, so that it can be
+// reused for the same language.
+type ParserPool struct {
+	maxParsers int
+
+	mutex sync.Mutex
+	idle  map[unsafe.Pointer][]*Parser
+	inUse map[*Parser]struct{}
+	// The order in which the idle parsers were returned, from the oldest to
+	// the newest, so that the least recently used ones are evicted first.
+	idleOrder []*Parser
+	stats     ParserPoolStats
+	closed    bool
+
+	// Closed and replaced whenever a parser is returned, so that waiting
+	// goroutines can try again.
+	returned chan struct{}
+}
+
+// Create a new parser pool.
+//
+// If `maxParsers` is positive, then at most that many parsers are alive at
+// the same time: when all of them are in use, [ParserPool.Get] waits for one
+// to be returned, and when idle parsers only exist for other languages, the
+// least recently used one is closed to make room.
+func NewParserPool(maxParsers int) *ParserPool {
+	return &ParserPool{
+		maxParsers: maxParsers,
+		idle:       make(map[unsafe.Pointer][]*Parser),
+		inUse:      make(map[*Parser]struct{}),
+		returned:   make(chan struct{}),
+	}
+}
+
+// Take a parser for the given language out of the pool, creating it if there
+// is no idle one.
+//
+// The parser is configured with `config`, which can be `nil`. It must be
+// returned to the pool with [ParserPool.Put] once it is no longer used.
+//
+// If the pool is full, this waits until a parser is returned or `ctx` is
+// done, in which case the context's error is returned. If the language is
+// incompatible with this library, a [LanguageError] is returned.
+func (pp *ParserPool) Get(ctx context.Context, language *Language, config *ParserConfig) (*Parser, error) {
+	if version := language.AbiVersion(); version < MIN_COMPATIBLE_LANGUAGE_VERSION || version > LANGUAGE_VERSION {
+		return nil, &LanguageError{version}
+	}
+	key := unsafe.Pointer(language.Inner)
+
+	for {
+		pp.mutex.Lock()
+		if pp.closed {
+			pp.mutex.Unlock()
+			return nil, ErrParserPoolClosed
+		}
+
+		if parsers := pp.idle[key]; len(parsers) > 0 {
+			parser := parsers[len(parsers)-1]
+			pp.removeIdle(key, parser)
+			pp.inUse[parser] = struct{}{}
+			pp.stats.Reused++
+			pp.mutex.Unlock()
+			return pp.configure(parser, config)
+		}
+
+		full := pp.maxParsers > 0 && len(pp.inUse)+len(pp.idleOrder) >= pp.maxParsers
+		if !full || len(pp.idleOrder) > 0 {
+			// Make room by closing the least recently used idle parser, which
+			// has a different language.
+			var evicted *Parser
+			if full {
+				evicted = pp.idleOrder[0]
+				pp.removeIdle(unsafe.Pointer(evicted.Language().Inner), evicted)
+				pp.stats.Evicted++
+			}
+			parser := NewParser()
+			parser.SetLanguage(language)
+			pp.inUse[parser] = struct{}{}
+			pp.stats.Created++
+			pp.mutex.Unlock()
+			if evicted != nil {
+				evicted.Close()
+			}
+			return pp.configure(parser, config)
+		}
+
+		returned := pp.returned
+		pp.stats.Waits++
+		pp.mutex.Unlock()
+
+		select {
+		case <-returned:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Reset a parser and return it to the pool.
+//
+// The parser must have been taken from this pool with [ParserPool.Get], and
+// must not be used after it was returned.
+func (pp *ParserPool) Put(parser *Parser) {
+	pp.mutex.Lock()
+	if _, ok := pp.inUse[parser]; !ok {
+		pp.mutex.Unlock()
+		panic("The parser was not taken from this pool")
+	}
+	delete(pp.inUse, parser)
+	closed := pp.closed
+	if !closed {
+		parser.Reset()
+		parser.timeout = 0
+		parser.SetLogger(nil)
+		parser.SetIncludedRanges(nil)
+		parser.StopPrintingDotGraphs()
+
+		key := unsafe.Pointer(parser.Language().Inner)
+		pp.idle[key] = append(pp.idle[key], parser)
+		pp.idleOrder = append(pp.idleOrder, parser)
+	}
+	close(pp.returned)
+	pp.returned = make(chan struct{})
+	pp.mutex.Unlock()
+
+	if closed {
+		parser.Close()
+	}
+}
+
+// Get statistics about the parsers of this pool.
+func (pp *ParserPool) Stats() ParserPoolStats {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	stats := pp.stats
+	stats.InUse = len(pp.inUse)
+	stats.Idle = len(pp.idleOrder)
+	return stats
+}
+
+// Close the idle parsers of this pool.
+//
+// Parsers that are in use are closed when they are returned, and
+// [ParserPool.Get] returns [ErrParserPoolClosed] from then on.
+func (pp *ParserPool) Close() {
+	pp.mutex.Lock()
+	idle := pp.idleOrder
+	pp.idle = make(map[unsafe.Pointer][]*Parser)
+	pp.idleOrder = nil
+	pp.closed = true
+	close(pp.returned)
+	pp.returned = make(chan struct{})
+	pp.mutex.Unlock()
+
+	for _, parser := range idle {
+		parser.Close()
+	}
+}
+
+func (pp *ParserPool) configure(parser *Parser, config *ParserConfig) (*Parser, error) {
+	if config == nil {
+		return parser, nil
+	}
+	if err := parser.SetIncludedRanges(config.IncludedRanges); err != nil {
+		pp.Put(parser)
+		return nil, err
+	}
+	parser.timeout = config.Timeout
+	if config.Logger != nil {
+		parser.SetLogger(config.Logger)
+	}
+	return parser, nil
+}
+
+func (pp *ParserPool) removeIdle(key unsafe.Pointer, parser *Parser) {
+	parsers := pp.idle[key]
+	for i, p := range parsers {
+		if p == parser {
+			pp.idle[key] = append(parsers[:i], parsers[i+1:]...)
+			break
+		}
+	}
+	if len(pp.idle[key]) == 0 {
+		delete(pp.idle, key)
+	}
+	for i, p := range pp.idleOrder {
+		if p == parser {
+			pp.idleOrder = append(pp.idleOrder[:i], pp.idleOrder[i+1:]...)
+			break
+		}
+	}
+}
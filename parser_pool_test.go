@@ -0,0 +1,179 @@
+package tree_sitter_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestParserPool(t *testing.T) {
+	pool := NewParserPool(0)
+	defer pool.Close()
+	json := getLanguage("json")
+
+	parser, err := pool.Get(context.Background(), json, nil)
+	assert.Nil(t, err)
+	tree := parser.Parse([]byte("[1, 2]"), nil)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number) (number)))", tree.RootNode().ToSexp())
+	pool.Put(parser)
+
+	// The idle parser is reused for the same language.
+	reused, err := pool.Get(context.Background(), getLanguage("json"), nil)
+	assert.Nil(t, err)
+	assert.Same(t, parser, reused)
+
+	other, err := pool.Get(context.Background(), getLanguage("python"), nil)
+	assert.Nil(t, err)
+	assert.NotSame(t, parser, other)
+	assert.Equal(t, getLanguage("python").Inner, other.Language().Inner)
+	pool.Put(reused)
+	pool.Put(other)
+
+	assert.Equal(t, ParserPoolStats{Idle: 2, Created: 2, Reused: 1}, pool.Stats())
+}
+
+func TestParserPoolConfig(t *testing.T) {
+	pool := NewParserPool(0)
+	defer pool.Close()
+	json := getLanguage("json")
+
+	var messages []string
+	source := []byte("[1] [2, 3]")
+	parser, err := pool.Get(context.Background(), json, &ParserConfig{
+		Timeout: time.Second,
+		Logger: func(_ LogType, message string) {
+			messages = append(messages, message)
+		},
+		IncludedRanges: []Range{{StartByte: 4, EndByte: 10, StartPoint: Point{0, 4}, EndPoint: Point{0, 10}}},
+	})
+	assert.Nil(t, err)
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number) (number)))", tree.RootNode().ToSexp())
+	assert.NotEmpty(t, messages)
+	pool.Put(parser)
+
+	// The settings are reset when the parser is returned.
+	parser, err = pool.Get(context.Background(), json, nil)
+	assert.Nil(t, err)
+	assert.Len(t, parser.IncludedRanges(), 1)
+	assert.Equal(t, uint(0), parser.IncludedRanges()[0].StartByte)
+	messages = nil
+	tree = parser.Parse(source, nil)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number)) (array (number) (number)))", tree.RootNode().ToSexp())
+	assert.Empty(t, messages)
+	pool.Put(parser)
+
+	// Invalid ranges return the parser to the pool.
+	_, err = pool.Get(context.Background(), json, &ParserConfig{
+		IncludedRanges: []Range{{StartByte: 4, EndByte: 10}, {StartByte: 2, EndByte: 3}},
+	})
+	assert.Equal(t, &IncludedRangesError{1}, err)
+	assert.Equal(t, 0, pool.Stats().InUse)
+}
+
+func TestParserPoolTimeout(t *testing.T) {
+	pool := NewParserPool(0)
+	defer pool.Close()
+	json := getLanguage("json")
+	source := []byte("[" + strings.Repeat("[1, 2, 3], ", 10000) + "0]")
+
+	parser, err := pool.Get(context.Background(), json, &ParserConfig{Timeout: time.Nanosecond})
+	assert.Nil(t, err)
+	assert.Nil(t, parser.Parse(source, nil))
+	tree, err := parser.ParseWithContext(context.Background(), func(i int, _ Point) []byte {
+		return source[min(i, len(source)):]
+	}, nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	pool.Put(parser)
+
+	// The timeout is removed when the parser is returned.
+	parser, err = pool.Get(context.Background(), json, nil)
+	assert.Nil(t, err)
+	tree = parser.Parse(source, nil)
+	assert.NotNil(t, tree)
+	tree.Close()
+	pool.Put(parser)
+}
+
+func TestParserPoolBounded(t *testing.T) {
+	pool := NewParserPool(1)
+	defer pool.Close()
+	json := getLanguage("json")
+
+	parser, err := pool.Get(context.Background(), json, nil)
+	assert.Nil(t, err)
+
+	// The pool is full, so this waits until the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(ctx, json, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// The idle parser is closed to make room for another language.
+	pool.Put(parser)
+	parser, err = pool.Get(context.Background(), getLanguage("python"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, getLanguage("python").Inner, parser.Language().Inner)
+	pool.Put(parser)
+
+	stats := pool.Stats()
+	assert.Equal(t, ParserPoolStats{Idle: 1, Created: 2, Evicted: 1, Waits: 1}, stats)
+}
+
+func TestParserPoolConcurrent(t *testing.T) {
+	pool := NewParserPool(2)
+	defer pool.Close()
+	languages := []*Language{getLanguage("json"), getLanguage("python")}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				parser, err := pool.Get(context.Background(), languages[(i+j)%2], nil)
+				assert.Nil(t, err)
+				tree := parser.Parse([]byte("[1]"), nil)
+				assert.NotNil(t, tree)
+				tree.Close()
+				pool.Put(parser)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := pool.Stats()
+	assert.Equal(t, 0, stats.InUse)
+	assert.LessOrEqual(t, stats.Idle, 2)
+	assert.Equal(t, uint64(160), stats.Created+stats.Reused)
+}
+
+func TestParserPoolClose(t *testing.T) {
+	pool := NewParserPool(1)
+	json := getLanguage("json")
+
+	parser, err := pool.Get(context.Background(), json, nil)
+	assert.Nil(t, err)
+
+	waiting := make(chan error)
+	go func() {
+		_, err := pool.Get(context.Background(), json, nil)
+		waiting <- err
+	}()
+	pool.Close()
+	assert.Equal(t, ErrParserPoolClosed, <-waiting)
+
+	pool.Put(parser)
+	assert.Equal(t, 0, pool.Stats().InUse)
+	assert.Equal(t, 0, pool.Stats().Idle)
+	assert.Panics(t, func() { pool.Put(parser) })
+}
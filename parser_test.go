@@ -178,6 +178,27 @@ func TestParsingWithLogging(t *testing.T) {
 	assert.True(t, rowStartsFrom0)
 }
 
+func TestParserSetLoggerReleasesOldLogger(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+
+	var released atomic.Bool
+	func() {
+		state := new([]string)
+		runtime.SetFinalizer(state, func(*[]string) { released.Store(true) })
+		parser.SetLogger(func(_ LogType, message string) {
+			*state = append(*state, message)
+		})
+	}()
+	parser.SetLogger(nil)
+
+	for i := 0; i < 10 && !released.Load(); i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, released.Load())
+}
+
 func TestParsingWithDebugGraphEnabled(t *testing.T) {
 	hasZeroIndexedRow := func(s string) bool {
 		return strings.Contains(s, "position: 0,")
@@ -6,6 +6,8 @@ package tree_sitter
 */
 import "C"
 
+import "bytes"
+
 // A position in a multi-line text document, in terms of rows and columns.
 //
 // Rows and columns are zero-based.
@@ -18,6 +20,18 @@ func NewPoint(row, column uint) Point {
 	return Point{Row: row, Column: column}
 }
 
+// Get the point that follows the given text, if the text starts at this
+// point.
+//
+// Columns are counted in bytes, so this can be used to compute the positions
+// for an [InputEdit] from the text that was inserted.
+func (p Point) Advance(text []byte) Point {
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		return Point{Row: p.Row + uint(bytes.Count(text, []byte{'\n'})), Column: uint(len(text) - i - 1)}
+	}
+	return Point{Row: p.Row, Column: p.Column + uint(len(text))}
+}
+
 func (p *Point) toTSPoint() C.TSPoint {
 	return C.TSPoint{
 		row:    C.uint32_t(p.Row),
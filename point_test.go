@@ -0,0 +1,14 @@
+package tree_sitter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestPointAdvance(t *testing.T) {
+	assert.Equal(t, NewPoint(2, 5), NewPoint(2, 3).Advance([]byte("ab")))
+	assert.Equal(t, NewPoint(4, 1), NewPoint(2, 3).Advance([]byte("ab\n\nc")))
+	assert.Equal(t, NewPoint(3, 0), NewPoint(2, 3).Advance([]byte("\n")))
+}
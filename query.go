@@ -11,8 +11,10 @@ import "C"
 import (
 	"bytes"
 	"fmt"
+	"iter"
 	"math"
 	"regexp"
+	"runtime"
 	"strings"
 	"unsafe"
 
@@ -21,12 +23,15 @@ import (
 
 type Query struct {
 	_inner             *C.TSQuery
+	cleanup            *cleanup
 	captureNames       []string
 	captureQuantifiers [][]CaptureQuantifier
 	TextPredicates     [][]TextPredicateCapture
 	propertySettings   [][]QueryProperty
 	propertyPredicates [][]PropertyPredicate
 	generalPredicates  [][]QueryPredicate
+	customPredicates   [][]customPredicateCall
+	directives         [][]directiveCall
 }
 
 type CaptureQuantifier int
@@ -58,7 +63,8 @@ func newCaptureQuantifier(raw C.TSQuantifier) CaptureQuantifier {
 
 // A stateful object for executing a [Query] on a syntax [Tree].
 type QueryCursor struct {
-	_inner *C.TSQueryCursor
+	_inner  *C.TSQueryCursor
+	cleanup *cleanup
 }
 
 // A stateful object that is passed into the progress callback [QueryOptions.ProgressCallback].
@@ -102,11 +108,17 @@ type QueryMatch struct {
 	Captures     []QueryCapture
 	PatternIndex uint
 	id           uint
+
+	// The metadata set by the directives of the pattern that matched, or
+	// `nil` if the pattern has no directives registered with
+	// [RegisterDirective].
+	Metadata *QueryMatchMetadata
 }
 
 // A sequence of [QueryMatch]es associated with a given [QueryCursor].
 type QueryMatches struct {
 	_inner *C.TSQueryCursor
+	cursor *QueryCursor
 	query  *Query
 	text   []byte
 }
@@ -114,6 +126,7 @@ type QueryMatches struct {
 // A sequence of [QueryCapture]s associated with a given [QueryCursor].
 type QueryCaptures struct {
 	_inner  *C.TSQueryCursor
+	cursor  *QueryCursor
 	query   *Query
 	text    []byte
 	buffer1 []byte
@@ -194,7 +207,19 @@ const (
 	QueryErrorLanguage
 )
 
+// Create a new query from a string containing one or more S-expression
+// patterns.
+//
+// The query is associated with a particular language, and can only be run
+// on syntax nodes parsed with that language. Its predicates and directives
+// are resolved with the ones registered by [RegisterPredicate] and
+// [RegisterDirective]; operators that aren't known are returned by
+// [Query.GeneralPredicates].
 func NewQuery(language *Language, source string) (*Query, *QueryError) {
+	return newQuery(language, source, defaultPredicateRegistry)
+}
+
+func newQuery(language *Language, source string, registry *PredicateRegistry) (*Query, *QueryError) {
 	var errorOffset C.uint32_t
 	var errorType C.TSQueryError
 	bytes := []byte(source)
@@ -310,11 +335,11 @@ func NewQuery(language *Language, source string) (*Query, *QueryError) {
 		}
 	}
 
-	res, err := fromRawParts(ptr, source)
+	res, err := fromRawParts(ptr, source, registry)
 	return res, err
 }
 
-func fromRawParts(ptr *C.TSQuery, source string) (*Query, *QueryError) {
+func fromRawParts(ptr *C.TSQuery, source string, registry *PredicateRegistry) (*Query, *QueryError) {
 	stringCount := int(C.ts_query_string_count(ptr))
 	captureCount := int(C.ts_query_capture_count(ptr))
 	patternCount := int(C.ts_query_pattern_count(ptr))
@@ -325,6 +350,8 @@ func fromRawParts(ptr *C.TSQuery, source string) (*Query, *QueryError) {
 	propertyPredicatesVec := make([][]PropertyPredicate, patternCount)
 	propertySettingsVec := make([][]QueryProperty, patternCount)
 	generalPredicatesVec := make([][]QueryPredicate, patternCount)
+	customPredicatesVec := make([][]customPredicateCall, patternCount)
+	directivesVec := make([][]directiveCall, patternCount)
 
 	// Build a vector of strings to store the capture names.
 	for i := 0; i < captureCount; i++ {
@@ -377,6 +404,8 @@ func fromRawParts(ptr *C.TSQuery, source string) (*Query, *QueryError) {
 		propertyPredicates := make([]PropertyPredicate, 0)
 		propertySettings := make([]QueryProperty, 0)
 		generalPredicates := make([]QueryPredicate, 0)
+		var customPredicates []customPredicateCall
+		var directives []directiveCall
 
 		// iterate over predicateSteps, and consi
 		split := func(steps []C.TSQueryPredicateStep, sep C.TSQueryPredicateStepType) [][]C.TSQueryPredicateStep {
@@ -526,10 +555,36 @@ func fromRawParts(ptr *C.TSQuery, source string) (*Query, *QueryError) {
 						*args[len(args)-1].String = stringValues[a.value_id]
 					}
 				}
-				generalPredicates = append(generalPredicates, QueryPredicate{
-					Operator: operatorName,
-					Args:     args,
-				})
+
+				predicate, directive := registry.lookup(operatorName)
+				switch {
+				case predicate != nil:
+					if predicate.Validate != nil {
+						if err := predicate.Validate(args); err != nil {
+							C.ts_query_delete(ptr)
+							return nil, predicateError(uint(row), fmt.Sprintf("Invalid arguments to #%s predicate: %s", operatorName, err))
+						}
+					}
+					customPredicates = append(customPredicates, customPredicateCall{
+						evaluate: predicate.Evaluate,
+						args:     args,
+					})
+				case directive != nil:
+					apply, err := directive.prepare(args)
+					if err != nil {
+						C.ts_query_delete(ptr)
+						return nil, predicateError(uint(row), fmt.Sprintf("Invalid arguments to #%s directive: %s", operatorName, err))
+					}
+					directives = append(directives, directiveCall{
+						apply: apply,
+						args:  args,
+					})
+				default:
+					generalPredicates = append(generalPredicates, QueryPredicate{
+						Operator: operatorName,
+						Args:     args,
+					})
+				}
 			}
 		}
 
@@ -537,22 +592,31 @@ func fromRawParts(ptr *C.TSQuery, source string) (*Query, *QueryError) {
 		propertyPredicatesVec[i] = propertyPredicates
 		propertySettingsVec[i] = propertySettings
 		generalPredicatesVec[i] = generalPredicates
+		customPredicatesVec[i] = customPredicates
+		directivesVec[i] = directives
 	}
 
 	query := &Query{
 		_inner:             ptr,
+		cleanup:            addLeakReport("Query"),
 		captureNames:       captureNames,
 		captureQuantifiers: captureQuantifiersVec,
 		TextPredicates:     textPredicatesVec,
 		propertyPredicates: propertyPredicatesVec,
 		propertySettings:   propertySettingsVec,
 		generalPredicates:  generalPredicatesVec,
+		customPredicates:   customPredicatesVec,
+		directives:         directivesVec,
 	}
 	return query, nil
 }
 
 func (q *Query) Close() {
-	C.ts_query_delete(q._inner)
+	if q._inner != nil {
+		q.cleanup.stop()
+		C.ts_query_delete(q._inner)
+		q._inner = nil
+	}
 }
 
 // Get the byte offset where the given pattern starts in the query's source.
@@ -617,6 +681,11 @@ func (q *Query) PropertySettings(index uint) []QueryProperty {
 // * `eq?` and `not-eq?`
 // * `is?` and `is-not?`
 // * `set!`
+// * the predicates and directives registered with [RegisterPredicate] and
+// [RegisterDirective], or with the [PredicateRegistry] that created the query
+//
+// Operators that aren't known are not an error, and don't affect which
+// patterns match. Check for them here to reject queries that use them.
 func (q *Query) GeneralPredicates(index uint) []QueryPredicate {
 	return q.generalPredicates[index]
 }
@@ -700,12 +769,21 @@ func parseProperty(row uint, functionName string, captureNames []string, stringV
 // The cursor stores the state that is needed to iteratively search for
 // matches.
 func NewQueryCursor() *QueryCursor {
-	return &QueryCursor{_inner: C.ts_query_cursor_new()}
+	inner := C.ts_query_cursor_new()
+	qc := &QueryCursor{_inner: inner}
+	qc.cleanup = addCleanup("QueryCursor", func(inner *C.TSQueryCursor) {
+		C.ts_query_cursor_delete(inner)
+	}, inner)
+	return qc
 }
 
 // Delete the underlying memory for a query cursor.
 func (qc *QueryCursor) Close() {
-	C.ts_query_cursor_delete(qc._inner)
+	if qc._inner != nil {
+		qc.cleanup.stop()
+		C.ts_query_cursor_delete(qc._inner)
+		qc._inner = nil
+	}
 }
 
 // Return the maximum number of in-progress matches for this cursor.
@@ -750,6 +828,7 @@ func (qc *QueryCursor) Matches(query *Query, node *Node, text []byte) QueryMatch
 	C.ts_query_cursor_exec(qc._inner, query._inner, node._inner)
 	qm := QueryMatches{
 		_inner: qc._inner,
+		cursor: qc,
 		query:  query,
 		text:   text,
 	}
@@ -789,6 +868,7 @@ func (qc *QueryCursor) MatchesWithOptions(query *Query, node *Node, text []byte,
 
 	qm := QueryMatches{
 		_inner: qc._inner,
+		cursor: qc,
 		query:  query,
 		text:   text,
 	}
@@ -811,6 +891,7 @@ func (qc *QueryCursor) Captures(query *Query, node *Node, text []byte) QueryCapt
 	C.ts_query_cursor_exec(qc._inner, query._inner, node._inner)
 	return QueryCaptures{
 		_inner:  qc._inner,
+		cursor:  qc,
 		query:   query,
 		text:    text,
 		buffer1: []byte{},
@@ -818,6 +899,39 @@ func (qc *QueryCursor) Captures(query *Query, node *Node, text []byte) QueryCapt
 	}
 }
 
+// Get an iterator over all of the matches in the order that they were found.
+//
+// This is the same as [QueryCursor.Matches], and the same caveat applies:
+// the memory of each match is reused for the next one, so anything that is
+// needed after the loop body must be copied. It is safe to break out of the
+// loop early.
+func (qc *QueryCursor) MatchesSeq(query *Query, node *Node, text []byte) iter.Seq[*QueryMatch] {
+	return func(yield func(*QueryMatch) bool) {
+		matches := qc.Matches(query, node, text)
+		for match := matches.Next(); match != nil; match = matches.Next() {
+			if !yield(match) {
+				return
+			}
+		}
+	}
+}
+
+// Get an iterator over all of the individual captures in the order that they
+// appear, along with the index of each capture within its match.
+//
+// This is the same as [QueryCursor.Captures], see also
+// [QueryCursor.MatchesSeq].
+func (qc *QueryCursor) CapturesSeq(query *Query, node *Node, text []byte) iter.Seq2[*QueryMatch, uint] {
+	return func(yield func(*QueryMatch, uint) bool) {
+		captures := qc.Captures(query, node, text)
+		for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+			if !yield(match, index) {
+				return
+			}
+		}
+	}
+}
+
 // Set the range of bytes in which the query will be executed.
 //
 // The query cursor will return matches that intersect with the given point range.
@@ -995,6 +1109,23 @@ func (qm *QueryMatch) satisfiesTextPredicate(query *Query, text []byte) bool {
 		}
 	}
 
+	if satisfies {
+		for _, predicate := range query.customPredicates[qm.PatternIndex] {
+			if !predicate.evaluate(qm, predicate.args, text) {
+				satisfies = false
+				break
+			}
+		}
+	}
+
+	if satisfies && len(query.directives[qm.PatternIndex]) > 0 {
+		// Each directive sees the metadata set by the ones before it.
+		qm.Metadata = &QueryMatchMetadata{}
+		for _, directive := range query.directives[qm.PatternIndex] {
+			directive.apply(qm, directive.args, text, qm.Metadata)
+		}
+	}
+
 	return satisfies
 }
 
@@ -1013,6 +1144,9 @@ func NewQueryProperty(key string, value *string, captureId *uint) QueryProperty
 //
 // If there are no more matches, it will return nil.
 func (qm *QueryMatches) Next() *QueryMatch {
+	// The cursor's memory is released once the cursor is unreachable, unless
+	// it was closed.
+	defer runtime.KeepAlive(qm.cursor)
 	for {
 		m := (*C.TSQueryMatch)(C.malloc(C.sizeof_TSQueryMatch))
 		defer C.free(unsafe.Pointer(m))
@@ -1037,21 +1171,24 @@ func (qm *QueryMatches) Next() *QueryMatch {
 //
 // If there are no more matches, it will return nil.
 func (qc *QueryCaptures) Next() (*QueryMatch, uint) {
+	defer runtime.KeepAlive(qc.cursor)
 	for {
 		m := (*C.TSQueryMatch)(C.malloc(C.sizeof_TSQueryMatch))
 		var captureIndex C.uint32_t
-		if C.ts_query_cursor_next_capture(qc._inner, m, &captureIndex) {
-			result := newQueryMatch(m, qc._inner)
-			if result.satisfiesTextPredicate(
-				qc.query,
-				qc.text,
-			) {
-				return &result, uint(captureIndex)
-			}
-			result.Remove()
-		} else {
+		if !C.ts_query_cursor_next_capture(qc._inner, m, &captureIndex) {
+			C.free(unsafe.Pointer(m))
 			return nil, 0
 		}
+		// The match's captures point into the cursor's memory, not into `m`.
+		result := newQueryMatch(m, qc._inner)
+		C.free(unsafe.Pointer(m))
+		if result.satisfiesTextPredicate(
+			qc.query,
+			qc.text,
+		) {
+			return &result, uint(captureIndex)
+		}
+		result.Remove()
 	}
 }
 
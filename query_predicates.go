@@ -0,0 +1,505 @@
+package tree_sitter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A predicate that can be used in queries in addition to the built-in ones,
+// such as `#has-ancestor?`.
+//
+// A pattern only matches if all of its custom predicates are satisfied.
+type CustomPredicate struct {
+	// An optional function that checks the predicate's arguments when a
+	// query that uses it is created. If it returns an error, [NewQuery]
+	// fails with a [QueryErrorPredicate] error.
+	Validate func(args []QueryPredicateArg) error
+
+	// Report whether a match satisfies the predicate. The `text` is the
+	// source code that was passed to [QueryCursor.Matches] or
+	// [QueryCursor.Captures].
+	Evaluate func(match *QueryMatch, args []QueryPredicateArg, text []byte) bool
+}
+
+// A directive that can be used in queries in addition to `#set!`, such as
+// `#offset!`.
+//
+// Directives don't affect whether a pattern matches. Instead they are run in
+// order for each match of their pattern, and can record information about
+// the match in its [QueryMatch.Metadata].
+type CustomDirective struct {
+	// An optional function that checks the directive's arguments when a
+	// query that uses it is created. If it returns an error, [NewQuery]
+	// fails with a [QueryErrorPredicate] error.
+	Validate func(args []QueryPredicateArg) error
+
+	// Apply the directive to a match. The `text` is the source code that
+	// was passed to [QueryCursor.Matches] or [QueryCursor.Captures].
+	Apply func(match *QueryMatch, args []QueryPredicateArg, text []byte, metadata *QueryMatchMetadata)
+
+	// Used by built-in directives instead of Validate and Apply, to prepare
+	// each use of the directive once, when the query is created.
+	bind func(args []QueryPredicateArg) (applyFunc, error)
+}
+
+type applyFunc = func(*QueryMatch, []QueryPredicateArg, []byte, *QueryMatchMetadata)
+
+// Information about a [QueryMatch] that was recorded by directives.
+type QueryMatchMetadata struct {
+	// Values that apply to the match as a whole.
+	Values map[string]string
+
+	// Information about individual captures, by capture index.
+	Captures map[uint]*CaptureMetadata
+}
+
+// Information about a capture that was recorded by directives.
+type CaptureMetadata struct {
+	// The range that should be used for the capture instead of its node's
+	// range, as set by `#offset!` and `#trim!`.
+	Range *Range
+
+	// The text that should be used for the capture instead of its node's
+	// text, as set by `#gsub!`.
+	Text *string
+
+	// Other values that apply to the capture.
+	Values map[string]string
+}
+
+type customPredicateCall struct {
+	evaluate func(*QueryMatch, []QueryPredicateArg, []byte) bool
+	args     []QueryPredicateArg
+}
+
+type directiveCall struct {
+	apply applyFunc
+	args  []QueryPredicateArg
+}
+
+var builtinPredicateNames = []string{
+	"eq?", "not-eq?", "any-eq?", "any-not-eq?",
+	"match?", "not-match?", "any-match?", "any-not-match?",
+	"any-of?", "not-any-of?",
+	"is?", "is-not?",
+	"set!",
+}
+
+// A set of custom predicates and directives that queries can use.
+//
+// A registry is safe for concurrent use. The operators of a query are looked
+// up once, when the query is created, so registering a predicate or directive
+// doesn't affect the queries that were created before.
+type PredicateRegistry struct {
+	mu         sync.RWMutex
+	predicates map[string]CustomPredicate
+	directives map[string]CustomDirective
+}
+
+// The registry that is used by [NewQuery], [RegisterPredicate] and
+// [RegisterDirective].
+var defaultPredicateRegistry = NewPredicateRegistry()
+
+// Create a new registry that contains the predicates and directives that are
+// registered by default, as listed in [RegisterPredicate] and
+// [RegisterDirective].
+//
+// Use a separate registry with [PredicateRegistry.NewQuery] to keep the
+// predicates of one part of a program from affecting the queries of another.
+func NewPredicateRegistry() *PredicateRegistry {
+	r := &PredicateRegistry{
+		predicates: map[string]CustomPredicate{},
+		directives: map[string]CustomDirective{},
+	}
+	r.RegisterPredicate("has-ancestor?", hasAncestorPredicate(true, true))
+	r.RegisterPredicate("not-has-ancestor?", hasAncestorPredicate(true, false))
+	r.RegisterPredicate("has-parent?", hasAncestorPredicate(false, true))
+	r.RegisterPredicate("not-has-parent?", hasAncestorPredicate(false, false))
+	r.RegisterPredicate("contains?", containsPredicate(true))
+	r.RegisterPredicate("not-contains?", containsPredicate(false))
+	r.RegisterDirective("offset!", offsetDirective)
+	r.RegisterDirective("trim!", trimDirective)
+	r.RegisterDirective("gsub!", gsubDirective)
+	return r
+}
+
+// Register a custom predicate that can be used in queries that are created
+// afterwards with [NewQuery].
+//
+// The name must end with `?`, e.g. `lua-match?`, and must not be one of the
+// built-in predicates. Registering a name again replaces the previous
+// predicate. The following predicates are registered by default:
+//   - `#has-ancestor? @capture type...` and `#not-has-ancestor?`, which check
+//     whether one of the capture's ancestors has one of the given types.
+//   - `#has-parent? @capture type...` and `#not-has-parent?`, which check
+//     whether the capture's parent has one of the given types.
+//   - `#contains? @capture string...` and `#not-contains?`, which check
+//     whether the capture's text contains one of the given strings.
+//
+// The predicate is available to every query in the program. Use a
+// [PredicateRegistry] to limit it to some queries instead.
+func RegisterPredicate(name string, predicate CustomPredicate) {
+	defaultPredicateRegistry.RegisterPredicate(name, predicate)
+}
+
+// Register a custom directive that can be used in queries that are created
+// afterwards with [NewQuery].
+//
+// The name must end with `!`, e.g. `inject!`, and must not be `set!`.
+// Registering a name again replaces the previous directive. The following
+// directives are registered by default:
+//   - `#offset! @capture startRow startColumn endRow endColumn`, which moves
+//     the start and end of the capture's range by the given number of rows
+//     and columns.
+//   - `#trim! @capture`, which removes leading and trailing whitespace from
+//     the capture's range.
+//   - `#gsub! @capture regex replacement`, which replaces all matches of a Go
+//     regular expression in the capture's text. The replacement can refer to
+//     submatches with `$1`, as in [regexp.Regexp.ReplaceAllString].
+//
+// The directive is available to every query in the program. Use a
+// [PredicateRegistry] to limit it to some queries instead.
+func RegisterDirective(name string, directive CustomDirective) {
+	defaultPredicateRegistry.RegisterDirective(name, directive)
+}
+
+// Register a custom predicate that can be used in queries that are created
+// afterwards with [PredicateRegistry.NewQuery].
+//
+// See [RegisterPredicate] for the rules about the name.
+func (r *PredicateRegistry) RegisterPredicate(name string, predicate CustomPredicate) {
+	if !strings.HasSuffix(name, "?") {
+		panic(fmt.Sprintf("tree_sitter: predicate name %q must end with '?'", name))
+	}
+	if predicate.Evaluate == nil {
+		panic(fmt.Sprintf("tree_sitter: predicate %q has no Evaluate function", name))
+	}
+	checkBuiltinPredicateName(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.predicates[name] = predicate
+}
+
+// Register a custom directive that can be used in queries that are created
+// afterwards with [PredicateRegistry.NewQuery].
+//
+// See [RegisterDirective] for the rules about the name.
+func (r *PredicateRegistry) RegisterDirective(name string, directive CustomDirective) {
+	if !strings.HasSuffix(name, "!") {
+		panic(fmt.Sprintf("tree_sitter: directive name %q must end with '!'", name))
+	}
+	if directive.Apply == nil && directive.bind == nil {
+		panic(fmt.Sprintf("tree_sitter: directive %q has no Apply function", name))
+	}
+	checkBuiltinPredicateName(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.directives[name] = directive
+}
+
+// Create a new query like [NewQuery], using the custom predicates and
+// directives of this registry instead of the ones registered with
+// [RegisterPredicate] and [RegisterDirective].
+func (r *PredicateRegistry) NewQuery(language *Language, source string) (*Query, *QueryError) {
+	return newQuery(language, source, r)
+}
+
+// Get the metadata for the capture with the given index, creating it if it
+// doesn't exist yet.
+func (m *QueryMatchMetadata) Capture(captureIndex uint) *CaptureMetadata {
+	if m.Captures == nil {
+		m.Captures = make(map[uint]*CaptureMetadata)
+	}
+	capture, ok := m.Captures[captureIndex]
+	if !ok {
+		capture = &CaptureMetadata{}
+		m.Captures[captureIndex] = capture
+	}
+	return capture
+}
+
+// Get the range of the first node of a capture, taking into account any
+// range that was set by a directive.
+func (qm *QueryMatch) CaptureRange(captureIndex uint) (Range, bool) {
+	if qm.Metadata != nil {
+		if capture, ok := qm.Metadata.Captures[captureIndex]; ok && capture.Range != nil {
+			return *capture.Range, true
+		}
+	}
+	nodes := qm.NodesForCaptureIndex(captureIndex)
+	if len(nodes) == 0 {
+		return Range{}, false
+	}
+	return nodes[0].Range(), true
+}
+
+// Get the text of the first node of a capture, taking into account any text
+// or range that was set by a directive.
+func (qm *QueryMatch) CaptureText(captureIndex uint, text []byte) (string, bool) {
+	if qm.Metadata != nil {
+		if capture, ok := qm.Metadata.Captures[captureIndex]; ok && capture.Text != nil {
+			return *capture.Text, true
+		}
+	}
+	r, ok := qm.CaptureRange(captureIndex)
+	if !ok {
+		return "", false
+	}
+	captureText, ok := rangeText(text, r.StartByte, r.EndByte)
+	if !ok {
+		return "", false
+	}
+	return string(captureText), true
+}
+
+// Get the text in a range, or false if the text is too short to contain it,
+// e.g. because no text was passed to the query cursor.
+func rangeText(text []byte, startByte, endByte uint) ([]byte, bool) {
+	if startByte > endByte || endByte > uint(len(text)) {
+		return nil, false
+	}
+	return text[startByte:endByte], true
+}
+
+func checkBuiltinPredicateName(name string) {
+	for _, builtin := range builtinPredicateNames {
+		if name == builtin {
+			panic(fmt.Sprintf("tree_sitter: cannot replace the built-in predicate %q", name))
+		}
+	}
+}
+
+// Validate the arguments of a use of the directive, and get the function
+// that applies it.
+func (d *CustomDirective) prepare(args []QueryPredicateArg) (applyFunc, error) {
+	if d.bind != nil {
+		return d.bind(args)
+	}
+	if d.Validate != nil {
+		if err := d.Validate(args); err != nil {
+			return nil, err
+		}
+	}
+	return d.Apply, nil
+}
+
+func (r *PredicateRegistry) lookup(name string) (*CustomPredicate, *CustomDirective) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if predicate, ok := r.predicates[name]; ok {
+		return &predicate, nil
+	}
+	if directive, ok := r.directives[name]; ok {
+		return nil, &directive
+	}
+	return nil, nil
+}
+
+// Check that the arguments are a capture followed by at least `minStrings`
+// strings.
+func validateCaptureAndStrings(minStrings int) func([]QueryPredicateArg) error {
+	return func(args []QueryPredicateArg) error {
+		if len(args) == 0 || args[0].CaptureId == nil {
+			return fmt.Errorf("first argument must be a capture")
+		}
+		if len(args)-1 < minStrings {
+			return fmt.Errorf("expected at least %d arguments after the capture, got %d", minStrings, len(args)-1)
+		}
+		for _, arg := range args[1:] {
+			if arg.String == nil {
+				return fmt.Errorf("arguments after the capture must be strings")
+			}
+		}
+		return nil
+	}
+}
+
+func hasAncestorPredicate(anyAncestor, positive bool) CustomPredicate {
+	return CustomPredicate{
+		Validate: validateCaptureAndStrings(1),
+		Evaluate: func(match *QueryMatch, args []QueryPredicateArg, _ []byte) bool {
+			for _, node := range match.NodesForCaptureIndex(*args[0].CaptureId) {
+				found := false
+				for parent := node.Parent(); parent != nil && !found; parent = parent.Parent() {
+					kind := parent.Kind()
+					for _, arg := range args[1:] {
+						if kind == *arg.String {
+							found = true
+							break
+						}
+					}
+					if !anyAncestor {
+						break
+					}
+				}
+				if found != positive {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+func containsPredicate(positive bool) CustomPredicate {
+	return CustomPredicate{
+		Validate: validateCaptureAndStrings(1),
+		Evaluate: func(match *QueryMatch, args []QueryPredicateArg, text []byte) bool {
+			for _, node := range match.NodesForCaptureIndex(*args[0].CaptureId) {
+				nodeText, ok := rangeText(text, node.StartByte(), node.EndByte())
+				if !ok {
+					return false
+				}
+				found := false
+				for _, arg := range args[1:] {
+					if bytes.Contains(nodeText, []byte(*arg.String)) {
+						found = true
+						break
+					}
+				}
+				if found != positive {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+var offsetDirective = CustomDirective{
+	Validate: func(args []QueryPredicateArg) error {
+		if err := validateCaptureAndStrings(4)(args); err != nil {
+			return err
+		}
+		if len(args) != 5 {
+			return fmt.Errorf("expected 4 offsets, got %d", len(args)-1)
+		}
+		for _, arg := range args[1:] {
+			if _, err := strconv.Atoi(*arg.String); err != nil {
+				return fmt.Errorf("offset %q is not an integer", *arg.String)
+			}
+		}
+		return nil
+	},
+	Apply: func(match *QueryMatch, args []QueryPredicateArg, text []byte, metadata *QueryMatchMetadata) {
+		captureIndex := *args[0].CaptureId
+		r, ok := match.CaptureRange(captureIndex)
+		if !ok {
+			return
+		}
+		var offsets [4]int
+		for i, arg := range args[1:] {
+			offsets[i], _ = strconv.Atoi(*arg.String)
+		}
+
+		start := Point{
+			Row:    uint(max(0, int(r.StartPoint.Row)+offsets[0])),
+			Column: uint(max(0, int(r.StartPoint.Column)+offsets[1])),
+		}
+		end := Point{
+			Row:    uint(max(0, int(r.EndPoint.Row)+offsets[2])),
+			Column: uint(max(0, int(r.EndPoint.Column)+offsets[3])),
+		}
+		if end.Row < start.Row || (end.Row == start.Row && end.Column < start.Column) {
+			end = start
+		}
+		metadata.Capture(captureIndex).Range = &Range{
+			StartByte:  byteForPoint(text, start, r.StartByte, r.StartPoint),
+			EndByte:    byteForPoint(text, end, r.EndByte, r.EndPoint),
+			StartPoint: start,
+			EndPoint:   end,
+		}
+	},
+}
+
+var trimDirective = CustomDirective{
+	Validate: func(args []QueryPredicateArg) error {
+		if len(args) != 1 || args[0].CaptureId == nil {
+			return fmt.Errorf("expected a single capture")
+		}
+		return nil
+	},
+	Apply: func(match *QueryMatch, args []QueryPredicateArg, text []byte, metadata *QueryMatchMetadata) {
+		captureIndex := *args[0].CaptureId
+		r, ok := match.CaptureRange(captureIndex)
+		if !ok {
+			return
+		}
+		content, ok := rangeText(text, r.StartByte, r.EndByte)
+		if !ok {
+			return
+		}
+		leading := uint(len(content) - len(bytes.TrimLeft(content, " \t\r\n\f\v")))
+		trimmed := bytes.TrimSpace(content)
+		if len(trimmed) == 0 {
+			leading = 0
+		}
+
+		startByte := r.StartByte + leading
+		endByte := startByte + uint(len(trimmed))
+		startPoint := r.StartPoint.Advance(text[r.StartByte:startByte])
+		metadata.Capture(captureIndex).Range = &Range{
+			StartByte:  startByte,
+			EndByte:    endByte,
+			StartPoint: startPoint,
+			EndPoint:   startPoint.Advance(text[startByte:endByte]),
+		}
+	},
+}
+
+// The regex of each use of `#gsub!` is compiled once, when its query is
+// created.
+var gsubDirective = CustomDirective{
+	bind: func(args []QueryPredicateArg) (applyFunc, error) {
+		if err := validateCaptureAndStrings(2)(args); err != nil {
+			return nil, err
+		}
+		if len(args) != 3 {
+			return nil, fmt.Errorf("expected a regex and a replacement, got %d arguments", len(args)-1)
+		}
+		regex, err := regexp.Compile(*args[1].String)
+		if err != nil {
+			return nil, err
+		}
+		return func(match *QueryMatch, args []QueryPredicateArg, text []byte, metadata *QueryMatchMetadata) {
+			captureIndex := *args[0].CaptureId
+			captureText, ok := match.CaptureText(captureIndex, text)
+			if !ok {
+				return
+			}
+			replaced := regex.ReplaceAllString(captureText, *args[2].String)
+			metadata.Capture(captureIndex).Text = &replaced
+		}, nil
+	},
+}
+
+// Get the byte offset of a point in the text, clamping the column to the end
+// of its row.
+//
+// The search starts at a point whose byte offset is already known, such as
+// the start of a node, so that only the rows in between are scanned.
+func byteForPoint(text []byte, point Point, knownByte uint, knownPoint Point) uint {
+	knownByte = min(knownByte, uint(len(text)))
+	lineStart := knownByte - min(knownPoint.Column, knownByte)
+	for row := knownPoint.Row; row < point.Row; row++ {
+		i := bytes.IndexByte(text[lineStart:], '\n')
+		if i < 0 {
+			return uint(len(text))
+		}
+		lineStart += uint(i) + 1
+	}
+	for row := knownPoint.Row; row > point.Row && lineStart > 0; row-- {
+		lineStart = uint(bytes.LastIndexByte(text[:lineStart-1], '\n') + 1)
+	}
+	lineEnd := uint(len(text))
+	if i := bytes.IndexByte(text[lineStart:], '\n'); i >= 0 {
+		lineEnd = lineStart + uint(i)
+	}
+	return min(lineStart+point.Column, lineEnd)
+}
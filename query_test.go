@@ -5198,6 +5198,298 @@ const foo = [
 	assert.Equal(t, []formattedCapture{{"foo", "3"}}, matches[0].Captures)
 }
 
+func TestQueryCursorSeqs(t *testing.T) {
+	language := getLanguage("javascript")
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := "a(b); c(d);"
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	query, err := NewQuery(language, `(call_expression function: (identifier) @fn arguments: (arguments (identifier) @arg))`)
+	assert.Nil(t, err)
+	defer query.Close()
+
+	cursor := NewQueryCursor()
+	defer cursor.Close()
+
+	var matches []formattedMatch
+	for match := range cursor.MatchesSeq(query, tree.RootNode(), []byte(source)) {
+		matches = append(matches, fmtMatch(match.PatternIndex, formatCaptures(match.Captures, query, source)...))
+	}
+	assert.Equal(
+		t,
+		[]formattedMatch{
+			fmtMatch(0, fmtCapture("fn", "a"), fmtCapture("arg", "b")),
+			fmtMatch(0, fmtCapture("fn", "c"), fmtCapture("arg", "d")),
+		},
+		matches,
+	)
+
+	var captures []QueryCapture
+	for match, index := range cursor.CapturesSeq(query, tree.RootNode(), []byte(source)) {
+		captures = append(captures, match.Captures[index])
+		if len(captures) == 3 {
+			break
+		}
+	}
+	assert.Equal(
+		t,
+		[]formattedCapture{{"fn", "a"}, {"arg", "b"}, {"fn", "c"}},
+		formatCaptures(captures, query, source),
+	)
+}
+
+func TestQueryMatchesWithCustomPredicates(t *testing.T) {
+	RegisterPredicate("is-upper?", CustomPredicate{
+		Evaluate: func(match *QueryMatch, args []QueryPredicateArg, text []byte) bool {
+			for _, node := range match.NodesForCaptureIndex(*args[0].CaptureId) {
+				nodeText := node.Utf8Text(text)
+				if strings.ToUpper(nodeText) != nodeText {
+					return false
+				}
+			}
+			return true
+		},
+	})
+
+	language := getLanguage("javascript")
+	query, err := NewQuery(
+		language,
+		`
+		((identifier) @constant (#is-upper? @constant))
+		((identifier) @in-call (#has-ancestor? @in-call call_expression) (#not-has-parent? @in-call call_expression))
+		((string) @greeting (#contains? @greeting "hello" "hi"))
+		`,
+	)
+	assert.Nil(t, err)
+	defer query.Close()
+
+	assert.Empty(t, query.GeneralPredicates(0))
+
+	assertQueryMatches(
+		t,
+		language,
+		query,
+		"MAX = f(x, 'hello', 'bye', 'hi');",
+		[]formattedMatch{
+			fmtMatch(0, fmtCapture("constant", "MAX")),
+			fmtMatch(1, fmtCapture("in-call", "x")),
+			fmtMatch(2, fmtCapture("greeting", "'hello'")),
+			fmtMatch(2, fmtCapture("greeting", "'hi'")),
+		},
+	)
+}
+
+func TestQueryMatchesWithCustomDirectives(t *testing.T) {
+	RegisterDirective("language!", CustomDirective{
+		Validate: func(args []QueryPredicateArg) error {
+			if len(args) != 1 || args[0].String == nil {
+				return fmt.Errorf("expected a language name")
+			}
+			return nil
+		},
+		Apply: func(match *QueryMatch, args []QueryPredicateArg, text []byte, metadata *QueryMatchMetadata) {
+			metadata.Values = map[string]string{"language": *args[0].String}
+		},
+	})
+
+	language := getLanguage("javascript")
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := "x = `\n  SELECT 1\n`;\ny = 'a-b-c';"
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	query, err := NewQuery(
+		language,
+		`
+		((template_string) @sql (#offset! @sql 0 1 0 -1) (#trim! @sql) (#language! "sql"))
+		((string) @str (#gsub! @str "-([a-z])" "_$1"))
+		(identifier) @id
+		`,
+	)
+	assert.Nil(t, err)
+	defer query.Close()
+
+	cursor := NewQueryCursor()
+	defer cursor.Close()
+
+	matches := cursor.Matches(query, tree.RootNode(), []byte(source))
+
+	match := matches.Next()
+	assert.Equal(t, uint(2), match.PatternIndex)
+	assert.Nil(t, match.Metadata)
+
+	match = matches.Next()
+	assert.Equal(t, uint(0), match.PatternIndex)
+	assert.Equal(t, map[string]string{"language": "sql"}, match.Metadata.Values)
+	sqlRange, ok := match.CaptureRange(0)
+	assert.True(t, ok)
+	assert.Equal(
+		t,
+		Range{StartByte: 8, EndByte: 16, StartPoint: NewPoint(1, 2), EndPoint: NewPoint(1, 10)},
+		sqlRange,
+	)
+	sqlText, _ := match.CaptureText(0, []byte(source))
+	assert.Equal(t, "SELECT 1", sqlText)
+
+	match = matches.Next()
+	assert.Equal(t, uint(2), match.PatternIndex)
+
+	match = matches.Next()
+	assert.Equal(t, uint(1), match.PatternIndex)
+	strText, _ := match.CaptureText(1, []byte(source))
+	assert.Equal(t, "'a_b_c'", strText)
+
+	assert.Nil(t, matches.Next())
+}
+
+func TestQueryErrorsOnInvalidCustomPredicates(t *testing.T) {
+	language := getLanguage("javascript")
+
+	_, err := NewQuery(language, `((identifier) @a (#has-ancestor? @a))`)
+	assert.Equal(t, QueryErrorPredicate, err.Kind)
+	_, err = NewQuery(language, `((identifier) @a (#offset! @a 0 x 0 0))`)
+	assert.Equal(t, QueryErrorPredicate, err.Kind)
+	_, err = NewQuery(language, `((identifier) @a (#gsub! @a "(" ""))`)
+	assert.Equal(t, QueryErrorPredicate, err.Kind)
+
+	assert.Panics(t, func() { RegisterPredicate("eq?", CustomPredicate{Evaluate: nil}) })
+	assert.Panics(t, func() {
+		RegisterPredicate("not-a-predicate", CustomPredicate{
+			Evaluate: func(*QueryMatch, []QueryPredicateArg, []byte) bool { return true },
+		})
+	})
+	assert.Panics(t, func() {
+		RegisterDirective("set!", CustomDirective{
+			Apply: func(*QueryMatch, []QueryPredicateArg, []byte, *QueryMatchMetadata) {},
+		})
+	})
+}
+
+func TestQueryWithPredicateRegistry(t *testing.T) {
+	registry := NewPredicateRegistry()
+	registry.RegisterPredicate("is-short?", CustomPredicate{
+		Evaluate: func(match *QueryMatch, args []QueryPredicateArg, text []byte) bool {
+			for _, node := range match.NodesForCaptureIndex(*args[0].CaptureId) {
+				if node.EndByte()-node.StartByte() > 1 {
+					return false
+				}
+			}
+			return true
+		},
+	})
+
+	language := getLanguage("javascript")
+	source := `((identifier) @id (#is-short? @id) (#not-has-parent? @id call_expression))`
+	query, err := registry.NewQuery(language, source)
+	assert.Nil(t, err)
+	defer query.Close()
+	assert.Empty(t, query.GeneralPredicates(0))
+	assertQueryMatches(
+		t,
+		language,
+		query,
+		"abc = f(x, y);",
+		[]formattedMatch{
+			fmtMatch(0, fmtCapture("id", "x")),
+			fmtMatch(0, fmtCapture("id", "y")),
+		},
+	)
+
+	// The predicate isn't known to queries that use the default registry, so
+	// it is returned as a general predicate and doesn't filter the matches.
+	query, err = NewQuery(language, source)
+	assert.Nil(t, err)
+	defer query.Close()
+	captureId := uint(0)
+	assert.Equal(
+		t,
+		[]QueryPredicate{{Operator: "is-short?", Args: []QueryPredicateArg{{CaptureId: &captureId}}}},
+		query.GeneralPredicates(0),
+	)
+	assertQueryMatches(
+		t,
+		language,
+		query,
+		"abc = f(x, y);",
+		[]formattedMatch{
+			fmtMatch(0, fmtCapture("id", "abc")),
+			fmtMatch(0, fmtCapture("id", "x")),
+			fmtMatch(0, fmtCapture("id", "y")),
+		},
+	)
+}
+
+func TestQueryOffsetDirectiveAcrossRows(t *testing.T) {
+	language := getLanguage("javascript")
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := "a;\nb;\nx = `\n  one\n  two\n`;"
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	query, err := NewQuery(language, `((template_string) @t (#offset! @t 1 -2 -1 2))`)
+	assert.Nil(t, err)
+	defer query.Close()
+
+	cursor := NewQueryCursor()
+	defer cursor.Close()
+	matches := cursor.Matches(query, tree.RootNode(), []byte(source))
+	match := matches.Next()
+	r, ok := match.CaptureRange(0)
+	assert.True(t, ok)
+	// The start moves down to the row with `one`, and the end moves up to the
+	// row with `two`.
+	assert.Equal(t, Range{StartByte: 14, EndByte: 21, StartPoint: NewPoint(3, 2), EndPoint: NewPoint(4, 3)}, r)
+	text, _ := match.CaptureText(0, []byte(source))
+	assert.Equal(t, "one\n  t", text)
+}
+
+func TestQueryTextPredicatesWithoutText(t *testing.T) {
+	language := getLanguage("javascript")
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := "abc = ' x ';"
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	query, err := NewQuery(
+		language,
+		`
+		((identifier) @a (#contains? @a "b"))
+		((identifier) @b (#not-contains? @b "z"))
+		((string) @c (#trim! @c) (#gsub! @c "x" "y"))
+		`,
+	)
+	assert.Nil(t, err)
+	defer query.Close()
+
+	cursor := NewQueryCursor()
+	defer cursor.Close()
+
+	// Captures whose text is missing or cut short don't match the text
+	// predicates, and are left alone by the directives.
+	for _, text := range [][]byte{nil, []byte(source[:2])} {
+		matches := cursor.Matches(query, tree.RootNode(), text)
+		match := matches.Next()
+		assert.Equal(t, uint(2), match.PatternIndex)
+		_, ok := match.CaptureText(2, text)
+		assert.False(t, ok)
+		assert.Empty(t, match.Metadata.Captures)
+		assert.Nil(t, matches.Next())
+	}
+}
+
 type formattedCapture struct {
 	Name  string
 	Value string
@@ -0,0 +1,232 @@
+package tree_sitter
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"unicode/utf8"
+)
+
+// The size of the chunks that are read from an [io.ReaderAt] or an
+// [io.Reader] while parsing.
+const readerChunkSize = 64 * 1024
+
+// The number of chunks that are kept in memory while parsing from an
+// [io.ReaderAt] or an [io.Reader].
+const readerCacheChunks = 16
+
+// Returned by [Parser.ParseReader] when the parser needs text that was
+// already discarded from its buffer.
+var ErrReaderRewound = errors.New("The parser needed text that was already discarded from the reader's buffer")
+
+// Parse UTF8 text that is read from `r` on demand, such as an [os.File].
+//
+// The text is read in chunks, and only a bounded number of them is kept in
+// memory, so that large files can be parsed without loading them entirely.
+// Use [Node.Utf8TextAt] to get the text of the resulting nodes.
+//
+// # Arguments:
+//   - `r` The reader to read the text from.
+//   - `size` The size of the text in bytes.
+//   - `old_tree` A previous syntax tree parsed from the same document. If the text of the
+//     document has changed since `old_tree` was created, then you must edit `old_tree` to match
+//     the new text using [Tree.Edit].
+//   - `options` Options for parsing the text. This can be used to set a progress callback.
+//
+// If reading fails, the error is returned instead of a tree. If parsing is
+// cancelled by the progress callback, the returned tree is `nil`.
+func (p *Parser) ParseReaderAt(r io.ReaderAt, size int64, oldTree *Tree, options *ParseOptions) (*Tree, error) {
+	return p.parseReaderInput(&readerInput{readerAt: r, size: size}, oldTree, options)
+}
+
+// Parse UTF8 text that is read sequentially from `r`, such as a network
+// connection.
+//
+// Like [Parser.ParseReaderAt], this only keeps a bounded number of chunks of
+// the text in memory. The parser usually reads the text from start to end,
+// but it can go back a little after syntax errors; if it goes back further
+// than the buffered text, [ErrReaderRewound] is returned.
+//
+// Since the text can't be read again, the caller is responsible for keeping
+// it if the text of the resulting nodes is needed, for example with an
+// [io.TeeReader].
+//
+// See [Parser.ParseReaderAt] for the other arguments.
+func (p *Parser) ParseReader(r io.Reader, oldTree *Tree, options *ParseOptions) (*Tree, error) {
+	return p.parseReaderInput(&readerInput{reader: r}, oldTree, options)
+}
+
+func (p *Parser) parseReaderInput(input *readerInput, oldTree *Tree, options *ParseOptions) (*Tree, error) {
+	// The parser takes the nil text that is returned after an error for the
+	// end of the text, so stop parsing as soon as reading fails.
+	var progressCallback func(ParseState) bool
+	if options != nil {
+		progressCallback = options.ProgressCallback
+	}
+	options = &ParseOptions{
+		ProgressCallback: func(state ParseState) bool {
+			if input.err != nil {
+				return true
+			}
+			return progressCallback != nil && progressCallback(state)
+		},
+	}
+
+	tree := p.parseUTF8(input.read, oldTree, options, true)
+	if input.err != nil {
+		if tree != nil {
+			tree.Close()
+		} else {
+			p.Reset()
+		}
+		return nil, input.err
+	}
+	return tree, nil
+}
+
+// Get this node's text by reading it from `r`, such as the file that the
+// tree was parsed from with [Parser.ParseReaderAt].
+func (n *Node) Utf8TextAt(r io.ReaderAt) (string, error) {
+	text := make([]byte, n.EndByte()-n.StartByte())
+	if read, err := r.ReadAt(text, int64(n.StartByte())); read < len(text) {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// Reads chunks of text from an [io.ReaderAt] or an [io.Reader] for the
+// parser, keeping the most recently read chunks in memory.
+type readerInput struct {
+	readerAt io.ReaderAt
+	size     int64
+
+	reader io.Reader
+
+	// For an [io.Reader], the offset after the last chunk that was read, and
+	// whether the end of the text has been reached.
+	readEnd int64
+	eof     bool
+
+	// For an [io.ReaderAt], the chunks are ordered from the least to the most
+	// recently used, and for an [io.Reader], by their offsets.
+	chunks []readerChunk
+
+	// The first error returned by the reader.
+	err error
+}
+
+type readerChunk struct {
+	offset int64
+	data   []byte
+}
+
+func (in *readerInput) read(offset int, _ Point) []byte {
+	if in.err != nil {
+		return nil
+	}
+	text := in.chunk(int64(offset))
+
+	// The lexer can't decode a character that is split between two chunks, so
+	// end the chunk before it, or join it with the next chunk if it is at the
+	// start.
+	if n := incompleteRuneSuffix(text); n > 0 {
+		if n < len(text) {
+			return text[:len(text)-n]
+		}
+		text = slices.Clone(text)
+		return append(text, in.chunk(int64(offset+len(text)))...)
+	}
+	return text
+}
+
+func (in *readerInput) chunk(offset int64) []byte {
+	if in.readerAt != nil {
+		return in.chunkFromReaderAt(offset)
+	}
+	return in.chunkFromReader(offset)
+}
+
+func (in *readerInput) chunkFromReaderAt(offset int64) []byte {
+	if offset >= in.size {
+		return nil
+	}
+	start := offset - offset%readerChunkSize
+	for i, chunk := range in.chunks {
+		if chunk.offset == start {
+			copy(in.chunks[i:], in.chunks[i+1:])
+			in.chunks[len(in.chunks)-1] = chunk
+			return chunk.data[offset-start:]
+		}
+	}
+
+	buffer := in.newBuffer()[:min(readerChunkSize, in.size-start)]
+	n, err := in.readerAt.ReadAt(buffer, start)
+	if n < len(buffer) {
+		if err != io.EOF {
+			in.err = err
+			return nil
+		}
+		// The text is shorter than its given size.
+		in.size = start + int64(n)
+	}
+	in.chunks = append(in.chunks, readerChunk{offset: start, data: buffer[:n]})
+	if offset >= in.size {
+		return nil
+	}
+	return buffer[offset-start : n]
+}
+
+func (in *readerInput) chunkFromReader(offset int64) []byte {
+	for offset >= in.readEnd && !in.eof {
+		buffer := in.newBuffer()
+		n, err := io.ReadFull(in.reader, buffer)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			in.eof = true
+		} else if err != nil {
+			in.err = err
+			return nil
+		}
+		if n > 0 {
+			in.chunks = append(in.chunks, readerChunk{offset: in.readEnd, data: buffer[:n]})
+			in.readEnd += int64(n)
+		}
+	}
+	if offset >= in.readEnd {
+		return nil
+	}
+	if offset < in.chunks[0].offset {
+		in.err = ErrReaderRewound
+		return nil
+	}
+
+	// All chunks but the last one are full, so the chunk can be found by its
+	// offset.
+	chunk := in.chunks[(offset-in.chunks[0].offset)/readerChunkSize]
+	return chunk.data[offset-chunk.offset:]
+}
+
+// Get a buffer for a new chunk, reusing the buffer of the oldest chunk if
+// the cache is full.
+func (in *readerInput) newBuffer() []byte {
+	if len(in.chunks) < readerCacheChunks {
+		return make([]byte, readerChunkSize)
+	}
+	oldest := in.chunks[0]
+	copy(in.chunks, in.chunks[1:])
+	in.chunks = in.chunks[:len(in.chunks)-1]
+	return oldest.data[:cap(oldest.data)]
+}
+
+// Get the number of bytes at the end of the text that belong to an
+// incomplete UTF8 character.
+func incompleteRuneSuffix(text []byte) int {
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(text[i]) {
+			if utf8.FullRune(text[i:]) {
+				return 0
+			}
+			return len(text) - i
+		}
+	}
+	return 0
+}
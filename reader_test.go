@@ -0,0 +1,149 @@
+package tree_sitter_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Generate a JSON document that is much larger than the chunks that are read
+// from readers, with multi-byte characters that are split between chunks.
+func largeJSONDocument() []byte {
+	var source bytes.Buffer
+	source.WriteString("[\n")
+	for i := 0; i < 20000; i++ {
+		if i > 0 {
+			source.WriteString(",\n")
+		}
+		fmt.Fprintf(&source, `{"id": %d, "name": "é%sü", "tags": [true, null]}`, i, strings.Repeat("ö", i%7))
+	}
+	source.WriteString("\n]\n")
+	return source.Bytes()
+}
+
+// Parse the text from memory in chunks, to compare with the trees that are
+// parsed from readers.
+func parseInChunks(parser *Parser, source []byte) *Tree {
+	return parser.ParseWithOptions(func(i int, _ Point) []byte {
+		return source[i:min(i+4096, len(source))]
+	}, nil, nil)
+}
+
+func TestParserParseReaderAt(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	source := largeJSONDocument()
+	expected := parseInChunks(parser, source)
+	defer expected.Close()
+
+	reader := bytes.NewReader(source)
+	tree, err := parser.ParseReaderAt(reader, int64(len(source)), nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.False(t, tree.RootNode().HasError())
+	assert.Equal(t, expected.RootNode().DescendantCount(), tree.RootNode().DescendantCount())
+	assert.Empty(t, expected.ChangedRanges(tree))
+
+	name := tree.RootNode().NamedChild(0).NamedChild(19998).NamedChild(1).ChildByFieldName("value")
+	text, err := name.Utf8TextAt(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, `"éööööööü"`, text)
+
+	// Reparsing after an edit only reads the text around the edit.
+	edit := &InputEdit{
+		StartByte:      3,
+		OldEndByte:     3,
+		NewEndByte:     3,
+		StartPosition:  Point{1, 1},
+		OldEndPosition: Point{1, 1},
+		NewEndPosition: Point{1, 1},
+	}
+	tree.Edit(edit)
+	newTree, err := parser.ParseReaderAt(reader, int64(len(source)), tree, nil)
+	assert.Nil(t, err)
+	defer newTree.Close()
+	assert.Empty(t, tree.ChangedRanges(newTree))
+}
+
+func TestParserParseReader(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	source := largeJSONDocument()
+	expected := parseInChunks(parser, source)
+	defer expected.Close()
+
+	tree, err := parser.ParseReader(iotest.HalfReader(bytes.NewReader(source)), nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, expected.RootNode().DescendantCount(), tree.RootNode().DescendantCount())
+	assert.Empty(t, expected.ChangedRanges(tree))
+}
+
+func TestParserParseReaderErrors(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	readErr := errors.New("connection reset")
+	tree, err := parser.ParseReader(iotest.DataErrReader(iotest.ErrReader(readErr)), nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, readErr, err)
+
+	source := largeJSONDocument()
+	tree, err = parser.ParseReader(iotest.TimeoutReader(bytes.NewReader(source)), nil, nil)
+	assert.Nil(t, tree)
+	assert.Equal(t, iotest.ErrTimeout, err)
+
+	// Parsing stops as soon as reading fails, instead of going on as if the
+	// text ended there.
+	reader := &errorRecordingReader{r: iotest.TimeoutReader(bytes.NewReader(source))}
+	callsAfterError := 0
+	tree, err = parser.ParseReader(reader, nil, &ParseOptions{
+		ProgressCallback: func(ParseState) bool {
+			if reader.err != nil {
+				callsAfterError++
+			}
+			return false
+		},
+	})
+	assert.Nil(t, tree)
+	assert.Equal(t, iotest.ErrTimeout, err)
+	assert.Zero(t, callsAfterError)
+
+	// A reader that is shorter than the given size ends the text early.
+	tree, err = parser.ParseReaderAt(strings.NewReader("[1, 2]"), 100, nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number) (number)))", tree.RootNode().ToSexp())
+
+	// The parser can be used again after an error.
+	tree, err = parser.ParseReader(strings.NewReader("[3]"), nil, nil)
+	assert.Nil(t, err)
+	defer tree.Close()
+	assert.Equal(t, "(document (array (number)))", tree.RootNode().ToSexp())
+}
+
+// Records the first error returned by a reader.
+type errorRecordingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errorRecordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,164 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The number of unchanged lines that are shown around each change in a
+// diff.
+const diffContextLines = 3
+
+// Format the changes as a unified diff of the original and the rewritten
+// source code, without modifying any files. The diff is empty if nothing
+// was changed.
+//
+// `name` is used as the file name in the diff's header.
+func (r *Result) Diff(name string) string {
+	if !r.Changed() {
+		return ""
+	}
+
+	oldLines := splitLines(r.original)
+	newLines := splitLines(r.Source)
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "--- a/%s\n+++ b/%s\n", name, name)
+
+	// Group the edits whose changed lines are close enough that their context
+	// lines would touch, and write a hunk for each group. The lines of the
+	// new source code are found by following how many lines each edit adds
+	// or removes.
+	lineDelta := 0
+	for i := 0; i < len(r.Edits); {
+		first := i
+		last := i
+		groupDelta := editLineDelta(r.Edits[i])
+		for last+1 < len(r.Edits) &&
+			int(r.Edits[last+1].StartPoint.Row) <= int(r.Edits[last].EndPoint.Row)+2*diffContextLines+1 {
+			last++
+			groupDelta += editLineDelta(r.Edits[last])
+		}
+		i = last + 1
+
+		oldStart := int(r.Edits[first].StartPoint.Row)
+		oldEnd := int(r.Edits[last].EndPoint.Row) + 1
+		newStart := oldStart + lineDelta
+		newEnd := oldEnd + lineDelta + groupDelta
+		lineDelta += groupDelta
+
+		writeHunk(&output, oldLines, newLines, oldStart, oldEnd, newStart, newEnd)
+	}
+	return output.String()
+}
+
+// The number of lines that an edit adds, or removes if it is negative.
+func editLineDelta(edit Edit) int {
+	return strings.Count(edit.Replacement, "\n") - int(edit.EndPoint.Row-edit.StartPoint.Row)
+}
+
+// Write a hunk for the changed lines `oldLines[oldStart:oldEnd]` and
+// `newLines[newStart:newEnd]`, with their surrounding context.
+func writeHunk(output *strings.Builder, oldLines, newLines []string, oldStart, oldEnd, newStart, newEnd int) {
+	oldEnd = min(oldEnd, len(oldLines))
+	newEnd = min(newEnd, len(newLines))
+	oldStart = min(oldStart, oldEnd)
+	newStart = min(newStart, newEnd)
+	before := min(diffContextLines, oldStart)
+	after := min(diffContextLines, len(oldLines)-oldEnd)
+
+	var lines []string
+	for _, line := range oldLines[oldStart-before : oldStart] {
+		lines = append(lines, " "+line)
+	}
+	lines = append(lines, diffLines(oldLines[oldStart:oldEnd], newLines[newStart:newEnd])...)
+	for _, line := range oldLines[oldEnd : oldEnd+after] {
+		lines = append(lines, " "+line)
+	}
+
+	oldCount, newCount := 0, 0
+	for _, line := range lines {
+		switch line[0] {
+		case ' ':
+			oldCount++
+			newCount++
+		case '-':
+			oldCount++
+		case '+':
+			newCount++
+		}
+	}
+	fmt.Fprintf(
+		output,
+		"@@ -%s +%s @@\n",
+		formatHunkRange(oldStart-before, oldCount),
+		formatHunkRange(newStart-before, newCount),
+	)
+	for _, line := range lines {
+		output.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			output.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func formatHunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Diff two short sequences of lines, keeping their longest common
+// subsequence as context.
+func diffLines(a, b []string) []string {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var result []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result = append(result, " "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lengths[i+1][j] >= lengths[i][j+1]):
+			result = append(result, "-"+a[i])
+			i++
+		default:
+			result = append(result, "+"+b[j])
+			j++
+		}
+	}
+	return result
+}
+
+// Split text into lines that keep their trailing newlines.
+func splitLines(text []byte) []string {
+	var lines []string
+	for len(text) > 0 {
+		end := bytes.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		lines = append(lines, string(text[:end]))
+		text = text[end:]
+	}
+	return lines
+}
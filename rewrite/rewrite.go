@@ -0,0 +1,264 @@
+// Package rewrite performs structural search and replace with Tree-sitter
+// queries.
+//
+// A [Rule] combines a query with a template that refers to the query's
+// captures. Rewriting a tree with a set of rules produces a list of
+// non-overlapping text edits, the rewritten source code, and the
+// [tree_sitter.InputEdit]s that update the tree to match it, so that it can
+// be reparsed incrementally.
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// The name of the capture that marks the node to replace.
+const TargetCaptureName = "rewrite"
+
+// A rule that replaces the nodes matched by a query with the text of a
+// template.
+//
+// In the template, `@name` is replaced by the text of the node captured as
+// `@name`, and `@@` is replaced by a single `@`. Since capture names can
+// contain `.` and `-`, the longest capture name that follows the `@` is used,
+// so with a capture named `@x`, `@x.Method()` becomes the text of `@x`
+// followed by `.Method()`. When a capture has several
+// nodes, for example because it is quantified with `*`, it is replaced by
+// the source code from the start of the first node to the end of the last
+// one, so that the separators between the nodes are kept. A capture that
+// didn't match anything is replaced by an empty string.
+//
+// The node that is replaced is the one captured as `@rewrite`. If the query
+// has no such capture, it is the outermost captured node of the match.
+type Rule struct {
+	query    *tree_sitter.Query
+	template []templatePart
+	target   *uint
+}
+
+type templatePart struct {
+	text         string
+	captureIndex *uint
+}
+
+// A single replacement of source code.
+type Edit struct {
+	StartByte  uint
+	EndByte    uint
+	StartPoint tree_sitter.Point
+	EndPoint   tree_sitter.Point
+
+	// The text that replaces the range.
+	Replacement string
+
+	// The index of the rule that produced the edit, and the index of the
+	// pattern in that rule's query.
+	RuleIndex    uint
+	PatternIndex uint
+}
+
+// An edit that was skipped because it overlaps an edit that was applied.
+type Overlap struct {
+	Skipped Edit
+	Applied Edit
+}
+
+// The result of [Rewrite].
+type Result struct {
+	// The rewritten source code.
+	Source []byte
+
+	// The edits that were applied, in the order of the original source code.
+	// Their ranges refer to the original source code, and don't overlap.
+	Edits []Edit
+
+	// The edits that update the tree to match the rewritten source code.
+	// They must be passed to [tree_sitter.Tree.Edit] in this order.
+	InputEdits []tree_sitter.InputEdit
+
+	// The edits that were not applied because they overlap other edits. The
+	// edit that starts first wins; if two edits start at the same position,
+	// the longer one wins, and then the one from the earlier rule.
+	Overlaps []Overlap
+
+	original []byte
+}
+
+// Create a new rule from a query and a template.
+func NewRule(query *tree_sitter.Query, template string) (*Rule, error) {
+	rule := &Rule{query: query}
+	if index, ok := query.CaptureIndexForName(TargetCaptureName); ok {
+		rule.target = &index
+	}
+
+	var text []byte
+	for i := 0; i < len(template); i++ {
+		if template[i] != '@' {
+			text = append(text, template[i])
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '@' {
+			text = append(text, '@')
+			i++
+			continue
+		}
+
+		nameEnd := i + 1
+		for nameEnd < len(template) && isCaptureNameByte(template[nameEnd]) {
+			nameEnd++
+		}
+		if nameEnd == i+1 {
+			return nil, fmt.Errorf("missing capture name after '@' at offset %d", i)
+		}
+		// Names can contain `.` and `-`, so use the longest one that the
+		// query has, and keep the rest as text, as in `@x.Method()`.
+		var index uint
+		var ok bool
+		end := nameEnd
+		for ; end > i+1; end-- {
+			if index, ok = query.CaptureIndexForName(template[i+1 : end]); ok {
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown capture @%s in template", template[i+1:nameEnd])
+		}
+		if len(text) > 0 {
+			rule.template = append(rule.template, templatePart{text: string(text)})
+			text = nil
+		}
+		rule.template = append(rule.template, templatePart{captureIndex: &index})
+		i = end - 1
+	}
+	if len(text) > 0 {
+		rule.template = append(rule.template, templatePart{text: string(text)})
+	}
+	return rule, nil
+}
+
+// Rewrite the source code of a tree with the given rules.
+//
+// The tree itself is not modified. To update it, pass the result's
+// [Result.InputEdits] to [tree_sitter.Tree.Edit] and reparse its
+// [Result.Source].
+func Rewrite(tree *tree_sitter.Tree, source []byte, rules ...*Rule) *Result {
+	cursor := tree_sitter.NewQueryCursor()
+	defer cursor.Close()
+
+	var edits []Edit
+	for ruleIndex, rule := range rules {
+		matches := cursor.Matches(rule.query, tree.RootNode(), source)
+		for match := matches.Next(); match != nil; match = matches.Next() {
+			if edit, ok := rule.edit(match, source); ok {
+				edit.RuleIndex = uint(ruleIndex)
+				edits = append(edits, edit)
+			}
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		a, b := edits[i], edits[j]
+		if a.StartByte != b.StartByte {
+			return a.StartByte < b.StartByte
+		}
+		if a.EndByte != b.EndByte {
+			return a.EndByte > b.EndByte
+		}
+		return a.RuleIndex < b.RuleIndex
+	})
+
+	result := &Result{original: source}
+	for _, edit := range edits {
+		if len(result.Edits) > 0 {
+			last := result.Edits[len(result.Edits)-1]
+			if edit.StartByte == last.StartByte && edit.EndByte == last.EndByte && edit.Replacement == last.Replacement {
+				continue
+			}
+			if edit.StartByte < last.EndByte || (edit.StartByte == last.StartByte && edit.StartByte == last.EndByte) {
+				result.Overlaps = append(result.Overlaps, Overlap{Skipped: edit, Applied: last})
+				continue
+			}
+		}
+		result.Edits = append(result.Edits, edit)
+	}
+
+	var output bytes.Buffer
+	var offset uint
+	for _, edit := range result.Edits {
+		output.Write(source[offset:edit.StartByte])
+		output.WriteString(edit.Replacement)
+		offset = edit.EndByte
+	}
+	output.Write(source[offset:])
+	result.Source = output.Bytes()
+
+	// Editing from the end of the source code to its start means that each
+	// edit's positions are not affected by the edits before it.
+	for i := len(result.Edits) - 1; i >= 0; i-- {
+		edit := result.Edits[i]
+		result.InputEdits = append(result.InputEdits, tree_sitter.InputEdit{
+			StartByte:      edit.StartByte,
+			OldEndByte:     edit.EndByte,
+			NewEndByte:     edit.StartByte + uint(len(edit.Replacement)),
+			StartPosition:  edit.StartPoint,
+			OldEndPosition: edit.EndPoint,
+			NewEndPosition: edit.StartPoint.Advance([]byte(edit.Replacement)),
+		})
+	}
+	return result
+}
+
+// Check whether any edits were applied.
+func (r *Result) Changed() bool {
+	return len(r.Edits) > 0
+}
+
+func (r *Rule) edit(match *tree_sitter.QueryMatch, source []byte) (Edit, bool) {
+	var target *tree_sitter.Node
+	for i := range match.Captures {
+		capture := &match.Captures[i]
+		if r.target != nil {
+			if uint(capture.Index) == *r.target {
+				target = &capture.Node
+				break
+			}
+			continue
+		}
+		if target == nil || capture.Node.StartByte() < target.StartByte() ||
+			(capture.Node.StartByte() == target.StartByte() && capture.Node.EndByte() > target.EndByte()) {
+			target = &capture.Node
+		}
+	}
+	if target == nil {
+		return Edit{}, false
+	}
+
+	var replacement bytes.Buffer
+	for _, part := range r.template {
+		if part.captureIndex == nil {
+			replacement.WriteString(part.text)
+			continue
+		}
+		nodes := match.NodesForCaptureIndex(*part.captureIndex)
+		if len(nodes) > 0 {
+			replacement.Write(source[nodes[0].StartByte():nodes[len(nodes)-1].EndByte()])
+		}
+	}
+
+	return Edit{
+		StartByte:    target.StartByte(),
+		EndByte:      target.EndByte(),
+		StartPoint:   target.StartPosition(),
+		EndPoint:     target.EndPosition(),
+		Replacement:  replacement.String(),
+		PatternIndex: match.PatternIndex,
+	}, true
+}
+
+func isCaptureNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-'
+}
@@ -0,0 +1,209 @@
+package rewrite_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/rewrite"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+)
+
+func newRule(t *testing.T, language *tree_sitter.Language, querySource, template string) *Rule {
+	query, queryErr := tree_sitter.NewQuery(language, querySource)
+	if !assert.Nil(t, queryErr) {
+		t.FailNow()
+	}
+	t.Cleanup(query.Close)
+
+	rule, err := NewRule(query, template)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return rule
+}
+
+func parse(t *testing.T, parser *tree_sitter.Parser, source []byte, oldTree *tree_sitter.Tree) *tree_sitter.Tree {
+	tree := parser.Parse(source, oldTree)
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func TestRewrite(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_go.Language())
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := []byte("package main\n\nfunc main() {\n\tfetch(url)\n\tfetch(a, b)\n\tfetch()\n\tother(x)\n}\n")
+	tree := parse(t, parser, source, nil)
+
+	// Each argument is captured as `@args`, and the template keeps the commas
+	// between them. The `+` leaves out calls without arguments.
+	rule := newRule(
+		t,
+		language,
+		`(call_expression
+		  function: (identifier) @fn (#eq? @fn "fetch")
+		  arguments: (argument_list ((_) @args ","?)+)) @call`,
+		"@fn(ctx, @args)",
+	)
+	result := Rewrite(tree, source, rule)
+
+	assert.True(t, result.Changed())
+	assert.Equal(
+		t,
+		"package main\n\nfunc main() {\n\tfetch(ctx, url)\n\tfetch(ctx, a, b)\n\tfetch()\n\tother(x)\n}\n",
+		string(result.Source),
+	)
+	assert.Len(t, result.Edits, 2)
+	assert.Empty(t, result.Overlaps)
+	assert.Equal(t, uint(3), result.Edits[0].StartPoint.Row)
+	assert.Equal(t, "fetch(ctx, url)", result.Edits[0].Replacement)
+
+	// Applying the input edits and reparsing gives the same tree as parsing
+	// the rewritten source code from scratch.
+	for _, edit := range result.InputEdits {
+		tree.Edit(&edit)
+	}
+	newTree := parse(t, parser, result.Source, tree)
+	freshTree := parse(t, parser, result.Source, nil)
+	assert.Equal(t, freshTree.RootNode().ToSexp(), newTree.RootNode().ToSexp())
+	assert.False(t, newTree.RootNode().HasError())
+
+	// A capture that didn't match anything is replaced by an empty string,
+	// and the template text around it is kept as is.
+	rule = newRule(
+		t,
+		language,
+		`(call_expression
+		  function: (identifier) @fn (#eq? @fn "fetch")
+		  arguments: (argument_list ((_) @args ","?)*)) @call`,
+		"@fn(ctx, @args)",
+	)
+	result = Rewrite(parse(t, parser, source, nil), source, rule)
+	assert.Len(t, result.Edits, 3)
+	assert.Equal(t, "fetch(ctx, )", result.Edits[2].Replacement)
+}
+
+func TestRewriteTargetCapture(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_javascript.Language())
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := []byte("var a = 1; var b = 2;")
+	tree := parse(t, parser, source, nil)
+
+	rule := newRule(t, language, `(variable_declaration "var" @rewrite)`, "let")
+	result := Rewrite(tree, source, rule)
+	assert.Equal(t, "let a = 1; let b = 2;", string(result.Source))
+
+	rule = newRule(t, language, `(variable_declarator name: (_) @name) @decl`, "@@@name")
+	result = Rewrite(tree, source, rule)
+	assert.Equal(t, "var @a; var @b;", string(result.Source))
+}
+
+func TestRewriteCaptureNamesWithDots(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_javascript.Language())
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := []byte("log(a); log(b);")
+	tree := parse(t, parser, source, nil)
+
+	// The `.` after `@x` is not part of the name, since there is no `@x.Method`
+	// capture, but `@fn.name` is a capture.
+	rule := newRule(
+		t,
+		language,
+		`(call_expression function: (identifier) @fn.name arguments: (arguments (_) @x)) @call`,
+		"@x.Method(@fn.name-1)",
+	)
+	result := Rewrite(tree, source, rule)
+	assert.Equal(t, "a.Method(log-1); b.Method(log-1);", string(result.Source))
+}
+
+func TestRewriteOverlaps(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_javascript.Language())
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := []byte("f(f(1));")
+	tree := parse(t, parser, source, nil)
+
+	calls := newRule(t, language, `(call_expression arguments: (arguments (_) @arg)) @call`, "g(@arg)")
+	numbers := newRule(t, language, `(number) @n`, "2")
+	result := Rewrite(tree, source, calls, numbers)
+
+	// The outer call wins over the inner call and the number inside it.
+	assert.Equal(t, "g(f(1));", string(result.Source))
+	assert.Len(t, result.Edits, 1)
+	assert.Len(t, result.Overlaps, 2)
+	for _, overlap := range result.Overlaps {
+		assert.Equal(t, result.Edits[0], overlap.Applied)
+	}
+	assert.Equal(t, "g(1)", result.Overlaps[0].Skipped.Replacement)
+	assert.Equal(t, uint(1), result.Overlaps[1].Skipped.RuleIndex)
+}
+
+func TestRewriteDiff(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_javascript.Language())
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	source := []byte("a(1);\nb();\nc();\nd();\ne();\nf();\ng();\nh();\ni();\nj();\na(2);\nk();")
+	tree := parse(t, parser, source, nil)
+
+	rule := newRule(
+		t,
+		language,
+		`(call_expression function: (identifier) @fn (#eq? @fn "a") arguments: (arguments (_) @arg)) @call`,
+		"z(@arg,\n  0)",
+	)
+	result := Rewrite(tree, source, rule)
+	assert.Equal(
+		t,
+		"--- a/test.js\n+++ b/test.js\n"+
+			"@@ -1,4 +1,5 @@\n"+
+			"-a(1);\n"+
+			"+z(1,\n"+
+			"+  0);\n"+
+			" b();\n"+
+			" c();\n"+
+			" d();\n"+
+			"@@ -8,5 +9,6 @@\n"+
+			" h();\n"+
+			" i();\n"+
+			" j();\n"+
+			"-a(2);\n"+
+			"+z(2,\n"+
+			"+  0);\n"+
+			" k();\n"+
+			"\\ No newline at end of file\n",
+		result.Diff("test.js"),
+	)
+
+	result = Rewrite(tree, source)
+	assert.False(t, result.Changed())
+	assert.Equal(t, source, result.Source)
+	assert.Empty(t, result.Diff("test.js"))
+}
+
+func TestNewRuleErrors(t *testing.T) {
+	language := tree_sitter.NewLanguage(tree_sitter_javascript.Language())
+	query, queryErr := tree_sitter.NewQuery(language, "(identifier) @id")
+	assert.Nil(t, queryErr)
+	defer query.Close()
+
+	_, err := NewRule(query, "@other")
+	assert.EqualError(t, err, "unknown capture @other in template")
+	_, err = NewRule(query, "@other.id")
+	assert.EqualError(t, err, "unknown capture @other.id in template")
+	_, err = NewRule(query, "x @ y")
+	assert.EqualError(t, err, "missing capture name after '@' at offset 2")
+}
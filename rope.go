@@ -0,0 +1,375 @@
+package tree_sitter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+)
+
+// The maximum number of bytes stored in a single chunk of a [Rope].
+const ropeChunkSize = 2048
+
+// A text buffer for large documents that are edited frequently.
+//
+// The text is stored as a balanced tree of chunks, so that inserting and
+// deleting text, and converting between byte offsets and points, take
+// logarithmic time in the size of the text. [Rope.ReadChunk] can be passed
+// directly to [Parser.ParseWithOptions], so that the text never has to be
+// copied into a single slice in order to be parsed:
+//
+//	rope := tree_sitter.NewRope(text)
+//	tree := parser.ParseWithOptions(rope.ReadChunk, nil, nil)
+//
+//	edit, _ := rope.Replace(start, end, newText)
+//	tree.Edit(&edit)
+//	newTree := parser.ParseWithOptions(rope.ReadChunk, tree, nil)
+//
+// A Rope must not be edited while it is being parsed.
+type Rope struct {
+	root *ropeNode
+}
+
+// A node of the treap that stores the chunks of a [Rope] in order.
+type ropeNode struct {
+	left     *ropeNode
+	right    *ropeNode
+	priority uint64
+	chunk    []byte
+
+	// The total length and number of newlines of this subtree.
+	length   uint
+	newlines uint
+}
+
+// Create a new rope that contains a copy of the given text.
+func NewRope(text []byte) *Rope {
+	return &Rope{root: buildRope(text)}
+}
+
+// Get the length of the text in bytes.
+func (r *Rope) Len() uint {
+	return r.root.len()
+}
+
+// Get the number of rows in the text, which is one more than the number of
+// newlines.
+func (r *Rope) LineCount() uint {
+	return r.root.lines() + 1
+}
+
+// Get a copy of the whole text.
+func (r *Rope) Bytes() []byte {
+	return r.Slice(0, r.Len())
+}
+
+// Get a copy of the whole text as a string.
+func (r *Rope) String() string {
+	return string(r.Bytes())
+}
+
+// Get a copy of the text between the given byte offsets, which are clamped
+// to the length of the text.
+func (r *Rope) Slice(startByte, endByte uint) []byte {
+	endByte = min(endByte, r.Len())
+	if startByte >= endByte {
+		return []byte{}
+	}
+	result := make([]byte, 0, endByte-startByte)
+	for offset := startByte; offset < endByte; {
+		chunk := r.chunkAt(offset)
+		chunk = chunk[:min(uint(len(chunk)), endByte-offset)]
+		result = append(result, chunk...)
+		offset += uint(len(chunk))
+	}
+	return result
+}
+
+// Get the rest of the chunk that contains the given byte offset, or an
+// empty slice if the offset is at or past the end of the text.
+//
+// Its signature matches the callback of [Parser.ParseWithOptions]. The
+// returned slice must not be modified, and is no longer valid after the
+// next edit.
+func (r *Rope) ReadChunk(offset int, _ Point) []byte {
+	if offset < 0 {
+		return []byte{}
+	}
+	return r.chunkAt(uint(offset))
+}
+
+// ReadAt implements [io.ReaderAt].
+func (r *Rope) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("Negative offset %d", off)
+	}
+	if uint64(off) >= uint64(r.Len()) {
+		return 0, io.EOF
+	}
+	var n int
+	for n < len(p) {
+		chunk := r.chunkAt(uint(off) + uint(n))
+		if len(chunk) == 0 {
+			return n, io.EOF
+		}
+		n += copy(p[n:], chunk)
+	}
+	return n, nil
+}
+
+// Insert text at the given byte offset, and return the edit that must be
+// applied to a tree that was parsed from the old text.
+func (r *Rope) Insert(offset uint, text []byte) (InputEdit, error) {
+	return r.Replace(offset, offset, text)
+}
+
+// Delete the text between the given byte offsets, and return the edit that
+// must be applied to a tree that was parsed from the old text.
+func (r *Rope) Delete(startByte, endByte uint) (InputEdit, error) {
+	return r.Replace(startByte, endByte, nil)
+}
+
+// Replace the text between the given byte offsets with `text`, and return
+// the edit that must be applied to a tree that was parsed from the old
+// text, using [Tree.Edit].
+func (r *Rope) Replace(startByte, oldEndByte uint, text []byte) (InputEdit, error) {
+	if startByte > oldEndByte || oldEndByte > r.Len() {
+		return InputEdit{}, fmt.Errorf("Invalid edit range %d..%d for a text of length %d", startByte, oldEndByte, r.Len())
+	}
+
+	startPosition := r.pointForByte(startByte)
+	edit := InputEdit{
+		StartByte:      startByte,
+		OldEndByte:     oldEndByte,
+		NewEndByte:     startByte + uint(len(text)),
+		StartPosition:  startPosition,
+		OldEndPosition: r.pointForByte(oldEndByte),
+		NewEndPosition: startPosition.Advance(text),
+	}
+
+	if oldEndByte > startByte {
+		left, rest := splitRope(r.root, startByte)
+		_, right := splitRope(rest, oldEndByte-startByte)
+		r.root = mergeRopes(left, right)
+	}
+	if len(text) > 0 && (r.root == nil || !r.root.insertInChunk(startByte, text)) {
+		left, right := splitRope(r.root, startByte)
+		r.root = mergeRopes(mergeRopes(left, buildRope(text)), right)
+	}
+
+	return edit, nil
+}
+
+// Get the row and column of the given byte offset.
+func (r *Rope) PointForByte(offset uint) (Point, error) {
+	if offset > r.Len() {
+		return Point{}, fmt.Errorf("Byte offset %d is out of range for a text of length %d", offset, r.Len())
+	}
+	return r.pointForByte(offset), nil
+}
+
+// Get the byte offset of the given row and column.
+//
+// The column may point at the end of the row, but not past it.
+func (r *Rope) ByteForPoint(point Point) (uint, error) {
+	rows := r.LineCount()
+	if point.Row >= rows {
+		return 0, fmt.Errorf("Row %d is out of range for a text with %d rows", point.Row, rows)
+	}
+
+	lineStart := r.root.lineStart(point.Row)
+	lineEnd := r.Len()
+	if point.Row+1 < rows {
+		lineEnd = r.root.lineStart(point.Row+1) - 1
+	}
+	if point.Column > lineEnd-lineStart {
+		return 0, fmt.Errorf("Column %d is out of range for row %d of length %d", point.Column, point.Row, lineEnd-lineStart)
+	}
+	return lineStart + point.Column, nil
+}
+
+func (r *Rope) pointForByte(offset uint) Point {
+	row := r.root.newlinesBefore(offset)
+	return Point{Row: row, Column: offset - r.root.lineStart(row)}
+}
+
+func (r *Rope) chunkAt(offset uint) []byte {
+	n := r.root
+	for n != nil {
+		leftLength := n.left.len()
+		switch {
+		case offset < leftLength:
+			n = n.left
+		case offset < leftLength+uint(len(n.chunk)):
+			return n.chunk[offset-leftLength:]
+		default:
+			offset -= leftLength + uint(len(n.chunk))
+			n = n.right
+		}
+	}
+	return []byte{}
+}
+
+func newRopeNode(chunk []byte) *ropeNode {
+	n := &ropeNode{priority: rand.Uint64(), chunk: chunk}
+	n.update()
+	return n
+}
+
+func buildRope(text []byte) *ropeNode {
+	var root *ropeNode
+	for len(text) > 0 {
+		size := min(len(text), ropeChunkSize)
+		root = mergeRopes(root, newRopeNode(bytes.Clone(text[:size])))
+		text = text[size:]
+	}
+	return root
+}
+
+func (n *ropeNode) len() uint {
+	if n == nil {
+		return 0
+	}
+	return n.length
+}
+
+func (n *ropeNode) lines() uint {
+	if n == nil {
+		return 0
+	}
+	return n.newlines
+}
+
+func (n *ropeNode) update() {
+	n.length = n.left.len() + uint(len(n.chunk)) + n.right.len()
+	n.newlines = n.left.lines() + uint(bytes.Count(n.chunk, []byte{'\n'})) + n.right.lines()
+}
+
+// Split the subtree into the text before and after the given offset.
+func splitRope(n *ropeNode, offset uint) (*ropeNode, *ropeNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	leftLength := n.left.len()
+	chunkEnd := leftLength + uint(len(n.chunk))
+	switch {
+	case offset <= leftLength:
+		left, right := splitRope(n.left, offset)
+		n.left = right
+		n.update()
+		return left, n
+	case offset >= chunkEnd:
+		left, right := splitRope(n.right, offset-chunkEnd)
+		n.right = left
+		n.update()
+		return n, right
+	default:
+		// Chunks are never modified in place, so both halves can share
+		// the same array.
+		i := offset - leftLength
+		right := mergeRopes(newRopeNode(n.chunk[i:]), n.right)
+		n.chunk = n.chunk[:i:i]
+		n.right = nil
+		n.update()
+		return n, right
+	}
+}
+
+// Concatenate two subtrees.
+func mergeRopes(left, right *ropeNode) *ropeNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	if left.priority > right.priority {
+		left.right = mergeRopes(left.right, right)
+		left.update()
+		return left
+	}
+	right.left = mergeRopes(left, right.left)
+	right.update()
+	return right
+}
+
+// Insert text into the chunk that contains the given offset, if it fits.
+// This keeps the number of chunks small when text is typed one character
+// at a time.
+func (n *ropeNode) insertInChunk(offset uint, text []byte) bool {
+	var inserted bool
+	leftLength := n.left.len()
+	chunkEnd := leftLength + uint(len(n.chunk))
+	switch {
+	case offset < leftLength:
+		inserted = n.left.insertInChunk(offset, text)
+	case offset <= chunkEnd:
+		if len(n.chunk)+len(text) > ropeChunkSize {
+			return false
+		}
+		i := offset - leftLength
+		chunk := make([]byte, 0, len(n.chunk)+len(text))
+		chunk = append(chunk, n.chunk[:i]...)
+		chunk = append(chunk, text...)
+		n.chunk = append(chunk, n.chunk[i:]...)
+		inserted = true
+	default:
+		inserted = n.right.insertInChunk(offset-chunkEnd, text)
+	}
+	if inserted {
+		n.length += uint(len(text))
+		n.newlines += uint(bytes.Count(text, []byte{'\n'}))
+	}
+	return inserted
+}
+
+// Get the number of newlines before the given offset.
+func (n *ropeNode) newlinesBefore(offset uint) uint {
+	var count uint
+	for n != nil {
+		leftLength := n.left.len()
+		chunkEnd := leftLength + uint(len(n.chunk))
+		switch {
+		case offset <= leftLength:
+			n = n.left
+		case offset <= chunkEnd:
+			return count + n.left.lines() + uint(bytes.Count(n.chunk[:offset-leftLength], []byte{'\n'}))
+		default:
+			count += n.newlines - n.right.lines()
+			offset -= chunkEnd
+			n = n.right
+		}
+	}
+	return count
+}
+
+// Get the byte offset at which the given row starts. The row must exist.
+func (n *ropeNode) lineStart(row uint) uint {
+	var offset uint
+	for row > 0 && n != nil {
+		leftLines := n.left.lines()
+		if row <= leftLines {
+			n = n.left
+			continue
+		}
+		row -= leftLines
+		offset += n.left.len()
+
+		chunk := n.chunk
+		for row > 0 {
+			i := bytes.IndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			chunk = chunk[i+1:]
+			row--
+		}
+		if row == 0 {
+			return offset + uint(len(n.chunk)-len(chunk))
+		}
+		offset += uint(len(n.chunk))
+		n = n.right
+	}
+	return offset
+}
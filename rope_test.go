@@ -0,0 +1,119 @@
+package tree_sitter_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestRopeEdits(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+
+	var expected []byte
+	for i := 0; i < 2000; i++ {
+		expected = append(expected, randWords(random, 10)...)
+		expected = append(expected, '\n')
+	}
+	rope := NewRope(expected)
+	assert.Equal(t, expected, rope.Bytes())
+
+	for i := 0; i < 2000; i++ {
+		edit := getRandomEdit(random, expected)
+		if i%10 == 0 {
+			// Insert text that spans multiple chunks.
+			edit.insertedText = bytes.Repeat([]byte("abc\n"), random.Intn(2000))
+		}
+
+		inputEdit, err := rope.Replace(edit.position, edit.position+edit.deletedLength, edit.insertedText)
+		assert.Nil(t, err)
+
+		startPosition, _ := positionForOffset(expected, edit.position)
+		oldEndPosition, _ := positionForOffset(expected, edit.position+edit.deletedLength)
+		expected = append(expected[:edit.position:edit.position], append(edit.insertedText, expected[edit.position+edit.deletedLength:]...)...)
+		newEndPosition, _ := positionForOffset(expected, edit.position+uint(len(edit.insertedText)))
+		assert.Equal(t, InputEdit{
+			StartByte:      edit.position,
+			OldEndByte:     edit.position + edit.deletedLength,
+			NewEndByte:     edit.position + uint(len(edit.insertedText)),
+			StartPosition:  startPosition,
+			OldEndPosition: oldEndPosition,
+			NewEndPosition: newEndPosition,
+		}, inputEdit)
+	}
+	assert.Equal(t, expected, rope.Bytes())
+	assert.Equal(t, uint(bytes.Count(expected, []byte{'\n'})+1), rope.LineCount())
+
+	for i := 0; i < 200; i++ {
+		offset := uint(random.Intn(len(expected) + 1))
+		point, err := rope.PointForByte(offset)
+		assert.Nil(t, err)
+		expectedPoint, _ := positionForOffset(expected, offset)
+		assert.Equal(t, expectedPoint, point)
+
+		byteOffset, err := rope.ByteForPoint(point)
+		assert.Nil(t, err)
+		assert.Equal(t, offset, byteOffset)
+
+		end := offset + uint(random.Intn(5000))
+		assert.Equal(t, expected[offset:min(end, uint(len(expected)))], rope.Slice(offset, end))
+	}
+}
+
+func TestRopeErrors(t *testing.T) {
+	rope := NewRope([]byte("ab\ncd"))
+
+	_, err := rope.Replace(3, 2, nil)
+	assert.NotNil(t, err)
+	_, err = rope.Delete(0, 6)
+	assert.NotNil(t, err)
+	_, err = rope.PointForByte(6)
+	assert.NotNil(t, err)
+	_, err = rope.ByteForPoint(NewPoint(0, 3))
+	assert.NotNil(t, err)
+	_, err = rope.ByteForPoint(NewPoint(2, 0))
+	assert.NotNil(t, err)
+
+	offset, err := rope.ByteForPoint(NewPoint(1, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, uint(5), offset)
+
+	buffer := make([]byte, 4)
+	n, err := rope.ReadAt(buffer, 2)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "\ncd", string(buffer[:n]))
+}
+
+func TestRopeParsing(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	source := bytes.Repeat([]byte("function f(a, b) {\n  return a + b;\n}\n"), 500)
+	rope := NewRope(source)
+
+	tree := parser.ParseWithOptions(rope.ReadChunk, nil, nil)
+	defer tree.Close()
+	assert.Equal(t, uint(500), tree.RootNode().NamedChildCount())
+	assert.Equal(t, uint(len(source)), tree.RootNode().EndByte())
+
+	edit, err := rope.Insert(28, []byte(" * c"))
+	assert.Nil(t, err)
+	tree.Edit(&edit)
+	newTree := parser.ParseWithOptions(rope.ReadChunk, tree, nil)
+	defer newTree.Close()
+
+	freshTree := parser.Parse(rope.Bytes(), nil)
+	defer freshTree.Close()
+	assert.Equal(t, freshTree.RootNode().ToSexp(), newTree.RootNode().ToSexp())
+	ranges := tree.ChangedRanges(newTree)
+	assert.NotEmpty(t, ranges)
+	for _, r := range ranges {
+		assert.Equal(t, uint(1), r.StartPoint.Row)
+		assert.Equal(t, uint(1), r.EndPoint.Row)
+	}
+}
@@ -0,0 +1,434 @@
+package tree_sitter
+
+import "iter"
+
+const (
+	snapshotNamed uint8 = 1 << iota
+	snapshotExtra
+	snapshotMissing
+	snapshotError
+	snapshotHasError
+)
+
+// The parent index of a snapshot's root node.
+const snapshotNoParent = ^uint32(0)
+
+// An immutable, Go-native copy of a syntax tree.
+//
+// A snapshot contains the kinds, field names, ranges and flags of all of the
+// tree's nodes, along with their parent and child links. Reading it never
+// calls into C, it stays valid after the [Tree] is closed, and it can be
+// read from many goroutines at once.
+type Snapshot struct {
+	nodes    []snapshotNode
+	children []uint32
+	kinds    []string
+	fields   []string
+
+	// The kinds of nodes whose ids are not in the language's symbol table,
+	// such as `ERROR`.
+	otherKinds map[uint16]string
+}
+
+type snapshotNode struct {
+	startPoint    Point
+	endPoint      Point
+	startByte     uint32
+	endByte       uint32
+	parent        uint32
+	indexInParent uint32
+	childStart    uint32
+	childCount    uint32
+	kindId        uint16
+	fieldId       uint16
+	flags         uint8
+}
+
+// A node in a [Snapshot].
+//
+// This is a small value that can be copied and compared freely. The zero
+// value is not a valid node.
+type SnapshotNode struct {
+	snapshot *Snapshot
+	index    uint32
+}
+
+// Create an immutable, Go-native copy of the syntax tree. See [Snapshot].
+func (t *Tree) Snapshot() *Snapshot {
+	language := t.Language()
+	root := t.RootNode()
+	nodeCount := root.DescendantCount()
+
+	s := &Snapshot{
+		nodes:    make([]snapshotNode, 0, nodeCount),
+		children: make([]uint32, 0, nodeCount),
+		kinds:    make([]string, language.NodeKindCount()),
+		fields:   make([]string, language.FieldCount()+1),
+
+		otherKinds: make(map[uint16]string),
+	}
+
+	cursor := root.Walk()
+	defer cursor.Close()
+
+	// The indices of the current node's ancestors, and the number of their
+	// children that have been visited so far.
+	var ancestors, visitedChildren []uint32
+	for {
+		node := cursor.Node()
+		index := uint32(len(s.nodes))
+		kindId := node.KindId()
+		fieldId := cursor.FieldId()
+		if int(kindId) >= len(s.kinds) {
+			if _, ok := s.otherKinds[kindId]; !ok {
+				s.otherKinds[kindId] = node.Kind()
+			}
+		} else if s.kinds[kindId] == "" {
+			s.kinds[kindId] = node.Kind()
+		}
+		if int(fieldId) < len(s.fields) && s.fields[fieldId] == "" {
+			s.fields[fieldId] = language.FieldNameForId(fieldId)
+		}
+
+		var flags uint8
+		if node.IsNamed() {
+			flags |= snapshotNamed
+		}
+		if node.IsExtra() {
+			flags |= snapshotExtra
+		}
+		if node.IsMissing() {
+			flags |= snapshotMissing
+		}
+		if node.IsError() {
+			flags |= snapshotError
+		}
+		if node.HasError() {
+			flags |= snapshotHasError
+		}
+
+		childCount := node.ChildCount()
+		entry := snapshotNode{
+			startPoint: node.StartPosition(),
+			endPoint:   node.EndPosition(),
+			startByte:  uint32(node.StartByte()),
+			endByte:    uint32(node.EndByte()),
+			parent:     snapshotNoParent,
+			childStart: uint32(len(s.children)),
+			childCount: uint32(childCount),
+			kindId:     kindId,
+			fieldId:    fieldId,
+			flags:      flags,
+		}
+		if depth := len(ancestors); depth > 0 {
+			parent := ancestors[depth-1]
+			entry.parent = parent
+			entry.indexInParent = visitedChildren[depth-1]
+			s.children[s.nodes[parent].childStart+entry.indexInParent] = index
+			visitedChildren[depth-1]++
+		}
+		s.nodes = append(s.nodes, entry)
+		for i := uint(0); i < childCount; i++ {
+			s.children = append(s.children, 0)
+		}
+
+		if cursor.GotoFirstChild() {
+			ancestors = append(ancestors, index)
+			visitedChildren = append(visitedChildren, 0)
+			continue
+		}
+		for !cursor.GotoNextSibling() {
+			if !cursor.GotoParent() {
+				return s
+			}
+			ancestors = ancestors[:len(ancestors)-1]
+			visitedChildren = visitedChildren[:len(visitedChildren)-1]
+		}
+	}
+}
+
+// Get the root node of the snapshot.
+func (s *Snapshot) RootNode() SnapshotNode {
+	return SnapshotNode{snapshot: s, index: 0}
+}
+
+// Get the number of nodes in the snapshot.
+func (s *Snapshot) NodeCount() uint {
+	return uint(len(s.nodes))
+}
+
+// Get the node with the given id, as returned by [SnapshotNode.Id].
+func (s *Snapshot) Node(id uint) (SnapshotNode, bool) {
+	if id >= uint(len(s.nodes)) {
+		return SnapshotNode{}, false
+	}
+	return SnapshotNode{snapshot: s, index: uint32(id)}, true
+}
+
+func (n SnapshotNode) data() *snapshotNode {
+	return &n.snapshot.nodes[n.index]
+}
+
+func (n SnapshotNode) node(index uint32) SnapshotNode {
+	return SnapshotNode{snapshot: n.snapshot, index: index}
+}
+
+// Get a numeric id for this node that is unique within its snapshot.
+//
+// Ids are assigned in preorder, so the root node's id is 0, and a node's
+// descendants have larger ids than the node itself.
+func (n SnapshotNode) Id() uint {
+	return uint(n.index)
+}
+
+// Get this node's type as a numerical id.
+func (n SnapshotNode) KindId() uint16 {
+	return n.data().kindId
+}
+
+// Get this node's type as a string.
+func (n SnapshotNode) Kind() string {
+	kindId := n.data().kindId
+	if int(kindId) < len(n.snapshot.kinds) {
+		return n.snapshot.kinds[kindId]
+	}
+	return n.snapshot.otherKinds[kindId]
+}
+
+// Check if this node is *named*.
+func (n SnapshotNode) IsNamed() bool {
+	return n.data().flags&snapshotNamed != 0
+}
+
+// Check if this node is *extra*.
+func (n SnapshotNode) IsExtra() bool {
+	return n.data().flags&snapshotExtra != 0
+}
+
+// Check if this node is *missing*.
+func (n SnapshotNode) IsMissing() bool {
+	return n.data().flags&snapshotMissing != 0
+}
+
+// Check if this node represents a syntax error.
+func (n SnapshotNode) IsError() bool {
+	return n.data().flags&snapshotError != 0
+}
+
+// Check if this node represents a syntax error or contains any syntax
+// errors anywhere within it.
+func (n SnapshotNode) HasError() bool {
+	return n.data().flags&snapshotHasError != 0
+}
+
+// Get the byte offset where this node starts.
+func (n SnapshotNode) StartByte() uint {
+	return uint(n.data().startByte)
+}
+
+// Get the byte offset where this node ends.
+func (n SnapshotNode) EndByte() uint {
+	return uint(n.data().endByte)
+}
+
+// Get the byte range of source code that this node represents.
+func (n SnapshotNode) ByteRange() (uint, uint) {
+	data := n.data()
+	return uint(data.startByte), uint(data.endByte)
+}
+
+// Get the range of source code that this node represents, both in terms of
+// raw bytes and of row/column coordinates.
+func (n SnapshotNode) Range() Range {
+	data := n.data()
+	return Range{
+		StartByte:  uint(data.startByte),
+		EndByte:    uint(data.endByte),
+		StartPoint: data.startPoint,
+		EndPoint:   data.endPoint,
+	}
+}
+
+// Get this node's start position in terms of rows and columns.
+func (n SnapshotNode) StartPosition() Point {
+	return n.data().startPoint
+}
+
+// Get this node's end position in terms of rows and columns.
+func (n SnapshotNode) EndPosition() Point {
+	return n.data().endPoint
+}
+
+// Get the field name of this node within its parent, or an empty string if
+// it is not a field.
+func (n SnapshotNode) FieldName() string {
+	fieldId := n.data().fieldId
+	if fieldId == 0 || int(fieldId) >= len(n.snapshot.fields) {
+		return ""
+	}
+	return n.snapshot.fields[fieldId]
+}
+
+// Get this node's immediate parent.
+func (n SnapshotNode) Parent() (SnapshotNode, bool) {
+	parent := n.data().parent
+	if parent == snapshotNoParent {
+		return SnapshotNode{}, false
+	}
+	return n.node(parent), true
+}
+
+// Get this node's number of children.
+func (n SnapshotNode) ChildCount() uint {
+	return uint(n.data().childCount)
+}
+
+// Get this node's child at the given index, where zero represents the first
+// child.
+func (n SnapshotNode) Child(i uint) (SnapshotNode, bool) {
+	data := n.data()
+	if i >= uint(data.childCount) {
+		return SnapshotNode{}, false
+	}
+	return n.node(n.snapshot.children[uint(data.childStart)+i]), true
+}
+
+// Get this node's number of *named* children.
+func (n SnapshotNode) NamedChildCount() uint {
+	var count uint
+	for child := range n.ChildrenSeq() {
+		if child.IsNamed() {
+			count++
+		}
+	}
+	return count
+}
+
+// Get this node's *named* child at the given index.
+func (n SnapshotNode) NamedChild(i uint) (SnapshotNode, bool) {
+	for child := range n.NamedChildrenSeq() {
+		if i == 0 {
+			return child, true
+		}
+		i--
+	}
+	return SnapshotNode{}, false
+}
+
+// Get the first child with the given field name.
+func (n SnapshotNode) ChildByFieldName(fieldName string) (SnapshotNode, bool) {
+	for child := range n.ChildrenSeq() {
+		if child.FieldName() == fieldName {
+			return child, true
+		}
+	}
+	return SnapshotNode{}, false
+}
+
+// Get this node's children with the given field name.
+func (n SnapshotNode) ChildrenByFieldName(fieldName string) []SnapshotNode {
+	var result []SnapshotNode
+	for child := range n.ChildrenSeq() {
+		if child.FieldName() == fieldName {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// Get this node's next sibling.
+//
+// Unlike [Node.NextSibling], this does not skip zero-width *missing* nodes.
+func (n SnapshotNode) NextSibling() (SnapshotNode, bool) {
+	parent, ok := n.Parent()
+	if !ok {
+		return SnapshotNode{}, false
+	}
+	return parent.Child(uint(n.data().indexInParent) + 1)
+}
+
+// Get this node's previous sibling.
+func (n SnapshotNode) PrevSibling() (SnapshotNode, bool) {
+	parent, ok := n.Parent()
+	index := n.data().indexInParent
+	if !ok || index == 0 {
+		return SnapshotNode{}, false
+	}
+	return parent.Child(uint(index) - 1)
+}
+
+// Get this node's next *named* sibling.
+func (n SnapshotNode) NextNamedSibling() (SnapshotNode, bool) {
+	for {
+		next, ok := n.NextSibling()
+		if !ok || next.IsNamed() {
+			return next, ok
+		}
+		n = next
+	}
+}
+
+// Get this node's previous *named* sibling.
+func (n SnapshotNode) PrevNamedSibling() (SnapshotNode, bool) {
+	for {
+		prev, ok := n.PrevSibling()
+		if !ok || prev.IsNamed() {
+			return prev, ok
+		}
+		n = prev
+	}
+}
+
+// Iterate over this node's children.
+func (n SnapshotNode) ChildrenSeq() iter.Seq[SnapshotNode] {
+	return func(yield func(SnapshotNode) bool) {
+		data := n.data()
+		for _, index := range n.snapshot.children[data.childStart : data.childStart+data.childCount] {
+			if !yield(n.node(index)) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate over this node's *named* children.
+func (n SnapshotNode) NamedChildrenSeq() iter.Seq[SnapshotNode] {
+	return func(yield func(SnapshotNode) bool) {
+		for child := range n.ChildrenSeq() {
+			if child.IsNamed() && !yield(child) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate over this node and all of its descendants in preorder.
+func (n SnapshotNode) Descendants() iter.Seq[SnapshotNode] {
+	return func(yield func(SnapshotNode) bool) {
+		// Nodes are stored in preorder, so a node's descendants directly
+		// follow it.
+		end := uint32(len(n.snapshot.nodes))
+		for ancestor := n; ; {
+			if next, ok := ancestor.NextSibling(); ok {
+				end = next.index
+				break
+			}
+			parent, ok := ancestor.Parent()
+			if !ok {
+				break
+			}
+			ancestor = parent
+		}
+		for index := n.index; index < end; index++ {
+			if !yield(n.node(index)) {
+				return
+			}
+		}
+	}
+}
+
+// Get the text of this node from the source code that it was parsed from.
+func (n SnapshotNode) Utf8Text(source []byte) string {
+	data := n.data()
+	return string(source[data.startByte:data.endByte])
+}
@@ -0,0 +1,144 @@
+package tree_sitter_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func assertSnapshotNodeMatches(t *testing.T, node *Node, snapshotNode SnapshotNode) {
+	assert.Equal(t, node.Kind(), snapshotNode.Kind())
+	assert.Equal(t, node.KindId(), snapshotNode.KindId())
+	assert.Equal(t, node.IsNamed(), snapshotNode.IsNamed())
+	assert.Equal(t, node.IsExtra(), snapshotNode.IsExtra())
+	assert.Equal(t, node.IsMissing(), snapshotNode.IsMissing())
+	assert.Equal(t, node.IsError(), snapshotNode.IsError())
+	assert.Equal(t, node.HasError(), snapshotNode.HasError())
+	assert.Equal(t, node.Range(), snapshotNode.Range())
+	assert.Equal(t, node.ChildCount(), snapshotNode.ChildCount())
+	assert.Equal(t, node.NamedChildCount(), snapshotNode.NamedChildCount())
+
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child, ok := snapshotNode.Child(i)
+		assert.True(t, ok)
+		assert.Equal(t, node.FieldNameForChild(uint32(i)), child.FieldName())
+		parent, ok := child.Parent()
+		assert.True(t, ok)
+		assert.Equal(t, snapshotNode, parent)
+		assertSnapshotNodeMatches(t, node.Child(i), child)
+	}
+	_, ok := snapshotNode.Child(node.ChildCount())
+	assert.False(t, ok)
+
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child, ok := snapshotNode.NamedChild(i)
+		assert.True(t, ok)
+		assert.Equal(t, node.NamedChild(i).Range(), child.Range())
+	}
+
+	// Unlike [Node.NextSibling], siblings in a snapshot include zero-width
+	// missing nodes, so compare them to the parent's children instead.
+	for i := uint(0); i+1 < node.ChildCount(); i++ {
+		child, _ := snapshotNode.Child(i)
+		next, ok := child.NextSibling()
+		assert.True(t, ok)
+		assert.Equal(t, node.Child(i+1).Range(), next.Range())
+		prev, ok := next.PrevSibling()
+		assert.True(t, ok)
+		assert.Equal(t, child, prev)
+	}
+	if node.ChildCount() > 0 {
+		first, _ := snapshotNode.Child(0)
+		_, ok := first.PrevSibling()
+		assert.False(t, ok)
+		last, _ := snapshotNode.Child(node.ChildCount() - 1)
+		_, ok = last.NextNamedSibling()
+		assert.False(t, ok)
+	}
+}
+
+func TestTreeSnapshot(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+
+	for _, test := range []struct {
+		language string
+		source   string
+	}{
+		{"javascript", "// hi\nclass A { f(a, b) { return a + b; } }\nfoo(1, 2 3);"},
+		{"rust", "fn main() { let x = 1 }"},
+		{"json", "[1, {\"a\": true}]"},
+	} {
+		parser.SetLanguage(getLanguage(test.language))
+		tree := parser.Parse([]byte(test.source), nil)
+		snapshot := tree.Snapshot()
+
+		assert.Equal(t, tree.RootNode().DescendantCount(), snapshot.NodeCount())
+		assertSnapshotNodeMatches(t, tree.RootNode(), snapshot.RootNode())
+
+		var kinds []string
+		for node := range tree.RootNode().Descendants() {
+			kinds = append(kinds, node.Kind())
+		}
+		var snapshotKinds []string
+		for node := range snapshot.RootNode().Descendants() {
+			snapshotKinds = append(snapshotKinds, node.Kind())
+		}
+		assert.Equal(t, kinds, snapshotKinds)
+
+		tree.Close()
+	}
+}
+
+func TestTreeSnapshotAfterClose(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	source := []byte("function f(a, b) { return a + b; }")
+	tree := parser.Parse(source, nil)
+	snapshot := tree.Snapshot()
+	tree.Close()
+
+	root := snapshot.RootNode()
+	function, ok := root.Child(0)
+	assert.True(t, ok)
+	name, ok := function.ChildByFieldName("name")
+	assert.True(t, ok)
+	assert.Equal(t, "f", name.Utf8Text(source))
+	assert.Len(t, function.ChildrenByFieldName("parameters"), 1)
+
+	node, ok := snapshot.Node(name.Id())
+	assert.True(t, ok)
+	assert.Equal(t, name, node)
+	_, ok = snapshot.Node(snapshot.NodeCount())
+	assert.False(t, ok)
+	_, ok = root.Parent()
+	assert.False(t, ok)
+
+	// The body's descendants stop before the next node outside of it.
+	body, _ := function.ChildByFieldName("body")
+	var count uint
+	for range body.Descendants() {
+		count++
+	}
+	assert.Equal(t, snapshot.NodeCount()-body.Id(), count)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var identifiers int
+			for node := range root.Descendants() {
+				if node.Kind() == "identifier" {
+					identifiers++
+				}
+			}
+			assert.Equal(t, 5, identifiers)
+		}()
+	}
+	wg.Wait()
+}
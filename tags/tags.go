@@ -0,0 +1,654 @@
+// Package tags extracts code navigation tags with Tree-sitter queries.
+//
+// It is a port of the Rust `tree-sitter-tags` crate, and understands the
+// same `tags.scm` and `locals.scm` query files.
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const (
+	maxLineLen                = 180
+	cancellationCheckInterval = 100
+)
+
+var (
+	// Returned when tagging was cancelled with the cancellation flag.
+	ErrCancelled = errors.New("tagging was cancelled")
+
+	// Returned when a language could not be assigned to the parser.
+	ErrInvalidLanguage = errors.New("invalid language")
+)
+
+// An error that is returned when a tags query contains a capture whose name
+// doesn't start with `definition.` or `reference.`, and isn't one of the
+// special capture names.
+type InvalidCaptureError struct {
+	Name string
+}
+
+// A half-open range of offsets.
+type Range struct {
+	Start uint
+	End   uint
+}
+
+// A definition of or a reference to a symbol.
+type Tag struct {
+	// The byte range of the whole tagged node, including its name.
+	Range Range
+
+	// The byte range of the name.
+	NameRange Range
+
+	// The byte range of the line containing the name, without leading and
+	// trailing whitespace, and limited to 180 bytes.
+	LineRange Range
+
+	// The start and end positions of the name.
+	StartPoint tree_sitter.Point
+	EndPoint   tree_sitter.Point
+
+	// The range of UTF-16 code units that the name spans within its line.
+	UTF16ColumnRange Range
+
+	// The documentation for the tag, collected from the nodes captured with
+	// `@doc`, or `nil` if there is none.
+	Docs *string
+
+	// Whether the tag is a definition rather than a reference.
+	IsDefinition bool
+
+	// The id of the kind of tag, such as `function` or `class`. Use
+	// [TagsConfiguration.SyntaxTypeName] to get its name.
+	SyntaxTypeId uint
+}
+
+// Contains the data needed to compute tags for code written in a particular
+// language.
+//
+// This struct is immutable and can be shared between goroutines.
+type TagsConfiguration struct {
+	Language *tree_sitter.Language
+	Query    *tree_sitter.Query
+
+	syntaxTypeNames             []string
+	captureMap                  map[uint]namedCapture
+	docCaptureIndex             *uint
+	nameCaptureIndex            *uint
+	ignoreCaptureIndex          *uint
+	localScopeCaptureIndex      *uint
+	localDefinitionCaptureIndex *uint
+	tagsPatternIndex            uint
+	patternInfo                 []patternInfo
+}
+
+// Holds the parser and query cursor that are used to compute tags.
+//
+// For the best performance [TagsContext] values should be reused between
+// calls. A separate context is needed for each goroutine that is computing
+// tags.
+type TagsContext struct {
+	Parser *tree_sitter.Parser
+	cursor *tree_sitter.QueryCursor
+}
+
+// An iterator over the [Tag]s of a document.
+//
+// See [TagsContext.GenerateTags].
+type TagsIter struct {
+	matches          tree_sitter.QueryMatches
+	tree             *tree_sitter.Tree
+	source           []byte
+	prevLineInfo     *lineInfo
+	config           *TagsConfiguration
+	cancellationFlag *uintptr
+	iterCount        int
+	tagQueue         []queuedTag
+	scopes           []*localScope
+}
+
+type namedCapture struct {
+	syntaxTypeId uint
+	isDefinition bool
+}
+
+type patternInfo struct {
+	docsAdjacentCapture *uint
+	localScopeInherits  bool
+	nameMustBeNonLocal  bool
+	docStripRegex       *regexp.Regexp
+}
+
+type localScope struct {
+	inherits  bool
+	start     uint
+	end       uint
+	localDefs []string
+}
+
+type lineInfo struct {
+	utf8Position tree_sitter.Point
+	utf8Byte     uint
+	utf16Column  uint
+	lineRange    Range
+}
+
+type queuedTag struct {
+	tag          Tag
+	patternIndex uint
+	ignored      bool
+}
+
+// Creates a [TagsConfiguration] for a given [tree_sitter.Language] and set of
+// queries.
+//
+// # Arguments:
+//   - `language` The Tree-sitter [tree_sitter.Language] that should be used
+//     for parsing.
+//   - `tagsQuery` A string containing tree patterns for definitions and
+//     references, typically the contents of a `tags.scm` file.
+//   - `localsQuery` A string containing tree patterns for tracking local
+//     variable definitions. This can be empty if local variable tracking is
+//     not needed.
+func NewTagsConfiguration(language *tree_sitter.Language, tagsQuery string, localsQuery string) (*TagsConfiguration, error) {
+	query, queryErr := tree_sitter.NewQuery(language, localsQuery+tagsQuery)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+
+	tagsQueryOffset := uint(len(localsQuery))
+	var tagsPatternIndex uint
+	for i := uint(0); i < query.PatternCount(); i++ {
+		if query.StartByteForPattern(i) < tagsQueryOffset {
+			tagsPatternIndex++
+		}
+	}
+
+	config := &TagsConfiguration{
+		Language:         language,
+		Query:            query,
+		captureMap:       make(map[uint]namedCapture),
+		tagsPatternIndex: tagsPatternIndex,
+	}
+	for i, name := range query.CaptureNames() {
+		index := uint(i)
+		switch name {
+		case "name":
+			config.nameCaptureIndex = &index
+		case "ignore":
+			config.ignoreCaptureIndex = &index
+		case "doc":
+			config.docCaptureIndex = &index
+		case "local.scope":
+			config.localScopeCaptureIndex = &index
+		case "local.definition":
+			config.localDefinitionCaptureIndex = &index
+		case "local.reference", "":
+		default:
+			var kind string
+			isDefinition := false
+			if strings.HasPrefix(name, "definition.") {
+				isDefinition = true
+				kind = strings.TrimPrefix(name, "definition.")
+			} else if strings.HasPrefix(name, "reference.") {
+				kind = strings.TrimPrefix(name, "reference.")
+			} else {
+				query.Close()
+				return nil, &InvalidCaptureError{Name: name}
+			}
+
+			syntaxTypeId := -1
+			for j, syntaxTypeName := range config.syntaxTypeNames {
+				if syntaxTypeName == kind {
+					syntaxTypeId = j
+					break
+				}
+			}
+			if syntaxTypeId < 0 {
+				syntaxTypeId = len(config.syntaxTypeNames)
+				config.syntaxTypeNames = append(config.syntaxTypeNames, kind)
+			}
+			config.captureMap[index] = namedCapture{syntaxTypeId: uint(syntaxTypeId), isDefinition: isDefinition}
+		}
+	}
+
+	config.patternInfo = make([]patternInfo, query.PatternCount())
+	for i := range config.patternInfo {
+		info := &config.patternInfo[i]
+		patternIndex := uint(i)
+		for _, predicate := range query.PropertyPredicates(patternIndex) {
+			if !predicate.Positive && predicate.Property.Key == "local" {
+				info.nameMustBeNonLocal = true
+			}
+		}
+		info.localScopeInherits = true
+		for _, property := range query.PropertySettings(patternIndex) {
+			if property.Key == "local.scope-inherits" && property.Value != nil && *property.Value == "false" {
+				info.localScopeInherits = false
+			}
+		}
+		if config.docCaptureIndex == nil {
+			continue
+		}
+		for _, predicate := range query.GeneralPredicates(patternIndex) {
+			if len(predicate.Args) < 2 ||
+				predicate.Args[0].CaptureId == nil ||
+				*predicate.Args[0].CaptureId != *config.docCaptureIndex {
+				continue
+			}
+			arg := predicate.Args[1]
+			switch {
+			case predicate.Operator == "select-adjacent!" && arg.CaptureId != nil:
+				info.docsAdjacentCapture = arg.CaptureId
+			case predicate.Operator == "strip!" && arg.String != nil:
+				regex, err := regexp.Compile(*arg.String)
+				if err != nil {
+					query.Close()
+					return nil, err
+				}
+				info.docStripRegex = regex
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// Delete the underlying memory for the configuration's query.
+func (c *TagsConfiguration) Close() {
+	c.Query.Close()
+}
+
+// Get the name of a syntax type, such as `function` or `class`, from its id.
+func (c *TagsConfiguration) SyntaxTypeName(id uint) string {
+	if id < uint(len(c.syntaxTypeNames)) {
+		return c.syntaxTypeNames[id]
+	}
+	return ""
+}
+
+// Create a new tags context.
+func NewTagsContext() *TagsContext {
+	return &TagsContext{
+		Parser: tree_sitter.NewParser(),
+		cursor: tree_sitter.NewQueryCursor(),
+	}
+}
+
+// Delete the underlying memory for the context's parser and query cursor.
+func (t *TagsContext) Close() {
+	t.Parser.Close()
+	t.cursor.Close()
+}
+
+// Iterate over the tags for a given slice of source code.
+//
+// # Arguments:
+//   - `config` The configuration of the language to compute tags for.
+//   - `source` The source code.
+//   - `cancellationFlag` An optional flag that is read atomically during
+//     parsing and tagging. If it is set to a non-zero value, tagging stops
+//     with [ErrCancelled].
+//
+// Along with the tags, this returns whether the syntax tree contains any
+// errors. The returned [TagsIter] must be closed once it is no longer
+// needed, and is only valid until the context is used again.
+func (t *TagsContext) GenerateTags(config *TagsConfiguration, source []byte, cancellationFlag *uintptr) (*TagsIter, bool, error) {
+	if err := t.Parser.SetLanguage(config.Language); err != nil {
+		return nil, false, ErrInvalidLanguage
+	}
+	t.Parser.Reset()
+
+	var options *tree_sitter.ParseOptions
+	if cancellationFlag != nil {
+		options = &tree_sitter.ParseOptions{
+			ProgressCallback: func(tree_sitter.ParseState) bool {
+				return atomic.LoadUintptr(cancellationFlag) != 0
+			},
+		}
+	}
+	tree := t.Parser.ParseWithOptions(func(i int, _ tree_sitter.Point) []byte {
+		if i < len(source) {
+			return source[i:]
+		}
+		return []byte{}
+	}, nil, options)
+	if tree == nil {
+		return nil, false, ErrCancelled
+	}
+
+	root := tree.RootNode()
+	iter := &TagsIter{
+		matches:          t.cursor.Matches(config.Query, root, source),
+		tree:             tree,
+		source:           source,
+		config:           config,
+		cancellationFlag: cancellationFlag,
+		scopes:           []*localScope{{start: 0, end: uint(len(source))}},
+	}
+	return iter, root.HasError(), nil
+}
+
+// Return the next tag in the document.
+//
+// If there are no more tags, it will return `nil`.
+func (it *TagsIter) Next() (*Tag, error) {
+	for {
+		// Periodically check for cancellation, returning `ErrCancelled` if the
+		// cancellation flag was flipped.
+		if it.cancellationFlag != nil {
+			it.iterCount++
+			if it.iterCount >= cancellationCheckInterval {
+				it.iterCount = 0
+				if atomic.LoadUintptr(it.cancellationFlag) != 0 {
+					return nil, ErrCancelled
+				}
+			}
+		}
+
+		// If there is a queued tag for an earlier node in the syntax tree, then pop
+		// it off of the queue and return it.
+		if n := len(it.tagQueue); n > 1 && it.tagQueue[0].tag.NameRange.End < it.tagQueue[n-1].tag.NameRange.Start {
+			entry := it.tagQueue[0]
+			it.tagQueue = it.tagQueue[1:]
+			if entry.ignored {
+				continue
+			}
+			return &entry.tag, nil
+		}
+
+		// Find the next match in the document.
+		match := it.matches.Next()
+		if match == nil {
+			// If there are no more matches, then drain the queue.
+			for len(it.tagQueue) > 0 {
+				entry := it.tagQueue[0]
+				it.tagQueue = it.tagQueue[1:]
+				if !entry.ignored {
+					return &entry.tag, nil
+				}
+			}
+			return nil, nil
+		}
+
+		config := it.config
+		info := &config.patternInfo[match.PatternIndex]
+
+		if match.PatternIndex < config.tagsPatternIndex {
+			for _, capture := range match.Captures {
+				start, end := capture.Node.ByteRange()
+				if isCaptureIndex(config.localScopeCaptureIndex, capture.Index) {
+					it.scopes = append(it.scopes, &localScope{
+						inherits: info.localScopeInherits,
+						start:    start,
+						end:      end,
+					})
+				} else if isCaptureIndex(config.localDefinitionCaptureIndex, capture.Index) {
+					for i := len(it.scopes) - 1; i >= 0; i-- {
+						scope := it.scopes[i]
+						if scope.start <= start && scope.end >= end {
+							scope.localDefs = append(scope.localDefs, string(it.source[start:end]))
+							break
+						}
+					}
+				}
+			}
+			continue
+		}
+
+		var nameNode, tagNode, docsAdjacentNode *tree_sitter.Node
+		var docNodes []tree_sitter.Node
+		var syntaxTypeId uint
+		isDefinition := false
+		isIgnored := false
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+
+			if isCaptureIndex(config.ignoreCaptureIndex, capture.Index) {
+				isIgnored = true
+				nameNode = &node
+			}
+
+			if isCaptureIndex(info.docsAdjacentCapture, capture.Index) {
+				docsAdjacentNode = &node
+			}
+
+			if isCaptureIndex(config.nameCaptureIndex, capture.Index) {
+				nameNode = &node
+			} else if isCaptureIndex(config.docCaptureIndex, capture.Index) {
+				docNodes = append(docNodes, node)
+			}
+
+			if namedCapture, ok := config.captureMap[uint(capture.Index)]; ok {
+				tagNode = &node
+				syntaxTypeId = namedCapture.syntaxTypeId
+				isDefinition = namedCapture.isDefinition
+			}
+		}
+
+		if nameNode == nil {
+			continue
+		}
+
+		nameStart, nameEnd := nameNode.ByteRange()
+		var entry queuedTag
+		if tagNode != nil {
+			if nameNode.HasError() {
+				continue
+			}
+
+			if info.nameMustBeNonLocal && it.isLocal(nameStart, nameEnd) {
+				continue
+			}
+
+			// If needed, filter the doc nodes based on their ranges, selecting
+			// only the slice that are adjacent to some specified node.
+			docsStartIndex := 0
+			if docsAdjacentNode != nil && len(docNodes) > 0 {
+				docsStartIndex = len(docNodes)
+				startRow := docsAdjacentNode.StartPosition().Row
+				for docsStartIndex > 0 {
+					docNode := docNodes[docsStartIndex-1]
+					if docNode.EndPosition().Row+1 >= startRow {
+						docsStartIndex--
+						startRow = docNode.StartPosition().Row
+					} else {
+						break
+					}
+				}
+			}
+
+			// Generate a doc string from all of the doc nodes, applying any strip
+			// regexes.
+			var docs *string
+			for _, docNode := range docNodes[docsStartIndex:] {
+				start, end := docNode.ByteRange()
+				content := it.source[start:end]
+				if !utf8.Valid(content) {
+					continue
+				}
+				text := string(content)
+				if info.docStripRegex != nil {
+					text = info.docStripRegex.ReplaceAllString(text, "")
+				}
+				if docs == nil {
+					docs = &text
+				} else {
+					*docs += "\n" + text
+				}
+			}
+
+			tagStart, tagEnd := tagNode.ByteRange()
+			startPoint := nameNode.StartPosition()
+			endPoint := nameNode.EndPosition()
+
+			// Compute tag properties that depend on the text of the containing line. If
+			// the previous tag occurred on the same line, then reuse results from the
+			// previous tag.
+			var prevUTF16Column uint
+			prevUTF8Byte := nameStart - startPoint.Column
+			var lineRange Range
+			if prev := it.prevLineInfo; prev != nil && prev.utf8Position.Row == startPoint.Row {
+				if prev.utf8Position.Column <= startPoint.Column {
+					prevUTF8Byte = prev.utf8Byte
+					prevUTF16Column = prev.utf16Column
+				}
+				lineRange = prev.lineRange
+			} else {
+				lineRange = computeLineRange(it.source, nameStart, startPoint, maxLineLen)
+			}
+
+			utf16StartColumn := prevUTF16Column + utf16Len(it.source[prevUTF8Byte:nameStart])
+			utf16EndColumn := utf16StartColumn + utf16Len(it.source[nameStart:nameEnd])
+
+			it.prevLineInfo = &lineInfo{
+				utf8Position: endPoint,
+				utf8Byte:     nameEnd,
+				utf16Column:  utf16EndColumn,
+				lineRange:    lineRange,
+			}
+			entry.tag = Tag{
+				Range:            Range{min(tagStart, nameStart), max(tagEnd, nameEnd)},
+				NameRange:        Range{nameStart, nameEnd},
+				LineRange:        lineRange,
+				StartPoint:       startPoint,
+				EndPoint:         endPoint,
+				UTF16ColumnRange: Range{utf16StartColumn, utf16EndColumn},
+				Docs:             docs,
+				IsDefinition:     isDefinition,
+				SyntaxTypeId:     syntaxTypeId,
+			}
+		} else if isIgnored {
+			entry.tag = Tag{NameRange: Range{nameStart, nameEnd}}
+			entry.ignored = true
+		} else {
+			continue
+		}
+		entry.patternIndex = match.PatternIndex
+
+		// Only create one tag per node. The tag queue is sorted by node position
+		// to allow for fast lookup.
+		i := sort.Search(len(it.tagQueue), func(i int) bool {
+			other := it.tagQueue[i].tag.NameRange
+			return other.End > nameEnd || (other.End == nameEnd && other.Start >= nameStart)
+		})
+		if i < len(it.tagQueue) && it.tagQueue[i].tag.NameRange == entry.tag.NameRange {
+			if it.tagQueue[i].patternIndex > entry.patternIndex {
+				it.tagQueue[i] = entry
+			}
+		} else {
+			it.tagQueue = append(it.tagQueue, queuedTag{})
+			copy(it.tagQueue[i+1:], it.tagQueue[i:])
+			it.tagQueue[i] = entry
+		}
+	}
+}
+
+// Delete the underlying memory for the document's syntax tree.
+func (it *TagsIter) Close() {
+	it.tree.Close()
+}
+
+// Get the text of the tag's name.
+func (t *Tag) Name(source []byte) string {
+	return string(source[t.NameRange.Start:t.NameRange.End])
+}
+
+// Get the text of the line containing the tag's name.
+func (t *Tag) LineText(source []byte) string {
+	return string(source[t.LineRange.Start:t.LineRange.End])
+}
+
+func (it *TagsIter) isLocal(start, end uint) bool {
+	name := string(it.source[start:end])
+	for i := len(it.scopes) - 1; i >= 0; i-- {
+		scope := it.scopes[i]
+		if scope.start <= start && scope.end >= end {
+			for _, def := range scope.localDefs {
+				if def == name {
+					return true
+				}
+			}
+			if !scope.inherits {
+				break
+			}
+		}
+	}
+	return false
+}
+
+func computeLineRange(text []byte, startByte uint, startPoint tree_sitter.Point, maxLineLen uint) Range {
+	// Trim leading whitespace
+	lineStartByte := startByte - startPoint.Column
+	for lineStartByte < uint(len(text)) && isASCIIWhitespace(text[lineStartByte]) {
+		lineStartByte++
+	}
+
+	maxLineLen = min(maxLineLen, uint(len(text))-lineStartByte)
+	textAfterLineStart := text[lineStartByte : lineStartByte+maxLineLen]
+	var lineLen uint
+	if i := strings.IndexByte(string(textAfterLineStart), '\n'); i >= 0 {
+		lineLen = uint(i)
+	} else {
+		lineLen = validUTF8Prefix(textAfterLineStart)
+	}
+
+	// Trim trailing whitespace
+	lineEndByte := lineStartByte + lineLen
+	for lineEndByte > lineStartByte && isASCIIWhitespace(text[lineEndByte-1]) {
+		lineEndByte--
+	}
+
+	return Range{lineStartByte, lineEndByte}
+}
+
+func validUTF8Prefix(text []byte) uint {
+	var length uint
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		if r == utf8.RuneError && size <= 1 {
+			break
+		}
+		length += uint(size)
+		text = text[size:]
+	}
+	return length
+}
+
+func utf16Len(text []byte) uint {
+	var length uint
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if n := utf16.RuneLen(r); n > 0 {
+			length += uint(n)
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+func isASCIIWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+func isCaptureIndex(index *uint, captureIndex uint32) bool {
+	return index != nil && *index == uint(captureIndex)
+}
+
+func (e *InvalidCaptureError) Error() string {
+	return fmt.Sprintf("Invalid capture @%s. Expected one of: @definition.*, @reference.*, @doc, @name, @local.(scope|definition|reference).", e.Name)
+}
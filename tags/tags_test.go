@@ -0,0 +1,291 @@
+package tags_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	. "github.com/tree-sitter/go-tree-sitter/tags"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+const pythonTagQuery = `
+(
+  (function_definition
+    name: (identifier) @name
+    body: (block . (expression_statement (string) @doc))) @definition.function
+  (#strip! @doc "(^['\"\\s]*)|(['\"\\s]*$)")
+)
+
+(function_definition
+  name: (identifier) @name) @definition.function
+
+(
+  (class_definition
+    name: (identifier) @name
+    body: (block
+      . (expression_statement (string) @doc))) @definition.class
+  (#strip! @doc "(^['\"\\s]*)|(['\"\\s]*$)")
+)
+
+(class_definition
+  name: (identifier) @name) @definition.class
+
+(call
+  function: (identifier) @name) @reference.call
+
+(call
+  function: (attribute
+    attribute: (identifier) @name)) @reference.call
+`
+
+const javascriptTagQuery = `
+(
+  (comment)* @doc .
+  (method_definition
+    name: (property_identifier) @name) @definition.method
+  (#select-adjacent! @doc @definition.method)
+  (#strip! @doc "(^[/\\*\\s]*)|([/\\*\\s]*$)")
+)
+
+(
+  (comment)* @doc .
+  (class_declaration
+    name: (identifier) @name) @definition.class
+  (#select-adjacent! @doc @definition.class)
+  (#strip! @doc "(^[/\\*\\s]*)|([/\\*\\s]*$)")
+)
+
+(
+  (comment)* @doc .
+  (function_declaration
+    name: (identifier) @name) @definition.function
+  (#select-adjacent! @doc @definition.function)
+  (#strip! @doc "(^[/\\*\\s]*)|([/\\*\\s]*$)")
+)
+
+((call_expression
+  function: (identifier) @ignore)
+  (#eq? @ignore "require"))
+
+(call_expression
+  function: (identifier) @name) @reference.call
+`
+
+const javascriptLocalsQuery = `
+(function_declaration) @local.scope
+(formal_parameters (identifier) @local.definition)
+`
+
+const javascriptLocalReferenceQuery = `
+((call_expression
+  function: (identifier) @name) @reference.call
+  (#is-not? local))
+`
+
+type simpleTag struct {
+	name string
+	kind string
+	docs string
+}
+
+func collectTags(t *testing.T, config *TagsConfiguration, source string) ([]Tag, []simpleTag) {
+	context := NewTagsContext()
+	defer context.Close()
+
+	iter, hasError, err := context.GenerateTags(config, []byte(source), nil)
+	assert.Nil(t, err)
+	assert.False(t, hasError)
+	defer iter.Close()
+
+	var tags []Tag
+	var simple []simpleTag
+	for {
+		tag, err := iter.Next()
+		assert.Nil(t, err)
+		if tag == nil {
+			break
+		}
+		tags = append(tags, *tag)
+		kind := config.SyntaxTypeName(tag.SyntaxTypeId)
+		if tag.IsDefinition {
+			kind = "definition." + kind
+		} else {
+			kind = "reference." + kind
+		}
+		var docs string
+		if tag.Docs != nil {
+			docs = *tag.Docs
+		}
+		simple = append(simple, simpleTag{tag.Name([]byte(source)), kind, docs})
+	}
+	return tags, simple
+}
+
+func TestTagsPython(t *testing.T) {
+	config, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_python.Language()),
+		pythonTagQuery,
+		"",
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	source := `class Customer:
+    """
+    Data about a customer
+    """
+
+    def age(self):
+        '''
+        Get the customer's age
+        '''
+        compute_age(self.id)
+`
+	tags, simple := collectTags(t, config, source)
+	assert.Equal(
+		t,
+		[]simpleTag{
+			{"Customer", "definition.class", "Data about a customer"},
+			{"age", "definition.function", "Get the customer's age"},
+			{"compute_age", "reference.call", ""},
+		},
+		simple,
+	)
+
+	assert.Equal(t, "class Customer:", tags[0].LineText([]byte(source)))
+	assert.Equal(t, "def age(self):", tags[1].LineText([]byte(source)))
+	assert.Equal(t, "compute_age(self.id)", tags[2].LineText([]byte(source)))
+	assert.Equal(t, tree_sitter.NewPoint(5, 8), tags[1].StartPoint)
+	assert.Equal(t, tree_sitter.NewPoint(5, 11), tags[1].EndPoint)
+	assert.Equal(t, Range{Start: 8, End: 11}, tags[1].UTF16ColumnRange)
+}
+
+func TestTagsJavascript(t *testing.T) {
+	config, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		javascriptTagQuery,
+		"",
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	source := `
+// hi
+
+// Data about a customer.
+// bla bla bla
+class Customer {
+  /*
+   * Get the customer's age
+   */
+  getAge() {
+  }
+}
+
+// ok
+
+class Agent {
+
+}
+
+const fs = require("fs");
+`
+	_, simple := collectTags(t, config, source)
+	assert.Equal(
+		t,
+		[]simpleTag{
+			{"Customer", "definition.class", "Data about a customer.\nbla bla bla"},
+			{"getAge", "definition.method", "Get the customer's age"},
+			{"Agent", "definition.class", ""},
+		},
+		simple,
+	)
+}
+
+func TestTagsWithLocals(t *testing.T) {
+	config, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		javascriptLocalReferenceQuery,
+		javascriptLocalsQuery,
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	source := "function f(callback) { callback(); other(); }\nother();\n"
+	tags, simple := collectTags(t, config, source)
+	assert.Equal(
+		t,
+		[]simpleTag{
+			{"other", "reference.call", ""},
+			{"other", "reference.call", ""},
+		},
+		simple,
+	)
+	assert.Equal(t, Range{Start: 35, End: 40}, tags[0].NameRange)
+	assert.Equal(t, Range{Start: 35, End: 42}, tags[0].Range)
+	assert.Equal(t, Range{Start: 0, End: 45}, tags[0].LineRange)
+}
+
+func TestTagsUTF16Columns(t *testing.T) {
+	config, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		javascriptTagQuery,
+		"",
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	source := "'😀'; a(); b();"
+	tags, _ := collectTags(t, config, source)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, Range{Start: 6, End: 7}, tags[0].UTF16ColumnRange)
+	assert.Equal(t, Range{Start: 11, End: 12}, tags[1].UTF16ColumnRange)
+}
+
+func TestTagsCancellation(t *testing.T) {
+	config, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		javascriptTagQuery,
+		"",
+	)
+	assert.Nil(t, err)
+	defer config.Close()
+
+	context := NewTagsContext()
+	defer context.Close()
+
+	source := make([]byte, 0, 20000)
+	for i := 0; i < 1000; i++ {
+		source = append(source, "a(); b();\n"...)
+	}
+	cancellationFlag := uintptr(0)
+	iter, _, err := context.GenerateTags(config, source, &cancellationFlag)
+	assert.Nil(t, err)
+	defer iter.Close()
+
+	for i := 0; ; i++ {
+		if i == 150 {
+			cancellationFlag = 1
+		}
+		tag, err := iter.Next()
+		if err != nil {
+			assert.ErrorIs(t, err, ErrCancelled)
+			return
+		}
+		if tag == nil {
+			break
+		}
+	}
+	t.Fatal("tagging was not cancelled")
+}
+
+func TestTagsInvalidCapture(t *testing.T) {
+	_, err := NewTagsConfiguration(
+		tree_sitter.NewLanguage(tree_sitter_javascript.Language()),
+		"(identifier) @method",
+		"",
+	)
+	assert.Equal(t, &InvalidCaptureError{Name: "method"}, err)
+}
@@ -13,12 +13,13 @@ import (
 // A stateful object that this is used to produce a [Tree] based on some
 // source code.
 type Tree struct {
-	_inner *C.TSTree
+	_inner  *C.TSTree
+	cleanup *cleanup
 }
 
 // Create a new tree from a raw pointer.
 func newTree(inner *C.TSTree) *Tree {
-	return &Tree{_inner: inner}
+	return &Tree{_inner: inner, cleanup: addLeakReport("Tree")}
 }
 
 // Get the root node of the syntax tree.
@@ -111,8 +112,10 @@ func (t *Tree) PrintDotGraph(file int) {
 }
 
 func (t *Tree) Close() {
-	if t != nil {
+	if t != nil && t._inner != nil {
+		t.cleanup.stop()
 		C.ts_tree_delete(t._inner)
+		t._inner = nil
 	}
 }
 
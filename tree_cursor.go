@@ -8,48 +8,65 @@ import "C"
 
 // A stateful object for walking a syntax [Tree] efficiently.
 type TreeCursor struct {
-	_inner C.TSTreeCursor
+	// The cursor is kept in its own allocation, so that it can be released
+	// after the [TreeCursor] has been garbage collected.
+	_inner  *C.TSTreeCursor
+	cleanup *cleanup
 }
 
 func newTreeCursor(node Node) *TreeCursor {
-	return &TreeCursor{_inner: C.ts_tree_cursor_new(node._inner)}
+	return wrapTreeCursor(C.ts_tree_cursor_new(node._inner))
+}
+
+func wrapTreeCursor(cursor C.TSTreeCursor) *TreeCursor {
+	inner := new(C.TSTreeCursor)
+	*inner = cursor
+	tc := &TreeCursor{_inner: inner}
+	tc.cleanup = addCleanup("TreeCursor", func(inner *C.TSTreeCursor) {
+		C.ts_tree_cursor_delete(inner)
+	}, inner)
+	return tc
 }
 
 func (tc *TreeCursor) Close() {
-	C.ts_tree_cursor_delete(&tc._inner)
+	if tc._inner != nil {
+		tc.cleanup.stop()
+		C.ts_tree_cursor_delete(tc._inner)
+		tc._inner = nil
+	}
 }
 
 func (tc *TreeCursor) Copy() *TreeCursor {
-	return &TreeCursor{_inner: C.ts_tree_cursor_copy(&tc._inner)}
+	return wrapTreeCursor(C.ts_tree_cursor_copy(tc._inner))
 }
 
 // Get the tree cursor's current [Node].
 func (tc *TreeCursor) Node() *Node {
-	return newNode(C.ts_tree_cursor_current_node(&tc._inner))
+	return newNode(C.ts_tree_cursor_current_node(tc._inner))
 }
 
 // Get the numerical field id of this tree cursor's current node.
 //
 // See also [TreeCursor.FieldName].
 func (tc *TreeCursor) FieldId() uint16 {
-	return uint16(C.ts_tree_cursor_current_field_id(&tc._inner))
+	return uint16(C.ts_tree_cursor_current_field_id(tc._inner))
 }
 
 // Get the field name of this tree cursor's current node.
 func (tc *TreeCursor) FieldName() string {
-	return C.GoString(C.ts_tree_cursor_current_field_name(&tc._inner))
+	return C.GoString(C.ts_tree_cursor_current_field_name(tc._inner))
 }
 
 // Get the depth of the cursor's current node relative to the original
 // node that the cursor was constructed with.
 func (tc *TreeCursor) Depth() uint32 {
-	return uint32(C.ts_tree_cursor_current_depth(&tc._inner))
+	return uint32(C.ts_tree_cursor_current_depth(tc._inner))
 }
 
 // Get the index of the cursor's current node out of all of the
 // descendants of the original node that the cursor was constructed with.
 func (tc *TreeCursor) DescendantIndex() uint32 {
-	return uint32(C.ts_tree_cursor_current_descendant_index(&tc._inner))
+	return uint32(C.ts_tree_cursor_current_descendant_index(tc._inner))
 }
 
 // Move this cursor to the first child of its current node.
@@ -57,7 +74,7 @@ func (tc *TreeCursor) DescendantIndex() uint32 {
 // This returns `true` if the cursor successfully moved, and returns
 // `false` if there were no children.
 func (tc *TreeCursor) GotoFirstChild() bool {
-	return bool(C.ts_tree_cursor_goto_first_child(&tc._inner))
+	return bool(C.ts_tree_cursor_goto_first_child(tc._inner))
 }
 
 // Move this cursor to the last child of its current node.
@@ -69,7 +86,7 @@ func (tc *TreeCursor) GotoFirstChild() bool {
 // [TreeCursor.GotoFirstChild] because it needs to
 // iterate through all the children to compute the child's position.
 func (tc *TreeCursor) GotoLastChild() bool {
-	return bool(C.ts_tree_cursor_goto_last_child(&tc._inner))
+	return bool(C.ts_tree_cursor_goto_last_child(tc._inner))
 }
 
 // Move this cursor to the parent of its current node.
@@ -81,7 +98,7 @@ func (tc *TreeCursor) GotoLastChild() bool {
 // Note that the given node is considered the root of the cursor,
 // and the cursor cannot walk outside this node.
 func (tc *TreeCursor) GotoParent() bool {
-	return bool(C.ts_tree_cursor_goto_parent(&tc._inner))
+	return bool(C.ts_tree_cursor_goto_parent(tc._inner))
 }
 
 // Move this cursor to the next sibling of its current node.
@@ -92,14 +109,14 @@ func (tc *TreeCursor) GotoParent() bool {
 // Note that the given node is considered the root of the cursor,
 // and the cursor cannot walk outside this node.
 func (tc *TreeCursor) GotoNextSibling() bool {
-	return bool(C.ts_tree_cursor_goto_next_sibling(&tc._inner))
+	return bool(C.ts_tree_cursor_goto_next_sibling(tc._inner))
 }
 
 // Move the cursor to the node that is the nth descendant of
 // the original node that the cursor was constructed with, where
 // zero represents the original node itself.
 func (tc *TreeCursor) GotoDescendant(descendantIndex uint32) {
-	C.ts_tree_cursor_goto_descendant(&tc._inner, C.uint32_t(descendantIndex))
+	C.ts_tree_cursor_goto_descendant(tc._inner, C.uint32_t(descendantIndex))
 }
 
 // Move this cursor to the previous sibling of its current node.
@@ -115,7 +132,7 @@ func (tc *TreeCursor) GotoDescendant(descendantIndex uint32) {
 // is considered the root of the cursor, and the cursor cannot
 // walk outside this node.
 func (tc *TreeCursor) GotoPreviousSibling() bool {
-	return bool(C.ts_tree_cursor_goto_previous_sibling(&tc._inner))
+	return bool(C.ts_tree_cursor_goto_previous_sibling(tc._inner))
 }
 
 // Move this cursor to the first child of its current node that extends
@@ -124,7 +141,7 @@ func (tc *TreeCursor) GotoPreviousSibling() bool {
 // This returns the index of the child node if one was found, and returns
 // `nil` if no such child was found.
 func (tc *TreeCursor) GotoFirstChildForByte(byteIndex uint32) *uint {
-	res := C.ts_tree_cursor_goto_first_child_for_byte(&tc._inner, C.uint32_t(byteIndex))
+	res := C.ts_tree_cursor_goto_first_child_for_byte(tc._inner, C.uint32_t(byteIndex))
 	if res < 0 {
 		return nil
 	}
@@ -138,7 +155,7 @@ func (tc *TreeCursor) GotoFirstChildForByte(byteIndex uint32) *uint {
 // This returns the index of the child node if one was found, and returns
 // `nil` if no such child was found.
 func (tc *TreeCursor) GotoFirstChildForPoint(point Point) *uint {
-	res := C.ts_tree_cursor_goto_first_child_for_point(&tc._inner, point.toTSPoint())
+	res := C.ts_tree_cursor_goto_first_child_for_point(tc._inner, point.toTSPoint())
 	if res < 0 {
 		return nil
 	}
@@ -149,7 +166,7 @@ func (tc *TreeCursor) GotoFirstChildForPoint(point Point) *uint {
 // Re-initialize this tree cursor to start at the original node that the
 // cursor was constructed with.
 func (tc *TreeCursor) Reset(node Node) {
-	C.ts_tree_cursor_reset(&tc._inner, node._inner)
+	C.ts_tree_cursor_reset(tc._inner, node._inner)
 }
 
 // Re-initialize a tree cursor to the same position as another cursor.
@@ -157,5 +174,5 @@ func (tc *TreeCursor) Reset(node Node) {
 // Unlike [TreeCursor.Reset], this will not lose parent
 // information and allows reusing already created cursors.
 func (tc *TreeCursor) ResetTo(cursor *TreeCursor) {
-	C.ts_tree_cursor_reset_to(&tc._inner, &cursor._inner)
+	C.ts_tree_cursor_reset_to(tc._inner, cursor._inner)
 }
@@ -0,0 +1,726 @@
+package tree_sitter
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// The default minimum height of the subtrees that are matched by their
+// structure alone. Smaller subtrees, such as single identifiers, are too
+// common to be matched without looking at their surroundings.
+const defaultDiffMinHeight = 2
+
+// The default minimum similarity of two nodes that are matched by their
+// common descendants.
+const defaultDiffMinSimilarity = 0.5
+
+// The kind of a [DiffOperation].
+type DiffOperationKind int
+
+const (
+	// A node that only exists in the new tree.
+	DiffInsert DiffOperationKind = iota
+
+	// A node that only exists in the old tree.
+	DiffDelete
+
+	// A leaf node whose text has changed.
+	DiffUpdate
+
+	// A node that has a different parent in the new tree, or that has been
+	// reordered among its siblings.
+	DiffMove
+)
+
+// Options for [Tree.Diff].
+type DiffOptions struct {
+	// Leave out *extra* nodes, such as comments, so that changes to them are
+	// not reported.
+	IgnoreExtras bool
+
+	// The minimum height of the subtrees that are matched by their structure
+	// alone, where a leaf node has a height of 1. Defaults to 2.
+	MinHeight uint
+
+	// The minimum ratio of common descendants, between 0 and 1, for two nodes
+	// of the same kind to be matched. Defaults to 0.5.
+	MinSimilarity float64
+}
+
+// A node-level change between two syntax trees.
+type DiffOperation struct {
+	Kind DiffOperationKind
+
+	// The node in the old tree. It is not set for insertions.
+	OldNode SnapshotNode
+
+	// The node in the new tree. It is not set for deletions.
+	NewNode SnapshotNode
+}
+
+// The structural difference between two syntax trees, as returned by
+// [Tree.Diff].
+type TreeDiff struct {
+	// Snapshots of the old and the new tree, which the nodes of the
+	// operations belong to.
+	OldSnapshot *Snapshot
+	NewSnapshot *Snapshot
+
+	// The operations that turn the old tree into the new one. Deletions come
+	// first, in the order of the old tree, followed by the other operations
+	// in the order of the new tree.
+	//
+	// An inserted or deleted subtree is reported once, at its root. A node
+	// that was both moved and updated is reported twice.
+	Operations []DiffOperation
+
+	oldToNew []int32
+	newToOld []int32
+}
+
+// Compute the structural difference between this syntax tree and another
+// one that was parsed with the same language.
+//
+// Unlike [Tree.ChangedRanges], the trees don't need to be related by edits.
+// Nodes are matched with the GumTree algorithm: first, identical subtrees
+// are matched from the largest to the smallest, and then the remaining nodes
+// are matched with nodes of the same kind that have the most matched
+// descendants in common. The unmatched nodes are reported as insertions and
+// deletions, and the matched ones as updates and moves.
+//
+// The source code of both trees is needed to tell whether leaf nodes have
+// changed. If `options` is `nil`, the defaults are used.
+func (t *Tree) Diff(source []byte, other *Tree, otherSource []byte, options *DiffOptions) (*TreeDiff, error) {
+	if t.Language().Inner != other.Language().Inner {
+		return nil, errors.New("Cannot diff trees that were parsed with different languages")
+	}
+
+	var opts DiffOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.MinHeight == 0 {
+		opts.MinHeight = defaultDiffMinHeight
+	}
+	if opts.MinSimilarity == 0 {
+		opts.MinSimilarity = defaultDiffMinSimilarity
+	}
+
+	oldSnapshot := t.Snapshot()
+	newSnapshot := other.Snapshot()
+	m := &diffMatcher{
+		old:     newDiffTree(oldSnapshot.RootNode(), source, opts.IgnoreExtras),
+		new:     newDiffTree(newSnapshot.RootNode(), otherSource, opts.IgnoreExtras),
+		options: opts,
+	}
+	m.oldToNew = filledInts(len(m.old.nodes), -1)
+	m.newToOld = filledInts(len(m.new.nodes), -1)
+	m.matchTopDown()
+	m.matchBottomUp()
+
+	diff := &TreeDiff{
+		OldSnapshot: oldSnapshot,
+		NewSnapshot: newSnapshot,
+		Operations:  m.operations(),
+		oldToNew:    make([]int32, oldSnapshot.NodeCount()),
+		newToOld:    make([]int32, newSnapshot.NodeCount()),
+	}
+	for i := range diff.oldToNew {
+		diff.oldToNew[i] = -1
+	}
+	for i := range diff.newToOld {
+		diff.newToOld[i] = -1
+	}
+	for i, j := range m.oldToNew {
+		if j >= 0 {
+			oldIndex, newIndex := m.old.nodes[i].node.index, m.new.nodes[j].node.index
+			diff.oldToNew[oldIndex] = int32(newIndex)
+			diff.newToOld[newIndex] = int32(oldIndex)
+		}
+	}
+	return diff, nil
+}
+
+// Get the node in the new tree that the given node of the old tree was
+// matched with.
+func (d *TreeDiff) NewNodeFor(oldNode SnapshotNode) (SnapshotNode, bool) {
+	if oldNode.snapshot != d.OldSnapshot || d.oldToNew[oldNode.index] < 0 {
+		return SnapshotNode{}, false
+	}
+	return SnapshotNode{snapshot: d.NewSnapshot, index: uint32(d.oldToNew[oldNode.index])}, true
+}
+
+// Get the node in the old tree that the given node of the new tree was
+// matched with.
+func (d *TreeDiff) OldNodeFor(newNode SnapshotNode) (SnapshotNode, bool) {
+	if newNode.snapshot != d.NewSnapshot || d.newToOld[newNode.index] < 0 {
+		return SnapshotNode{}, false
+	}
+	return SnapshotNode{snapshot: d.OldSnapshot, index: uint32(d.newToOld[newNode.index])}, true
+}
+
+func (k DiffOperationKind) String() string {
+	switch k {
+	case DiffInsert:
+		return "insert"
+	case DiffDelete:
+		return "delete"
+	case DiffUpdate:
+		return "update"
+	case DiffMove:
+		return "move"
+	default:
+		return fmt.Sprintf("DiffOperationKind(%d)", int(k))
+	}
+}
+
+// Format the operation with the kind of its node and one-based rows and
+// columns, e.g. `move function_declaration from 1:1 to 7:1`.
+func (o DiffOperation) String() string {
+	switch o.Kind {
+	case DiffInsert:
+		return fmt.Sprintf("insert %s at %s", o.NewNode.Kind(), formatDiffPosition(o.NewNode))
+	case DiffDelete:
+		return fmt.Sprintf("delete %s at %s", o.OldNode.Kind(), formatDiffPosition(o.OldNode))
+	default:
+		return fmt.Sprintf(
+			"%s %s from %s to %s",
+			o.Kind,
+			o.OldNode.Kind(),
+			formatDiffPosition(o.OldNode),
+			formatDiffPosition(o.NewNode),
+		)
+	}
+}
+
+func formatDiffPosition(node SnapshotNode) string {
+	position := node.StartPosition()
+	return fmt.Sprintf("%d:%d", position.Row+1, position.Column+1)
+}
+
+// A syntax tree in the form that the diff algorithm works on, with its nodes
+// in preorder, so that the descendants of a node directly follow it.
+type diffTree struct {
+	nodes []diffNode
+}
+
+type diffNode struct {
+	node     SnapshotNode
+	parent   int
+	children []int
+
+	// The text of a leaf node, which is empty for other nodes.
+	label string
+
+	// A hash of the node's kind, its label and its children's hashes, which
+	// is equal for isomorphic subtrees.
+	hash   uint64
+	height int
+	size   int
+}
+
+func newDiffTree(root SnapshotNode, source []byte, ignoreExtras bool) *diffTree {
+	t := &diffTree{}
+	t.add(root, -1, source, ignoreExtras)
+	return t
+}
+
+func (t *diffTree) add(node SnapshotNode, parent int, source []byte, ignoreExtras bool) int {
+	index := len(t.nodes)
+	t.nodes = append(t.nodes, diffNode{node: node, parent: parent})
+
+	height := 0
+	for child := range node.ChildrenSeq() {
+		if ignoreExtras && child.IsExtra() {
+			continue
+		}
+		childIndex := t.add(child, index, source, ignoreExtras)
+		t.nodes[index].children = append(t.nodes[index].children, childIndex)
+		height = max(height, t.nodes[childIndex].height)
+	}
+
+	n := &t.nodes[index]
+	n.height = height + 1
+	n.size = len(t.nodes) - index
+	if node.ChildCount() == 0 && node.EndByte() <= uint(len(source)) {
+		n.label = node.Utf8Text(source)
+	}
+
+	h := fnv.New64a()
+	var buffer [8]byte
+	binary.LittleEndian.PutUint16(buffer[:], node.KindId())
+	h.Write(buffer[:2])
+	h.Write([]byte(n.label))
+	for _, child := range n.children {
+		binary.LittleEndian.PutUint64(buffer[:], t.nodes[child].hash)
+		h.Write(buffer[:])
+	}
+	n.hash = h.Sum64()
+	return index
+}
+
+func (t *diffTree) isDescendant(node, ancestor int) bool {
+	return node > ancestor && node < ancestor+t.nodes[ancestor].size
+}
+
+func (t *diffTree) postorder() []int {
+	result := make([]int, 0, len(t.nodes))
+	var visit func(int)
+	visit = func(i int) {
+		for _, child := range t.nodes[i].children {
+			visit(child)
+		}
+		result = append(result, i)
+	}
+	visit(0)
+	return result
+}
+
+type diffMatcher struct {
+	old, new *diffTree
+	options  DiffOptions
+
+	// The index of each node's match in the other tree, or -1.
+	oldToNew []int
+	newToOld []int
+}
+
+func (m *diffMatcher) match(i, j int) {
+	m.oldToNew[i] = j
+	m.newToOld[j] = i
+}
+
+func (m *diffMatcher) matchSubtrees(i, j int) {
+	// Isomorphic subtrees have the same shape, so their nodes line up in
+	// preorder.
+	for k := 0; k < m.old.nodes[i].size; k++ {
+		m.match(i+k, j+k)
+	}
+}
+
+func (m *diffMatcher) isomorphic(i, j int) bool {
+	a, b := &m.old.nodes[i], &m.new.nodes[j]
+	if a.hash != b.hash || a.size != b.size {
+		return false
+	}
+	for k := 0; k < a.size; k++ {
+		x, y := &m.old.nodes[i+k], &m.new.nodes[j+k]
+		if x.node.KindId() != y.node.KindId() || x.label != y.label || len(x.children) != len(y.children) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *diffMatcher) sameKind(i, j int) bool {
+	return m.old.nodes[i].node.KindId() == m.new.nodes[j].node.KindId()
+}
+
+func (m *diffMatcher) sameKindAndLabel(i, j int) bool {
+	return m.sameKind(i, j) && m.old.nodes[i].label == m.new.nodes[j].label
+}
+
+// The ratio of the descendants of two nodes that are matched with each
+// other, between 0 and 1.
+func (m *diffMatcher) similarity(i, j int, matched []int) float64 {
+	if i < 0 || j < 0 {
+		return 0
+	}
+	oldSize, newSize := m.old.nodes[i].size-1, m.new.nodes[j].size-1
+	if oldSize+newSize == 0 {
+		return 0
+	}
+	// The descendants of `j` are the nodes after it in preorder, up to its
+	// size.
+	common := sort.SearchInts(matched, j+newSize+1) - sort.SearchInts(matched, j+1)
+	return 2 * float64(common) / float64(oldSize+newSize)
+}
+
+// Get the sorted indices of the new nodes that the descendants of the old
+// node `i` are matched with, for computing its [diffMatcher.similarity] with
+// several nodes.
+func (m *diffMatcher) matchedDescendants(i int) []int {
+	if i < 0 {
+		return nil
+	}
+	var matched []int
+	for k := i + 1; k < i+m.old.nodes[i].size; k++ {
+		if match := m.oldToNew[k]; match >= 0 {
+			matched = append(matched, match)
+		}
+	}
+	sort.Ints(matched)
+	return matched
+}
+
+// Match identical subtrees, from the highest to the lowest.
+func (m *diffMatcher) matchTopDown() {
+	minHeight := int(m.options.MinHeight)
+	oldQueue := &diffHeightQueue{tree: m.old}
+	newQueue := &diffHeightQueue{tree: m.new}
+	heap.Push(oldQueue, 0)
+	heap.Push(newQueue, 0)
+
+	// Pairs of isomorphic subtrees that have other isomorphic candidates,
+	// which are matched after all of the unambiguous pairs.
+	var candidates [][2]int
+	inCandidates := make(map[int]bool)
+	for {
+		oldHeight, newHeight := oldQueue.peekHeight(), newQueue.peekHeight()
+		if min(oldHeight, newHeight) < minHeight {
+			break
+		}
+		if oldHeight != newHeight {
+			if oldHeight > newHeight {
+				for _, i := range oldQueue.popHeight(oldHeight) {
+					oldQueue.open(i)
+				}
+			} else {
+				for _, j := range newQueue.popHeight(newHeight) {
+					newQueue.open(j)
+				}
+			}
+			continue
+		}
+
+		oldNodes, newNodes := oldQueue.popHeight(oldHeight), newQueue.popHeight(newHeight)
+		newByHash := make(map[uint64][]int)
+		for _, j := range newNodes {
+			hash := m.new.nodes[j].hash
+			newByHash[hash] = append(newByHash[hash], j)
+		}
+		oldByHash := make(map[uint64][]int)
+		for _, i := range oldNodes {
+			hash := m.old.nodes[i].hash
+			oldByHash[hash] = append(oldByHash[hash], i)
+		}
+		for _, i := range oldNodes {
+			sameHash := newByHash[m.old.nodes[i].hash]
+			if len(sameHash) == 1 && len(oldByHash[m.old.nodes[i].hash]) == 1 {
+				if m.isomorphic(i, sameHash[0]) {
+					m.matchSubtrees(i, sameHash[0])
+				}
+				continue
+			}
+			for _, j := range sameHash {
+				if m.isomorphic(i, j) {
+					candidates = append(candidates, [2]int{i, j})
+					inCandidates[i] = true
+					inCandidates[-j-1] = true
+				}
+			}
+		}
+		for _, i := range oldNodes {
+			if m.oldToNew[i] < 0 && !inCandidates[i] {
+				oldQueue.open(i)
+			}
+		}
+		for _, j := range newNodes {
+			if m.newToOld[j] < 0 && !inCandidates[-j-1] {
+				newQueue.open(j)
+			}
+		}
+	}
+
+	// Prefer the candidates whose parents are most similar.
+	similarities := make([]float64, len(candidates))
+	matched := make(map[int][]int)
+	for k, candidate := range candidates {
+		i, j := m.old.nodes[candidate[0]].parent, m.new.nodes[candidate[1]].parent
+		if _, ok := matched[i]; !ok {
+			matched[i] = m.matchedDescendants(i)
+		}
+		similarities[k] = m.similarity(i, j, matched[i])
+	}
+	order := make([]int, len(candidates))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return similarities[order[a]] > similarities[order[b]]
+	})
+	for _, k := range order {
+		i, j := candidates[k][0], candidates[k][1]
+		if m.oldToNew[i] < 0 && m.newToOld[j] < 0 {
+			m.matchSubtrees(i, j)
+		}
+	}
+}
+
+// Match the remaining nodes with nodes of the same kind that have enough
+// matched descendants in common, and then try to match the unmatched
+// descendants of each new pair.
+func (m *diffMatcher) matchBottomUp() {
+	for _, i := range m.old.postorder() {
+		if i == 0 {
+			if m.oldToNew[0] < 0 && m.newToOld[0] < 0 && m.sameKind(0, 0) {
+				m.match(0, 0)
+				m.recover(0, 0)
+			}
+			continue
+		}
+		if m.oldToNew[i] >= 0 || len(m.old.nodes[i].children) == 0 {
+			continue
+		}
+
+		best, bestSimilarity := -1, 0.0
+		matched := m.matchedDescendants(i)
+		seen := make(map[int]bool)
+		for k := i + 1; k < i+m.old.nodes[i].size; k++ {
+			match := m.oldToNew[k]
+			if match < 0 {
+				continue
+			}
+			for j := m.new.nodes[match].parent; j >= 0 && !seen[j]; j = m.new.nodes[j].parent {
+				seen[j] = true
+				if m.newToOld[j] >= 0 || !m.sameKind(i, j) {
+					continue
+				}
+				if similarity := m.similarity(i, j, matched); similarity > bestSimilarity {
+					best, bestSimilarity = j, similarity
+				}
+			}
+		}
+		if best >= 0 && bestSimilarity >= m.options.MinSimilarity {
+			m.match(i, best)
+			m.recover(i, best)
+		}
+	}
+}
+
+// Match the unmatched children of two matched nodes: first isomorphic
+// subtrees, then nodes with the same kind and text, and then nodes with the
+// same kind, keeping their order in both trees.
+func (m *diffMatcher) recover(i, j int) {
+	for pass, equal := range []func(int, int) bool{m.isomorphic, m.sameKindAndLabel, m.sameKind} {
+		var oldChildren, newChildren []int
+		for _, child := range m.old.nodes[i].children {
+			if m.oldToNew[child] < 0 {
+				oldChildren = append(oldChildren, child)
+			}
+		}
+		for _, child := range m.new.nodes[j].children {
+			if m.newToOld[child] < 0 {
+				newChildren = append(newChildren, child)
+			}
+		}
+		for _, pair := range longestCommonSubsequence(oldChildren, newChildren, equal) {
+			if pass == 0 {
+				m.matchSubtrees(pair[0], pair[1])
+			} else {
+				m.match(pair[0], pair[1])
+				m.recover(pair[0], pair[1])
+			}
+		}
+	}
+}
+
+func (m *diffMatcher) operations() []DiffOperation {
+	var operations []DiffOperation
+	for i, node := range m.old.nodes {
+		if m.oldToNew[i] < 0 && (node.parent < 0 || m.oldToNew[node.parent] >= 0) {
+			operations = append(operations, DiffOperation{Kind: DiffDelete, OldNode: node.node})
+		}
+	}
+
+	// Find the children that were reordered within their parent: those that
+	// are not part of the longest sequence of children that kept their order.
+	reordered := make(map[int]bool)
+	for j, node := range m.new.nodes {
+		i := m.newToOld[j]
+		if i < 0 || len(node.children) == 0 {
+			continue
+		}
+		var oldChildren, newChildren []int
+		for _, child := range m.old.nodes[i].children {
+			if match := m.oldToNew[child]; match >= 0 && m.new.nodes[match].parent == j {
+				oldChildren = append(oldChildren, child)
+			}
+		}
+		for _, child := range node.children {
+			if match := m.newToOld[child]; match >= 0 && m.old.nodes[match].parent == i {
+				newChildren = append(newChildren, child)
+			}
+		}
+		// The children are matched one to one, so the longest sequence that
+		// kept its order is the longest increasing sequence of the new
+		// positions of the old children.
+		positions := make(map[int]int, len(newChildren))
+		for position, child := range newChildren {
+			positions[child] = position
+		}
+		newPositions := make([]int, len(oldChildren))
+		for k, child := range oldChildren {
+			newPositions[k] = positions[m.oldToNew[child]]
+		}
+		aligned := make(map[int]bool)
+		for _, position := range longestIncreasingSubsequence(newPositions) {
+			aligned[newChildren[position]] = true
+		}
+		for _, child := range newChildren {
+			if !aligned[child] {
+				reordered[child] = true
+			}
+		}
+	}
+
+	for j, node := range m.new.nodes {
+		i := m.newToOld[j]
+		if i < 0 {
+			if node.parent < 0 || m.newToOld[node.parent] >= 0 {
+				operations = append(operations, DiffOperation{Kind: DiffInsert, NewNode: node.node})
+			}
+			continue
+		}
+		oldNode := m.old.nodes[i]
+		if j > 0 && (oldNode.parent < 0 || m.oldToNew[oldNode.parent] != node.parent || reordered[j]) {
+			operations = append(operations, DiffOperation{Kind: DiffMove, OldNode: oldNode.node, NewNode: node.node})
+		}
+		if oldNode.label != node.label {
+			operations = append(operations, DiffOperation{Kind: DiffUpdate, OldNode: oldNode.node, NewNode: node.node})
+		}
+	}
+	return operations
+}
+
+// The maximum number of cells in the table that [longestCommonSubsequence]
+// builds. For larger sequences, only their common prefix and suffix are used.
+const maxDiffTableSize = 1 << 20
+
+// Find the longest common subsequence of two sequences, as pairs of indices
+// into them.
+func longestCommonSubsequence(a, b []int, equal func(int, int) bool) [][2]int {
+	// Trim the common prefix and suffix, which is usually most of the
+	// sequences, to keep the table small.
+	var prefix, suffix [][2]int
+	for len(a) > 0 && len(b) > 0 && equal(a[0], b[0]) {
+		prefix = append(prefix, [2]int{a[0], b[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && equal(a[len(a)-1], b[len(b)-1]) {
+		suffix = append(suffix, [2]int{a[len(a)-1], b[len(b)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	if (len(a)+1)*(len(b)+1) > maxDiffTableSize {
+		a, b = nil, nil
+	}
+
+	// lengths[x][y] is the length of the longest common subsequence of a[x:]
+	// and b[y:].
+	lengths := make([][]int, len(a)+1)
+	for x := range lengths {
+		lengths[x] = make([]int, len(b)+1)
+	}
+	for x := len(a) - 1; x >= 0; x-- {
+		for y := len(b) - 1; y >= 0; y-- {
+			if equal(a[x], b[y]) {
+				lengths[x][y] = lengths[x+1][y+1] + 1
+			} else {
+				lengths[x][y] = max(lengths[x+1][y], lengths[x][y+1])
+			}
+		}
+	}
+
+	result := prefix
+	for x, y := 0, 0; x < len(a) && y < len(b); {
+		if equal(a[x], b[y]) {
+			result = append(result, [2]int{a[x], b[y]})
+			x++
+			y++
+		} else if lengths[x+1][y] >= lengths[x][y+1] {
+			x++
+		} else {
+			y++
+		}
+	}
+	for k := len(suffix) - 1; k >= 0; k-- {
+		result = append(result, suffix[k])
+	}
+	return result
+}
+
+// Find the longest strictly increasing subsequence of distinct values, in
+// O(n log n) time.
+func longestIncreasingSubsequence(values []int) []int {
+	// tails[l] is the index of the smallest value that ends an increasing
+	// subsequence of length l+1, and previous[k] is the index of the value
+	// before values[k] in the longest subsequence that ends with it.
+	var tails []int
+	previous := make([]int, len(values))
+	for k, value := range values {
+		l := sort.Search(len(tails), func(l int) bool { return values[tails[l]] >= value })
+		previous[k] = -1
+		if l > 0 {
+			previous[k] = tails[l-1]
+		}
+		if l == len(tails) {
+			tails = append(tails, k)
+		} else {
+			tails[l] = k
+		}
+	}
+
+	result := make([]int, len(tails))
+	if len(tails) > 0 {
+		for l, k := len(tails)-1, tails[len(tails)-1]; l >= 0; l, k = l-1, previous[k] {
+			result[l] = values[k]
+		}
+	}
+	return result
+}
+
+func filledInts(n, value int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = value
+	}
+	return result
+}
+
+// A priority queue of the nodes of a [diffTree], ordered by their height.
+type diffHeightQueue struct {
+	tree  *diffTree
+	nodes []int
+}
+
+func (q *diffHeightQueue) Len() int { return len(q.nodes) }
+
+func (q *diffHeightQueue) Less(a, b int) bool {
+	return q.tree.nodes[q.nodes[a]].height > q.tree.nodes[q.nodes[b]].height
+}
+
+func (q *diffHeightQueue) Swap(a, b int) { q.nodes[a], q.nodes[b] = q.nodes[b], q.nodes[a] }
+
+func (q *diffHeightQueue) Push(x any) { q.nodes = append(q.nodes, x.(int)) }
+
+func (q *diffHeightQueue) Pop() any {
+	last := q.nodes[len(q.nodes)-1]
+	q.nodes = q.nodes[:len(q.nodes)-1]
+	return last
+}
+
+func (q *diffHeightQueue) peekHeight() int {
+	if len(q.nodes) == 0 {
+		return 0
+	}
+	return q.tree.nodes[q.nodes[0]].height
+}
+
+func (q *diffHeightQueue) popHeight(height int) []int {
+	var result []int
+	for q.peekHeight() == height {
+		result = append(result, heap.Pop(q).(int))
+	}
+	return result
+}
+
+func (q *diffHeightQueue) open(i int) {
+	for _, child := range q.tree.nodes[i].children {
+		heap.Push(q, child)
+	}
+}
@@ -0,0 +1,137 @@
+package tree_sitter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func diffSources(t *testing.T, language, oldSource, newSource string, options *DiffOptions) *TreeDiff {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage(language))
+
+	oldTree := parser.Parse([]byte(oldSource), nil)
+	defer oldTree.Close()
+	newTree := parser.Parse([]byte(newSource), nil)
+	defer newTree.Close()
+
+	diff, err := oldTree.Diff([]byte(oldSource), newTree, []byte(newSource), options)
+	assert.Nil(t, err)
+	return diff
+}
+
+func diffOperationStrings(diff *TreeDiff) []string {
+	var result []string
+	for _, operation := range diff.Operations {
+		result = append(result, operation.String())
+	}
+	return result
+}
+
+func TestTreeDiffIdentical(t *testing.T) {
+	source := "function f(a) { return a + 1; }"
+	diff := diffSources(t, "javascript", source, source, nil)
+	assert.Empty(t, diff.Operations)
+
+	for node := range diff.OldSnapshot.RootNode().Descendants() {
+		match, ok := diff.NewNodeFor(node)
+		assert.True(t, ok)
+		assert.Equal(t, node.Id(), match.Id())
+		back, ok := diff.OldNodeFor(match)
+		assert.True(t, ok)
+		assert.Equal(t, node, back)
+	}
+}
+
+func TestTreeDiffMoveAndRename(t *testing.T) {
+	diff := diffSources(
+		t,
+		"javascript",
+		"function foo(a, b) {\n  return a + b;\n}\nfunction baz() {\n  log(1);\n}\n",
+		"function baz() {\n  log(1);\n}\nfunction bar(a, b) {\n  return a + b;\n}\n",
+		nil,
+	)
+	assert.Equal(t, []string{
+		"move function_declaration from 1:1 to 4:1",
+		"update identifier from 1:10 to 4:10",
+	}, diffOperationStrings(diff))
+
+	update := diff.Operations[1]
+	assert.Equal(t, DiffUpdate, update.Kind)
+	function, ok := update.NewNode.Parent()
+	assert.True(t, ok)
+	assert.Equal(t, diff.Operations[0].NewNode, function)
+}
+
+func TestTreeDiffInsertAndDelete(t *testing.T) {
+	diff := diffSources(
+		t,
+		"javascript",
+		"function f() {\n  a();\n  b();\n}\n",
+		"function f() {\n  a();\n  if (x) { y = 1; }\n}\n",
+		nil,
+	)
+	assert.Equal(t, []string{
+		"delete expression_statement at 3:3",
+		"insert if_statement at 3:3",
+	}, diffOperationStrings(diff))
+
+	_, ok := diff.NewNodeFor(diff.Operations[0].OldNode)
+	assert.False(t, ok)
+	_, ok = diff.OldNodeFor(diff.Operations[1].NewNode)
+	assert.False(t, ok)
+}
+
+func TestTreeDiffIgnoreExtras(t *testing.T) {
+	oldSource := "let x = 1; // one\n"
+	newSource := "// a new comment\nlet x = 2;\n"
+
+	diff := diffSources(t, "javascript", oldSource, newSource, nil)
+	assert.Equal(t, []string{
+		"update comment from 1:12 to 1:1",
+		"move lexical_declaration from 1:1 to 2:1",
+		"update number from 1:9 to 2:9",
+	}, diffOperationStrings(diff))
+
+	diff = diffSources(t, "javascript", oldSource, newSource, &DiffOptions{IgnoreExtras: true})
+	assert.Equal(t, []string{"update number from 1:9 to 2:9"}, diffOperationStrings(diff))
+}
+
+func TestTreeDiffDifferentLanguages(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+
+	parser.SetLanguage(getLanguage("json"))
+	jsonTree := parser.Parse([]byte("[]"), nil)
+	defer jsonTree.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+	jsTree := parser.Parse([]byte("[]"), nil)
+	defer jsTree.Close()
+
+	_, err := jsonTree.Diff([]byte("[]"), jsTree, []byte("[]"), nil)
+	assert.NotNil(t, err)
+}
+
+func TestTreeDiffLargeLists(t *testing.T) {
+	var oldStatements, changedStatements []string
+	for i := 0; i < 1500; i++ {
+		oldStatements = append(oldStatements, fmt.Sprintf("f(%d);", i))
+		changedStatements = append(changedStatements, fmt.Sprintf("g(%d);", i))
+	}
+	movedStatements := append(append([]string{}, oldStatements[1:]...), oldStatements[0])
+
+	// Only the statement that left the longest sequence of statements that
+	// kept their order is moved.
+	diff := diffSources(t, "javascript", strings.Join(oldStatements, "\n"), strings.Join(movedStatements, "\n"), nil)
+	assert.Equal(t, []string{"move expression_statement from 1:1 to 1500:1"}, diffOperationStrings(diff))
+
+	// Lists that are too long to align are still matched by their common
+	// prefix.
+	diff = diffSources(t, "javascript", strings.Join(oldStatements, "\n"), strings.Join(changedStatements, "\n"), nil)
+	assert.Len(t, diff.Operations, 1500)
+	assert.Equal(t, "update identifier from 1:1 to 1:1", diff.Operations[0].String())
+}
@@ -0,0 +1,231 @@
+package tree_sitter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// A syntax node that was decoded from JSON by a [TreeDecoder].
+//
+// Unlike a [Node], it is a plain Go value that does not depend on a [Tree],
+// so it can be used after the tree is closed, or in a different process.
+type JSONNode struct {
+	Kind       string      `json:"kind"`
+	FieldName  string      `json:"field,omitempty"`
+	IsNamed    bool        `json:"named,omitempty"`
+	IsExtra    bool        `json:"extra,omitempty"`
+	IsMissing  bool        `json:"missing,omitempty"`
+	IsError    bool        `json:"error,omitempty"`
+	StartByte  uint        `json:"startByte"`
+	EndByte    uint        `json:"endByte"`
+	StartPoint Point       `json:"startPoint"`
+	EndPoint   Point       `json:"endPoint"`
+	Text       *string     `json:"text,omitempty"`
+	Children   []*JSONNode `json:"children,omitempty"`
+}
+
+// The JSON form of a [Point], as it is written by a [TreeEncoder].
+type jsonPoint struct {
+	Row    uint `json:"row"`
+	Column uint `json:"column"`
+}
+
+// The fields of a [JSONNode] with the points replaced by their JSON form.
+type jsonNodeFields struct {
+	*jsonNodeAlias
+	StartPoint jsonPoint `json:"startPoint"`
+	EndPoint   jsonPoint `json:"endPoint"`
+}
+
+// A type with the same fields as [JSONNode], but without its methods, so that
+// encoding it doesn't call them again.
+type jsonNodeAlias JSONNode
+
+// Encode the node in the same form as a [TreeEncoder].
+func (n *JSONNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNodeFields{
+		jsonNodeAlias: (*jsonNodeAlias)(n),
+		StartPoint:    jsonPoint(n.StartPoint),
+		EndPoint:      jsonPoint(n.EndPoint),
+	})
+}
+
+// Decode a node that was written by a [TreeEncoder].
+func (n *JSONNode) UnmarshalJSON(data []byte) error {
+	fields := jsonNodeFields{jsonNodeAlias: (*jsonNodeAlias)(n)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	n.StartPoint = Point(fields.StartPoint)
+	n.EndPoint = Point(fields.EndPoint)
+	return nil
+}
+
+// Get the range of source code that this node represents.
+func (n *JSONNode) Range() Range {
+	return Range{
+		StartByte:  n.StartByte,
+		EndByte:    n.EndByte,
+		StartPoint: n.StartPoint,
+		EndPoint:   n.EndPoint,
+	}
+}
+
+// Get the first child with the given field name, or `nil` if there is none.
+func (n *JSONNode) ChildByFieldName(fieldName string) *JSONNode {
+	for _, child := range n.Children {
+		if child.FieldName == fieldName {
+			return child
+		}
+	}
+	return nil
+}
+
+// A TreeEncoder writes syntax trees to an output stream as JSON.
+//
+// Each node is written as an object with its kind, its field name, its
+// flags, its byte and point ranges, and its children:
+//
+//	{"kind":"pair","named":true,"startByte":1,"endByte":7,
+//	 "startPoint":{"row":0,"column":1},"endPoint":{"row":0,"column":7},
+//	 "children":[{"kind":"string","field":"key",...},...]}
+//
+// Flags that are false and empty fields are left out. The output can be read
+// back with a [TreeDecoder].
+type TreeEncoder struct {
+	w         *bufio.Writer
+	source    []byte
+	namedOnly bool
+	buffer    []byte
+}
+
+// Create a new encoder that writes to `w`.
+func NewTreeEncoder(w io.Writer) *TreeEncoder {
+	return &TreeEncoder{w: bufio.NewWriter(w)}
+}
+
+// Set the source code that the encoded trees were parsed from, so that the
+// text of every leaf node is included as its `text` property. Pass `nil` to
+// leave out the text.
+func (e *TreeEncoder) SetSource(source []byte) {
+	e.source = source
+}
+
+// Set whether anonymous nodes, such as punctuation, are left out.
+func (e *TreeEncoder) SetNamedOnly(namedOnly bool) {
+	e.namedOnly = namedOnly
+}
+
+// Write the JSON encoding of the given tree, followed by a newline.
+func (e *TreeEncoder) EncodeTree(tree *Tree) error {
+	return e.Encode(tree.RootNode())
+}
+
+// Write the JSON encoding of the given node and its descendants, followed by
+// a newline.
+func (e *TreeEncoder) Encode(node *Node) error {
+	cursor := node.Walk()
+	defer cursor.Close()
+
+	e.encodeNode(cursor, "")
+	e.w.WriteByte('\n')
+	return e.w.Flush()
+}
+
+func (e *TreeEncoder) encodeNode(cursor *TreeCursor, fieldName string) {
+	node := cursor.Node()
+
+	b := e.buffer[:0]
+	b = append(b, `{"kind":`...)
+	b = appendJSONString(b, node.Kind())
+	if fieldName != "" {
+		b = append(b, `,"field":`...)
+		b = appendJSONString(b, fieldName)
+	}
+	b = appendJSONFlag(b, "named", node.IsNamed())
+	b = appendJSONFlag(b, "extra", node.IsExtra())
+	b = appendJSONFlag(b, "missing", node.IsMissing())
+	b = appendJSONFlag(b, "error", node.IsError())
+	b = append(b, `,"startByte":`...)
+	b = strconv.AppendUint(b, uint64(node.StartByte()), 10)
+	b = append(b, `,"endByte":`...)
+	b = strconv.AppendUint(b, uint64(node.EndByte()), 10)
+	b = append(b, `,"startPoint":`...)
+	b = appendJSONPoint(b, node.StartPosition())
+	b = append(b, `,"endPoint":`...)
+	b = appendJSONPoint(b, node.EndPosition())
+	if e.source != nil && node.ChildCount() == 0 && node.EndByte() <= uint(len(e.source)) {
+		b = append(b, `,"text":`...)
+		b = appendJSONString(b, string(e.source[node.StartByte():node.EndByte()]))
+	}
+	e.w.Write(b)
+	e.buffer = b
+
+	if cursor.GotoFirstChild() {
+		first := true
+		for {
+			if !e.namedOnly || cursor.Node().IsNamed() {
+				if first {
+					e.w.WriteString(`,"children":[`)
+					first = false
+				} else {
+					e.w.WriteByte(',')
+				}
+				e.encodeNode(cursor, cursor.FieldName())
+			}
+			if !cursor.GotoNextSibling() {
+				break
+			}
+		}
+		cursor.GotoParent()
+		if !first {
+			e.w.WriteByte(']')
+		}
+	}
+	e.w.WriteByte('}')
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	encoded, _ := json.Marshal(s)
+	return append(b, encoded...)
+}
+
+func appendJSONFlag(b []byte, name string, value bool) []byte {
+	if !value {
+		return b
+	}
+	b = append(b, ',', '"')
+	b = append(b, name...)
+	return append(b, `":true`...)
+}
+
+func appendJSONPoint(b []byte, point Point) []byte {
+	b = append(b, `{"row":`...)
+	b = strconv.AppendUint(b, uint64(point.Row), 10)
+	b = append(b, `,"column":`...)
+	b = strconv.AppendUint(b, uint64(point.Column), 10)
+	return append(b, '}')
+}
+
+// A TreeDecoder reads syntax trees that were written by a [TreeEncoder]
+// from an input stream.
+type TreeDecoder struct {
+	decoder *json.Decoder
+}
+
+// Create a new decoder that reads from `r`.
+func NewTreeDecoder(r io.Reader) *TreeDecoder {
+	return &TreeDecoder{decoder: json.NewDecoder(r)}
+}
+
+// Read the next tree from the input stream. At the end of the stream, the
+// returned error is [io.EOF].
+func (d *TreeDecoder) Decode() (*JSONNode, error) {
+	var node JSONNode
+	if err := d.decoder.Decode(&node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
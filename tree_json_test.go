@@ -0,0 +1,118 @@
+package tree_sitter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/tree-sitter/go-tree-sitter"
+)
+
+func assertJSONNodeMatches(t *testing.T, node *Node, jsonNode *JSONNode, source []byte) {
+	assert.Equal(t, node.Kind(), jsonNode.Kind)
+	assert.Equal(t, node.IsNamed(), jsonNode.IsNamed)
+	assert.Equal(t, node.IsExtra(), jsonNode.IsExtra)
+	assert.Equal(t, node.IsMissing(), jsonNode.IsMissing)
+	assert.Equal(t, node.IsError(), jsonNode.IsError)
+	assert.Equal(t, node.Range(), jsonNode.Range())
+	if node.ChildCount() == 0 {
+		assert.Equal(t, node.Utf8Text(source), *jsonNode.Text)
+	} else {
+		assert.Nil(t, jsonNode.Text)
+	}
+
+	assert.Equal(t, int(node.ChildCount()), len(jsonNode.Children))
+	cursor := node.Walk()
+	defer cursor.Close()
+	for i, child := range node.Children(cursor) {
+		assert.Equal(t, node.FieldNameForChild(uint32(i)), jsonNode.Children[i].FieldName)
+		assertJSONNodeMatches(t, &child, jsonNode.Children[i], source)
+	}
+}
+
+func TestTreeEncoder(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("javascript"))
+
+	source := []byte("// hi\nconst s = \"é\\n\";\nfoo(s,);")
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	var output bytes.Buffer
+	encoder := NewTreeEncoder(&output)
+	encoder.SetSource(source)
+	assert.Nil(t, encoder.EncodeTree(tree))
+	assert.True(t, json.Valid(output.Bytes()))
+
+	decoded, err := NewTreeDecoder(&output).Decode()
+	assert.Nil(t, err)
+	assertJSONNodeMatches(t, tree.RootNode(), decoded, source)
+
+	// A decoded node is encoded in the same form again.
+	encoded, err := json.Marshal(decoded)
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), `"startPoint":{"row":0,"column":0}`)
+	var roundTripped JSONNode
+	assert.Nil(t, json.Unmarshal(encoded, &roundTripped))
+	assert.Equal(t, decoded, &roundTripped)
+
+	declaration := decoded.Children[1].Children[1]
+	assert.Equal(t, "variable_declarator", declaration.Kind)
+	assert.Equal(t, "identifier", declaration.ChildByFieldName("name").Kind)
+	value := declaration.ChildByFieldName("value")
+	assert.Equal(t, "é", *value.Children[1].Text)
+	assert.Equal(t, "\\n", *value.Children[2].Text)
+	assert.True(t, decoded.Children[0].IsExtra)
+}
+
+func TestTreeEncoderOutput(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("json"))
+
+	source := []byte("[1,\n2")
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	var output bytes.Buffer
+	encoder := NewTreeEncoder(&output)
+	encoder.SetNamedOnly(true)
+	array := tree.RootNode().Child(0)
+	assert.Nil(t, encoder.Encode(array))
+	assert.Nil(t, encoder.Encode(array.NamedChild(1)))
+
+	assert.Equal(
+		t,
+		`{"kind":"array","named":true,"startByte":0,"endByte":5,"startPoint":{"row":0,"column":0},"endPoint":{"row":1,"column":1},"children":[`+
+			`{"kind":"number","named":true,"startByte":1,"endByte":2,"startPoint":{"row":0,"column":1},"endPoint":{"row":0,"column":2}},`+
+			`{"kind":"number","named":true,"startByte":4,"endByte":5,"startPoint":{"row":1,"column":0},"endPoint":{"row":1,"column":1}}]}`+"\n"+
+			`{"kind":"number","named":true,"startByte":4,"endByte":5,"startPoint":{"row":1,"column":0},"endPoint":{"row":1,"column":1}}`+"\n",
+		output.String(),
+	)
+
+	decoder := NewTreeDecoder(&output)
+	first, err := decoder.Decode()
+	assert.Nil(t, err)
+	assert.Len(t, first.Children, 2)
+	second, err := decoder.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, first.Children[1], second)
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTreeEncoderErrorNodes(t *testing.T) {
+	parser := NewParser()
+	defer parser.Close()
+	parser.SetLanguage(getLanguage("rust"))
+
+	tree := parser.Parse([]byte("fn main() { let x = 1 }"), nil)
+	defer tree.Close()
+
+	var output bytes.Buffer
+	assert.Nil(t, NewTreeEncoder(&output).EncodeTree(tree))
+	assert.Contains(t, output.String(), `{"kind":";","missing":true,"startByte":21,"endByte":21`)
+}
@@ -0,0 +1,176 @@
+//go:build tree_sitter_wasm
+
+package tree_sitter
+
+/*
+#cgo CFLAGS: -Iinclude -Isrc -std=c11 -D_POSIX_C_SOURCE=200112L -D_DEFAULT_SOURCE -DTREE_SITTER_FEATURE_WASM
+#cgo LDFLAGS: -lwasmtime
+#include <tree_sitter/api.h>
+#include <wasm.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// An engine that compiles and runs Wasm modules.
+//
+// A single engine can be shared by any number of [WasmStore]s. Each store
+// keeps its own reference to the engine, so it is safe to close the engine
+// once all of the stores that need it have been created.
+type WasmEngine struct {
+	_inner *C.TSWasmEngine
+}
+
+// A store of Wasm modules that can be used to load and run languages that
+// were compiled to Wasm.
+//
+// A [Parser] must have a [WasmStore] assigned via [Parser.SetWasmStore] in
+// order to parse with a language for which [Language.IsWasm] returns `true`.
+type WasmStore struct {
+	_inner *C.TSWasmStore
+}
+
+type WasmErrorKind int
+
+const (
+	WasmErrorKindParse WasmErrorKind = iota
+	WasmErrorKindCompile
+	WasmErrorKindInstantiate
+	WasmErrorKindOther
+)
+
+// An error that occurred when creating a [WasmStore] or loading a language
+// into one.
+type WasmError struct {
+	Kind    WasmErrorKind
+	Message string
+}
+
+// Create a new Wasm engine with the default configuration.
+func NewWasmEngine() *WasmEngine {
+	return &WasmEngine{_inner: (*C.TSWasmEngine)(unsafe.Pointer(C.wasm_engine_new()))}
+}
+
+// Delete the underlying memory for a Wasm engine.
+func (e *WasmEngine) Close() {
+	C.wasm_engine_delete((*C.wasm_engine_t)(unsafe.Pointer(e._inner)))
+}
+
+// Create a new Wasm store that runs modules with the given engine.
+func NewWasmStore(engine *WasmEngine) (*WasmStore, error) {
+	var cError C.TSWasmError
+	ptr := C.ts_wasm_store_new(engine._inner, &cError)
+	if ptr == nil {
+		return nil, newWasmError(cError)
+	}
+	return &WasmStore{_inner: ptr}, nil
+}
+
+// Delete the underlying memory for a Wasm store.
+//
+// A store that has been assigned to a [Parser] is owned by that parser and
+// must not be closed, unless it has been taken back with
+// [Parser.TakeWasmStore].
+func (s *WasmStore) Close() {
+	if s._inner != nil {
+		C.ts_wasm_store_delete(s._inner)
+		s._inner = nil
+	}
+}
+
+// Create a language from a buffer of Wasm.
+//
+// The `name` is the name of the language as it appears in the grammar, and
+// is used to find the `tree_sitter_<name>` function exported by the module.
+//
+// The resulting language behaves like any other [Language], except that in
+// order to use it with a [Parser], that parser must have a [WasmStore]. Note
+// that the language can be used with any Wasm store, it doesn't need to be
+// the same store that was used to originally load it. Call [Language.Close]
+// once the language is no longer needed.
+func (s *WasmStore) LoadLanguage(name string, wasm []byte) (*Language, error) {
+	cName := C.CString(name)
+	defer go_free(unsafe.Pointer(cName))
+
+	var wasmPtr *C.char
+	if len(wasm) > 0 {
+		wasmPtr = (*C.char)(unsafe.Pointer(&wasm[0]))
+	}
+
+	var cError C.TSWasmError
+	ptr := C.ts_wasm_store_load_language(s._inner, cName, wasmPtr, C.uint32_t(len(wasm)), &cError)
+	if ptr == nil {
+		return nil, newWasmError(cError)
+	}
+	return &Language{Inner: ptr}, nil
+}
+
+// Get the number of languages instantiated in this Wasm store.
+func (s *WasmStore) LanguageCount() uint {
+	return uint(C.ts_wasm_store_language_count(s._inner))
+}
+
+// Assign the given Wasm store to the parser.
+//
+// A parser must have a Wasm store in order to use Wasm languages. The
+// parser takes ownership of the store, which is released when the parser
+// is closed or another store is assigned.
+func (p *Parser) SetWasmStore(store *WasmStore) {
+	C.ts_parser_set_wasm_store(p._inner, store._inner)
+	store._inner = nil
+}
+
+// Remove the parser's current Wasm store and return it.
+//
+// This returns `nil` if the parser doesn't have a Wasm store. If the parser's
+// current language is a Wasm language, the language is unset.
+func (p *Parser) TakeWasmStore() *WasmStore {
+	ptr := C.ts_parser_take_wasm_store(p._inner)
+	if ptr == nil {
+		return nil
+	}
+	return &WasmStore{_inner: ptr}
+}
+
+func newWasmError(cError C.TSWasmError) *WasmError {
+	var kind WasmErrorKind
+	switch cError.kind {
+	case C.TSWasmErrorKindParse:
+		kind = WasmErrorKindParse
+	case C.TSWasmErrorKindCompile:
+		kind = WasmErrorKindCompile
+	case C.TSWasmErrorKindInstantiate:
+		kind = WasmErrorKindInstantiate
+	default:
+		kind = WasmErrorKindOther
+	}
+
+	var message string
+	if cError.message != nil {
+		message = C.GoString(cError.message)
+		go_free(unsafe.Pointer(cError.message))
+	}
+
+	return &WasmError{Kind: kind, Message: message}
+}
+
+func (e *WasmError) Error() string {
+	var kind string
+	switch e.Kind {
+	case WasmErrorKindParse:
+		kind = "Failed to parse Wasm module"
+	case WasmErrorKindCompile:
+		kind = "Failed to compile Wasm module"
+	case WasmErrorKindInstantiate:
+		kind = "Failed to instantiate Wasm module"
+	default:
+		kind = "Wasm error"
+	}
+	if e.Message == "" {
+		return kind
+	}
+	return fmt.Sprintf("%s: %s", kind, e.Message)
+}